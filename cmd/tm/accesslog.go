@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// statusRecorder captures the status code and body size written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush keeps SSE (/stream) working through the wrapper
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// accessLogMiddleware logs one line per request (enabled by -v or access_log=true)
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"query", redactQuery(r.URL.Query()),
+			"status", rec.status,
+			"duration", time.Since(start).Round(time.Millisecond),
+			"bytes", rec.bytes,
+			"request_id", r.Header.Get(requestIDHeader),
+			"remote", r.RemoteAddr)
+	})
+}
+
+// redactQuery encodes query params with the auth token masked
+func redactQuery(q url.Values) string {
+	if q.Has("token") {
+		q.Set("token", "REDACTED")
+	}
+	return q.Encode()
+}
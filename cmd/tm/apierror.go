@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Error codes in API error responses. Clients branch on the code; the
+// message is for people and may change.
+const (
+	errCodeUnauthorized     = "unauthorized"
+	errCodeMethodNotAllowed = "method_not_allowed"
+	errCodeInvalidRequest   = "invalid_request"
+	errCodeNotConfigured    = "not_configured" // the source's sync isn't set up
+	errCodeNotFound         = "not_found"
+	errCodeUnsupported      = "unsupported" // e.g. streaming through a writer that can't flush
+	errCodeInternal         = "internal"
+)
+
+// apiError is the body of every error response from tm serve's HTTP API:
+// {"error":{"code":"unauthorized","message":"Unauthorized"}}
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeError sends an API error response with the given status
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// apiErrorMessage returns the message of an API error response body, or the
+// body itself when it isn't one (an older server, a proxy's error page)
+func apiErrorMessage(body []byte) string {
+	var e apiError
+	if err := json.Unmarshal(body, &e); err == nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	auditFileName   = "audit.jsonl"
+	auditMaxEntries = 10000
+)
+
+// AuditEntry records one item handed to the queue
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"` // github, calendar, readwise, manual
+	ExternalID string    `json:"external_id,omitempty"`
+	Verb       string    `json:"verb,omitempty"`
+	Title      string    `json:"title,omitempty"`
+}
+
+// AuditLog is a bounded JSONL log of queued items
+type AuditLog struct {
+	path    string
+	max     int
+	mu      sync.Mutex
+	entries []AuditEntry
+	lines   int // lines currently in the file (may exceed max until compaction)
+}
+
+// NewAuditLog opens (or creates) the audit log in dataDir
+func NewAuditLog(dataDir string, max int) (*AuditLog, error) {
+	a := &AuditLog{
+		path: filepath.Join(dataDir, auditFileName),
+		max:  max,
+	}
+
+	entries, err := readAuditFile(a.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	a.lines = len(entries)
+	if len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+	a.entries = entries
+
+	return a, nil
+}
+
+// Record appends an entry, compacting the file once it holds twice the cap
+func (a *AuditLog) Record(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > a.max {
+		a.entries = a.entries[len(a.entries)-a.max:]
+	}
+
+	if a.lines >= 2*a.max {
+		if err := a.rewrite(); err != nil {
+			logger.Error("failed to compact audit log", "error", err)
+		}
+		return
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error("failed to write audit log", "error", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(entry)
+	f.Write(append(data, '\n'))
+	a.lines++
+}
+
+// rewrite replaces the file with the in-memory entries (caller holds mu)
+func (a *AuditLog) rewrite() error {
+	tmp := a.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, e := range a.entries {
+		data, _ := json.Marshal(e)
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	a.lines = len(a.entries)
+	return os.Rename(tmp, a.path)
+}
+
+// Query returns entries matching source (empty = all) newer than since
+func (a *AuditLog) Query(source string, since time.Time) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return filterAudit(a.entries, source, since)
+}
+
+func filterAudit(entries []AuditEntry, source string, since time.Time) []AuditEntry {
+	result := make([]AuditEntry, 0)
+	for _, e := range entries {
+		if source != "" && e.Source != source {
+			continue
+		}
+		if !since.IsZero() && e.Time.Before(since) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func readAuditFile(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // skip torn lines
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := parseDuration(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid since")
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+
+	entries := s.audit.Query(r.URL.Query().Get("source"), since)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// runLog prints the audit log straight from disk (works without a server)
+func runLog(args []string) {
+	var source string
+	var since time.Time
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--source", "-s":
+			if i+1 < len(args) {
+				source = args[i+1]
+				i++
+			}
+		case "--since":
+			if i+1 < len(args) {
+				d, err := parseDuration(args[i+1])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid --since %q\n", args[i+1])
+					os.Exit(1)
+				}
+				since = time.Now().Add(-d)
+				i++
+			}
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	entries, err := readAuditFile(filepath.Join(home, ".config", "tm", auditFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("No sync history yet")
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error reading audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	entries = filterAudit(entries, source, since)
+	if len(entries) == 0 {
+		fmt.Println("No matching entries")
+		return
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s  %-8s  %-11s  %s  %s\n",
+			e.Time.In(displayLocation).Format("2006-01-02 15:04:05"), e.Source, e.Verb, e.ExternalID, e.Title)
+	}
+}
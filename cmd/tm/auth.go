@@ -6,12 +6,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -66,7 +68,7 @@ func getGoogleOAuthConfig() *oauth2.Config {
 
 // runGoogleAuth runs the OAuth browser flow for Google Calendar
 func runGoogleAuth() {
-	fmt.Println("🔐 Google Calendar Authentication")
+	fmt.Println(em("🔐 Google Calendar Authentication"))
 	fmt.Println()
 
 	// Check if already authenticated
@@ -90,7 +92,7 @@ func runGoogleAuth() {
 
 	// Check if client ID is configured
 	if config.ClientID == "YOUR_CLIENT_ID.apps.googleusercontent.com" {
-		fmt.Println("⚠️  Google OAuth not configured!")
+		fmt.Println(em("⚠️  Google OAuth not configured!"))
 		fmt.Println()
 		fmt.Println("To set up Google Calendar sync:")
 		fmt.Println()
@@ -106,14 +108,64 @@ func runGoogleAuth() {
 		os.Exit(1)
 	}
 
+	// Generate auth URL
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+
+	fmt.Println("Opening browser for Google sign-in...")
+	code, err := waitForOAuthCode(authURL, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exchange code for tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exchanging code: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Get user email
+	email := getUserEmail(ctx, config, token)
+
+	// Save tokens
+	tokens = &GoogleTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		Email:        email,
+	}
+
+	if err := saveGoogleTokens(*tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf(em("✅ Authenticated as %s\n"), email)
+	fmt.Println(em("✅ Token saved to ~/.config/tm/google.json"))
+	fmt.Println()
+
+	// List calendars
+	listCalendarsAfterAuth(ctx, config, token)
+}
+
+// waitForOAuthCode opens authURL in the browser and serves the local callback
+// until the provider redirects back with a code (or an error / timeout).
+func waitForOAuthCode(authURL, state string) (string, error) {
 	// Create channel to receive the auth code
 	codeChan := make(chan string, 1)
 	errChan := make(chan error, 1)
 
 	// Start local server to receive callback
-	server := &http.Server{Addr: ":" + OAuthCallbackPort}
+	mux := http.NewServeMux()
+	server := &http.Server{Addr: ":" + OAuthCallbackPort, Handler: mux}
 
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
 		// Verify state
 		if r.URL.Query().Get("state") != state {
 			errChan <- fmt.Errorf("invalid state parameter")
@@ -156,10 +208,13 @@ func runGoogleAuth() {
 		}
 	}()
 
-	// Generate auth URL
-	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	// Shutdown server once we have an answer
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
 
-	fmt.Println("Opening browser for Google sign-in...")
 	fmt.Printf("(listening on localhost:%s for callback)\n", OAuthCallbackPort)
 	fmt.Println()
 
@@ -175,54 +230,12 @@ func runGoogleAuth() {
 	// Wait for callback or timeout
 	select {
 	case code := <-codeChan:
-		// Exchange code for tokens
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		token, err := config.Exchange(ctx, code)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error exchanging code: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Get user email
-		email := getUserEmail(ctx, config, token)
-
-		// Save tokens
-		tokens := GoogleTokens{
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-			TokenType:    token.TokenType,
-			Expiry:       token.Expiry,
-			Email:        email,
-		}
-
-		if err := saveGoogleTokens(tokens); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving tokens: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Println()
-		fmt.Printf("✅ Authenticated as %s\n", email)
-		fmt.Println("✅ Token saved to ~/.config/tm/google.json")
-		fmt.Println()
-
-		// List calendars
-		listCalendarsAfterAuth(ctx, config, token)
-
+		return code, nil
 	case err := <-errChan:
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-
+		return "", err
 	case <-time.After(2 * time.Minute):
-		fmt.Fprintf(os.Stderr, "Timeout waiting for authentication\n")
-		os.Exit(1)
+		return "", fmt.Errorf("timeout waiting for authentication")
 	}
-
-	// Shutdown server
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	server.Shutdown(ctx)
 }
 
 // listCalendarsAfterAuth lists calendars after successful authentication
@@ -241,7 +254,7 @@ func listCalendarsAfterAuth(ctx context.Context, config *oauth2.Config, token *o
 	for _, cal := range list.Items {
 		marker := "  "
 		if cal.Primary {
-			marker = "✓ "
+			marker = em("✓ ")
 		}
 		name := cal.Summary
 		if cal.SummaryOverride != "" {
@@ -299,7 +312,7 @@ func runListCalendars() {
 	for _, cal := range list.Items {
 		marker := "  "
 		if enabled[cal.Id] || (cal.Primary && enabled["primary"]) {
-			marker = "✓ "
+			marker = em("✓ ")
 		}
 		name := cal.Summary
 		if cal.SummaryOverride != "" {
@@ -331,40 +344,13 @@ func runCalendarsEnable(calendarID string) {
 	}
 
 	// Add to config file
-	home, _ := os.UserHomeDir()
-	configPath := filepath.Join(home, ".config", "tm", "config")
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
-		os.Exit(1)
-	}
-
-	content := string(data)
 	newCalendars := append(cfg.GoogleCalendars, calendarID)
-	calendarLine := "google_calendars=" + joinCalendars(newCalendars)
-
-	// Update or add the line
-	lines := strings.Split(content, "\n")
-	found := false
-	for i, line := range lines {
-		if strings.HasPrefix(line, "google_calendars=") {
-			lines[i] = calendarLine
-			found = true
-			break
-		}
-	}
-	if !found {
-		lines = append(lines, calendarLine)
-	}
-
-	err = os.WriteFile(configPath, []byte(strings.Join(lines, "\n")), 0600)
-	if err != nil {
+	if err := writeConfigValue("google_calendars", joinCalendars(newCalendars), false); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("✅ Enabled calendar: %s\n", calendarID)
+	fmt.Printf(em("✅ Enabled calendar: %s\n"), calendarID)
 	fmt.Println("Restart 'tm serve' to start syncing")
 }
 
@@ -388,40 +374,105 @@ func runCalendarsDisable(calendarID string) {
 		return
 	}
 
-	// Update config file
-	home, _ := os.UserHomeDir()
-	configPath := filepath.Join(home, ".config", "tm", "config")
+	// Update config file, removing the line once no calendars are left
+	err := writeConfigValue("google_calendars", joinCalendars(newCalendars), len(newCalendars) == 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+		os.Exit(1)
+	}
 
-	data, err := os.ReadFile(configPath)
+	fmt.Printf(em("✅ Disabled calendar: %s\n"), calendarID)
+	fmt.Println("Restart 'tm serve' to apply changes")
+}
+
+// runCalendarsRefresh re-fetches the calendar list, updates the cached names
+// used for the calendar: frontmatter, and reports what changed
+func runCalendarsRefresh() {
+	var change *CalendarNamesChange
+	var err error
+	if serverRunning() {
+		change, err = refreshCalendarsHTTP()
+	} else {
+		change, err = refreshCalendarsLocal()
+	}
+	if err == errNotConfigured {
+		fmt.Println("No calendars enabled for sync.")
+		fmt.Println("Run 'tm calendars enable <id>' to add one")
+		return
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error refreshing calendars: %v\n", err)
 		os.Exit(1)
 	}
 
-	content := string(data)
-	calendarLine := "google_calendars=" + joinCalendars(newCalendars)
+	for _, cal := range change.Added {
+		fmt.Printf("  + %s (%s)\n", cal.ID, cal.Name)
+	}
+	for _, cal := range change.Removed {
+		fmt.Printf("  - %s (%s)\n", cal.ID, cal.Name)
+	}
+	for _, r := range change.Renamed {
+		fmt.Printf(em("  ~ %s: %s → %s\n"), r.ID, r.From, r.To)
+	}
+	if len(change.Added)+len(change.Removed)+len(change.Renamed) == 0 {
+		fmt.Println("Calendar names are up to date")
+	}
 
-	lines := strings.Split(content, "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(line, "google_calendars=") {
-			if len(newCalendars) == 0 {
-				// Remove the line
-				lines = append(lines[:i], lines[i+1:]...)
-			} else {
-				lines[i] = calendarLine
-			}
-			break
+	// Enabled calendars the account can no longer see would silently sync nothing
+	exists := make(map[string]bool)
+	for _, cal := range change.Calendars {
+		exists[cal.ID] = true
+		if cal.Primary {
+			exists["primary"] = true
 		}
 	}
+	for _, id := range loadConfig().GoogleCalendars {
+		if !exists[id] {
+			fmt.Printf(em("⚠️  Enabled calendar '%s' no longer exists - run 'tm calendars disable %s'\n"), id, id)
+		}
+	}
+}
+
+// refreshCalendarsHTTP asks the running server to refresh, since it holds calendar.db
+func refreshCalendarsHTTP() (*CalendarNamesChange, error) {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
 
-	err = os.WriteFile(configPath, []byte(strings.Join(lines, "\n")), 0600)
+	resp, err := http.Post(url+"/calendars/refresh?token="+token, "application/json", nil)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	fmt.Printf("✅ Disabled calendar: %s\n", calendarID)
-	fmt.Println("Restart 'tm serve' to apply changes")
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s", apiErrorMessage(body))
+	}
+
+	var change CalendarNamesChange
+	if err := json.NewDecoder(resp.Body).Decode(&change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// refreshCalendarsLocal refreshes calendar.db directly when no server is running
+func refreshCalendarsLocal() (*CalendarNamesChange, error) {
+	syncer, closeFn, err := openLocalSyncer("calendar")
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	return syncer.(*CalendarSyncer).RefreshCalendarNames(context.Background())
 }
 
 // Helper functions
@@ -499,3 +550,33 @@ func saveGoogleTokens(tokens GoogleTokens) error {
 func joinCalendars(calendars []string) string {
 	return strings.Join(calendars, ",")
 }
+
+// savingTokenSource calls save whenever the wrapped source refreshes the token,
+// so rotated refresh tokens survive restarts
+type savingTokenSource struct {
+	base oauth2.TokenSource
+	save func(*oauth2.Token) error
+	mu   sync.Mutex
+	last string
+}
+
+func newSavingTokenSource(base oauth2.TokenSource, current string, save func(*oauth2.Token) error) *savingTokenSource {
+	return &savingTokenSource{base: base, save: save, last: current}
+}
+
+func (ts *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := ts.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if token.AccessToken != ts.last {
+		ts.last = token.AccessToken
+		if err := ts.save(token); err != nil {
+			logger.Warn("failed to save refreshed token", "error", err)
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// backfillProgress is how far a source's first full import has got. Total
+// is an estimate from the API and 0 when it can't say.
+type backfillProgress struct {
+	Done     int    `json:"done"`
+	Total    int    `json:"total,omitempty"`
+	Unit     string `json:"unit"` // what Done counts: issues, items
+	Complete bool   `json:"-"`
+}
+
+// backfiller is implemented by syncers that import a large history in
+// resumable batches; Backfill returns nil once every part has caught up
+type backfiller interface {
+	Backfill() *backfillProgress
+}
+
+// backfillState is embedded by syncers to implement backfiller. Sync records
+// each part's progress (a repo, or "" for a single cursor); it has its own
+// lock so /status never waits for a sync in progress.
+type backfillState struct {
+	mu    sync.Mutex
+	parts map[string]backfillProgress
+}
+
+func (b *backfillState) setBackfill(part string, p backfillProgress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.parts == nil {
+		b.parts = make(map[string]backfillProgress)
+	}
+	b.parts[part] = p
+}
+
+// forgetBackfill drops one part's progress, or every part's when part is
+// "", after the cache (and with it the cursors) was cleared
+func (b *backfillState) forgetBackfill(part string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if part == "" {
+		b.parts = nil
+		return
+	}
+	delete(b.parts, part)
+}
+
+// Backfill implements backfiller: the sum over all parts while any of them
+// is still importing
+func (b *backfillState) Backfill() *backfillProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := make([]string, 0, len(b.parts))
+	for name := range b.parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sum backfillProgress
+	pending := false
+	for _, name := range names {
+		p := b.parts[name]
+		if !p.Complete {
+			pending = true
+		}
+		// Totals are estimates; never report more done than there is
+		if p.Total > 0 && p.Done > p.Total {
+			p.Done = p.Total
+		}
+		sum.Done += p.Done
+		sum.Total += p.Total
+		sum.Unit = p.Unit
+	}
+	if !pending {
+		return nil
+	}
+	return &sum
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// errDBLocked means another process holds a cache database open
+var errDBLocked = errors.New("database is locked")
+
+// openBolt opens a bbolt cache, turning a lock timeout into an actionable error.
+//
+// bbolt locks with flock(2), which the kernel releases when the holder exits,
+// so a timeout always means a live process has the file open - there is no
+// stale lock to clean up, only a process to stop.
+func openBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		holder := "another tm process (is `tm serve` or `tm resync` running?)"
+		if pid, cmd := boltLockHolder(path); pid != 0 {
+			holder = fmt.Sprintf("pid %d (%s) - stop it or use its HTTP endpoints instead", pid, cmd)
+		}
+		return nil, fmt.Errorf("%w: %s is in use by %s", errDBLocked, path, holder)
+	}
+	return db, err
+}
+
+// boltLockHolder finds a process with path open by scanning /proc.
+// Returns 0 where /proc isn't available (macOS, Windows).
+func boltLockHolder(path string) (int, string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, ""
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		target, err := os.Readlink(fd)
+		if err != nil || target != abs {
+			continue
+		}
+
+		pid, err := strconv.Atoi(strings.Split(fd, "/")[2])
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+
+		cmdline, _ := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+		cmd := strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+		if cmd == "" {
+			cmd = "unknown"
+		}
+		return pid, cmd
+	}
+	return 0, ""
+}
+
+// countBucket returns the number of keys in a bucket (0 if it doesn't exist)
+func countBucket(db *bolt.DB, bucket string) (int, error) {
+	var n int
+	err := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(bucket)); b != nil {
+			n = b.Stats().KeyN
+		}
+		return nil
+	})
+	return n, err
+}
+
+// pruneBucket deletes every entry for which expired returns true and reports
+// how many were removed. Freed pages are reused by later writes.
+func pruneBucket(db *bolt.DB, bucket string, expired func(v []byte) bool) (int, error) {
+	var n int
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			if expired(v) {
+				keysToDelete = append(keysToDelete, k)
+			}
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(keysToDelete)
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,767 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CalDAV reuses calendarBucket/calendarMetaBucket in its own caldav.db, so
+// events are stored exactly like Google's
+const caldavNS = "urn:ietf:params:xml:ns:caldav"
+
+// caldavCalendar is one calendar collection found under caldav_url
+type caldavCalendar struct {
+	URL  string // absolute collection URL
+	Name string // displayname ("" = last path segment)
+}
+
+// CalDAVSyncer syncs events from a CalDAV server (Fastmail, iCloud, Nextcloud, ...)
+// into the same Calendar collection as Google Calendar
+type CalDAVSyncer struct {
+	client     *http.Client
+	db         *bolt.DB
+	url        string // a calendar, a calendar home, or a principal/server URL
+	user       string
+	password   string
+	names      map[string]string // calendar name or URL -> choice label (calendar_names)
+	collection string            // calendar_collection override ("" = Calendar)
+	footer     string            // link-back footer template ("" = off)
+	syncLabel  string            // require_sync_label: only events whose description has #label ("" = all)
+
+	authMu sync.Mutex
+	digest *digestChallenge // set once the server asks for digest auth
+	nc     int              // digest nonce count
+
+	calendars   []caldavCalendar // discovered collections, reused for calendarNamesTTL
+	calendarsAt time.Time
+
+	unchangedCount   // for /status
+	startupWatermark // calendar_since_startup
+	deliveryLead     // calendar_deliver_before
+}
+
+// NewCalDAVSyncer creates a new syncer
+func NewCalDAVSyncer(serverURL, user, password, dataDir string) (*CalDAVSyncer, error) {
+	if _, err := url.Parse(serverURL); err != nil {
+		return nil, fmt.Errorf("invalid caldav_url: %w", err)
+	}
+
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "caldav.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(calendarBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(calendarMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &CalDAVSyncer{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		db:       db,
+		url:      serverURL,
+		user:     user,
+		password: password,
+	}, nil
+}
+
+// Close closes the database
+func (s *CalDAVSyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of cached events
+func (s *CalDAVSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, calendarBucket)
+}
+
+// Prune implements pruner: drops events that ended before cutoff, never
+// reaching into the sync window
+func (s *CalDAVSyncer) Prune(cutoff time.Time) (int, error) {
+	if windowStart := time.Now().AddDate(0, 0, -7); cutoff.After(windowStart) {
+		cutoff = windowStart
+	}
+	return pruneBucket(s.db, calendarBucket, func(v []byte) bool {
+		var event CalendarEvent
+		if err := json.Unmarshal(v, &event); err != nil {
+			return false
+		}
+		return event.End.Before(cutoff)
+	})
+}
+
+// ClearCache clears all cached events from the database
+func (s *CalDAVSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(calendarBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Name implements Syncer
+func (s *CalDAVSyncer) Name() string {
+	return "caldav"
+}
+
+// Verify implements verifier: a PROPFIND on caldav_url (401 is an invalid password)
+func (s *CalDAVSyncer) Verify(ctx context.Context) error {
+	_, err := s.propfind(ctx, s.url, "0", `<d:prop><d:resourcetype/></d:prop>`)
+	return err
+}
+
+// Sync implements Syncer: fetches events in the sync window and renders changes
+// as queue items, exactly like the Google Calendar sync
+func (s *CalDAVSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	calendars, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched := make(map[string]caldavCalendar, len(calendars))
+	keys := make([]string, 0, len(calendars))
+	for _, cal := range calendars {
+		fetched[cal.URL] = cal
+		keys = append(keys, cal.URL)
+	}
+	results := fetchConcurrently(ctx, keys, defaultSyncConcurrency, func(ctx context.Context, calURL string) ([]CalendarEvent, error) {
+		return s.fetchEvents(ctx, fetched[calURL])
+	})
+
+	var items []QueueItem
+	var created, updated, cancelled, unchanged, past, failed int
+	var firstErr error
+	for _, r := range results {
+		if r.err != nil {
+			logger.Warn("caldav sync: calendar failed", "calendar", fetched[r.key].Name, "error", r.err)
+			failed++
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		for _, event := range r.value {
+			if s.syncLabel != "" && !hasHashtag(event.Description, s.syncLabel) {
+				continue
+			}
+
+			upsertResult, err := upsertCalendarEvent(s.db, event)
+			if err != nil {
+				logger.Warn("caldav sync: couldn't store event", "title", event.Title, "error", err)
+				continue
+			}
+			switch upsertResult.Action {
+			case "created":
+				created++
+			case "updated":
+				updated++
+			case "cancelled":
+				cancelled++
+			default:
+				unchanged++
+				continue
+			}
+			if s.beforeStartup(event) {
+				past++
+				continue
+			}
+			event.Verb = upsertResult.Verb
+			item := s.queueItem(event)
+			item.DeliverAfter = s.deliverAfter(event, time.Now())
+			items = append(items, item)
+		}
+	}
+
+	logger.Info("CalDAV sync complete",
+		"calendars", len(calendars),
+		"created", created,
+		"updated", updated,
+		"cancelled", cancelled,
+		"unchanged", unchanged,
+		"before_startup", past,
+		"errors", failed)
+	s.unchanged = unchanged
+
+	// Only fail the run when nothing could be fetched
+	if failed > 0 && failed == len(results) {
+		return nil, firstErr
+	}
+	return items, nil
+}
+
+// queueItem renders an event with the configured collection and footer
+func (s *CalDAVSyncer) queueItem(event CalendarEvent) QueueItem {
+	event.Collection = s.collection
+	event.Footer = s.footer
+	return QueueItem{
+		ID:         fmt.Sprintf("caldav-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      event.Title,
+		Content:    event.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Priority:   priorityHigh,
+		Source:     "caldav",
+		ExternalID: event.ID,
+		SourceTime: event.End,
+		Verb:       event.Verb,
+	}
+}
+
+// Replay implements replayer: the cached event as created (or cancelled)
+func (s *CalDAVSyncer) Replay(id string) (QueueItem, error) {
+	event, err := loadCached[CalendarEvent](s.db, calendarBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	event.Choice = s.choice(event.CalendarID, event.CalendarName)
+	event.Verb = "created"
+	if event.Status == "cancelled" {
+		event.Verb = "cancelled"
+	}
+	return s.queueItem(event), nil
+}
+
+// ReplayIDs implements replayer
+func (s *CalDAVSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, calendarBucket)
+}
+
+// choice looks up calendar_names by calendar name, then by collection URL
+func (s *CalDAVSyncer) choice(calURL, calName string) string {
+	if label, ok := s.names[strings.ToLower(calName)]; ok {
+		return label
+	}
+	return s.names[strings.ToLower(calURL)]
+}
+
+// ============================================================================
+// Discovery
+// ============================================================================
+
+// davMultistatus is a WebDAV 207 Multi-Status response
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"DAV: href"`
+	Propstat []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Status string  `xml:"DAV: status"`
+	Prop   davProp `xml:"DAV: prop"`
+}
+
+type davProp struct {
+	DisplayName  string `xml:"DAV: displayname"`
+	ResourceType struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	Components []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set>comp"`
+	Principal    string `xml:"DAV: current-user-principal>href"`
+	HomeSet      string `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set>href"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+// found returns the props the server returned with a 2xx status
+func (r davResponse) found() davProp {
+	var prop davProp
+	for _, ps := range r.Propstat {
+		if strings.Contains(ps.Status, " 2") {
+			p := ps.Prop
+			if p.DisplayName != "" {
+				prop.DisplayName = p.DisplayName
+			}
+			if p.ResourceType.Calendar != nil {
+				prop.ResourceType = p.ResourceType
+			}
+			prop.Components = append(prop.Components, p.Components...)
+			if p.Principal != "" {
+				prop.Principal = p.Principal
+			}
+			if p.HomeSet != "" {
+				prop.HomeSet = p.HomeSet
+			}
+			if p.CalendarData != "" {
+				prop.CalendarData = p.CalendarData
+			}
+		}
+	}
+	return prop
+}
+
+// hasEvents reports whether a calendar collection holds VEVENTs (not just tasks)
+func (p davProp) hasEvents() bool {
+	if len(p.Components) == 0 {
+		return true
+	}
+	for _, c := range p.Components {
+		if strings.EqualFold(c.Name, "VEVENT") {
+			return true
+		}
+	}
+	return false
+}
+
+// discover finds the calendars to sync: caldav_url itself when it's a
+// calendar, else every event calendar in the home set it (or its principal)
+// points to. The result is reused for calendarNamesTTL.
+func (s *CalDAVSyncer) discover(ctx context.Context) ([]caldavCalendar, error) {
+	if s.calendars != nil && time.Since(s.calendarsAt) < calendarNamesTTL {
+		return s.calendars, nil
+	}
+
+	self, err := s.propfind(ctx, s.url, "0", `<d:prop><d:resourcetype/><d:displayname/><d:current-user-principal/><c:calendar-home-set/></d:prop>`)
+	if err != nil {
+		return nil, err
+	}
+	var prop davProp
+	if len(self.Responses) > 0 {
+		prop = self.Responses[0].found()
+	}
+	if prop.ResourceType.Calendar != nil {
+		s.calendars = []caldavCalendar{{URL: s.url, Name: calendarDisplayName(s.url, prop.DisplayName)}}
+		s.calendarsAt = time.Now()
+		return s.calendars, nil
+	}
+
+	home := s.url
+	switch {
+	case prop.HomeSet != "":
+		home = s.resolve(s.url, prop.HomeSet)
+	case prop.Principal != "":
+		principalURL := s.resolve(s.url, prop.Principal)
+		principal, err := s.propfind(ctx, principalURL, "0", `<d:prop><c:calendar-home-set/></d:prop>`)
+		if err != nil {
+			return nil, fmt.Errorf("principal lookup: %w", err)
+		}
+		if len(principal.Responses) > 0 {
+			if homeSet := principal.Responses[0].found().HomeSet; homeSet != "" {
+				home = s.resolve(principalURL, homeSet)
+			}
+		}
+	}
+
+	list, err := s.propfind(ctx, home, "1", `<d:prop><d:resourcetype/><d:displayname/><c:supported-calendar-component-set/></d:prop>`)
+	if err != nil {
+		return nil, fmt.Errorf("listing calendars: %w", err)
+	}
+	var calendars []caldavCalendar
+	for _, r := range list.Responses {
+		p := r.found()
+		if p.ResourceType.Calendar == nil || !p.hasEvents() {
+			continue
+		}
+		calURL := s.resolve(home, r.Href)
+		calendars = append(calendars, caldavCalendar{URL: calURL, Name: calendarDisplayName(calURL, p.DisplayName)})
+	}
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("no event calendars found at %s", home)
+	}
+
+	s.calendars = calendars
+	s.calendarsAt = time.Now()
+	return calendars, nil
+}
+
+// calendarDisplayName falls back to the collection's last path segment
+func calendarDisplayName(calURL, displayName string) string {
+	if displayName != "" {
+		return displayName
+	}
+	u, err := url.Parse(calURL)
+	if err != nil {
+		return calURL
+	}
+	name, _ := url.PathUnescape(filepath.Base(strings.TrimSuffix(u.Path, "/")))
+	return name
+}
+
+// resolve makes an href from a multistatus response absolute
+func (s *CalDAVSyncer) resolve(base, href string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return b.ResolveReference(ref).String()
+}
+
+// ============================================================================
+// Events
+// ============================================================================
+
+// fetchEvents runs a calendar-query REPORT for VEVENTs in the sync window (the
+// same one week back, twelve forward as the Google sync). The server expands
+// recurring events into instances, so every occurrence syncs on its own.
+func (s *CalDAVSyncer) fetchEvents(ctx context.Context, cal caldavCalendar) ([]CalendarEvent, error) {
+	now := time.Now().UTC()
+	start := now.AddDate(0, 0, -7).Format("20060102T150405Z")
+	end := now.AddDate(0, 0, 84).Format("20060102T150405Z")
+
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="%s">
+  <d:prop>
+    <d:getetag/>
+    <c:calendar-data><c:expand start="%s" end="%s"/></c:calendar-data>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"><c:time-range start="%s" end="%s"/></c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`, caldavNS, start, end, start, end)
+
+	ms, err := s.multistatus(ctx, "REPORT", cal.URL, "1", body)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("caldav sync: fetched", "calendar", cal.Name, "raw_count", len(ms.Responses))
+
+	var events []CalendarEvent
+	for _, r := range ms.Responses {
+		data := r.found().CalendarData
+		if data == "" {
+			continue
+		}
+		parsed, err := s.convertEvents(cal, data)
+		if err != nil {
+			logger.Warn("caldav sync: skipping unparseable event", "href", r.Href, "error", err)
+			continue
+		}
+		events = append(events, parsed...)
+	}
+	return events, nil
+}
+
+// convertEvents maps every VEVENT in one calendar object resource to CalendarEvents
+func (s *CalDAVSyncer) convertEvents(cal caldavCalendar, data string) ([]CalendarEvent, error) {
+	root, err := parseICal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []CalendarEvent
+	for _, vcal := range root.Children {
+		if vcal.Name != "VCALENDAR" {
+			continue
+		}
+		zones := icalZones(vcal)
+		for _, vevent := range vcal.Children {
+			if vevent.Name != "VEVENT" {
+				continue
+			}
+			event, err := s.convertEvent(cal, vevent, zones)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *CalDAVSyncer) convertEvent(cal caldavCalendar, vevent *icalComponent, zones map[string]*time.Location) (CalendarEvent, error) {
+	uid := vevent.text("UID")
+	if uid == "" {
+		return CalendarEvent{}, fmt.Errorf("VEVENT without UID")
+	}
+
+	// Each instance of a recurring event is its own record
+	id := "caldav_" + uid
+	if p, ok := vevent.prop("RECURRENCE-ID"); ok {
+		if t, _, err := parseICalTime(p, zones); err == nil {
+			id += "_" + t.UTC().Format("20060102T150405Z")
+		}
+	}
+
+	status := strings.ToLower(vevent.text("STATUS"))
+	if status == "" {
+		status = "confirmed"
+	}
+
+	event := CalendarEvent{
+		ID:           id,
+		CalendarID:   cal.URL,
+		CalendarName: cal.Name,
+		Choice:       s.choice(cal.URL, cal.Name),
+		Title:        vevent.text("SUMMARY"),
+		Description:  vevent.text("DESCRIPTION"),
+		Location:     vevent.text("LOCATION"),
+		Status:       status,
+	}
+
+	// Start, then end from DTEND or DURATION (RFC 5545 defaults: one day for
+	// dates, zero for date-times)
+	p, ok := vevent.prop("DTSTART")
+	if !ok {
+		return CalendarEvent{}, fmt.Errorf("VEVENT %s without DTSTART", uid)
+	}
+	var err error
+	if event.Start, event.AllDay, err = parseICalTime(p, zones); err != nil {
+		return CalendarEvent{}, fmt.Errorf("DTSTART: %w", err)
+	}
+	if p, ok := vevent.prop("DTEND"); ok {
+		if event.End, _, err = parseICalTime(p, zones); err != nil {
+			return CalendarEvent{}, fmt.Errorf("DTEND: %w", err)
+		}
+	} else if p, ok := vevent.prop("DURATION"); ok {
+		d, err := parseICalDuration(p.Value)
+		if err != nil {
+			return CalendarEvent{}, err
+		}
+		event.End = event.Start.Add(d)
+	} else if event.AllDay {
+		event.End = event.Start.AddDate(0, 0, 1)
+	} else {
+		event.End = event.Start
+	}
+
+	// Attendees: common name when given, else the address
+	for _, prop := range vevent.Props {
+		if prop.Name != "ATTENDEE" {
+			continue
+		}
+		event.Guests++
+		if cn := prop.Params["CN"]; cn != "" {
+			event.Attendees = append(event.Attendees, cn)
+		} else {
+			event.Attendees = append(event.Attendees, strings.TrimPrefix(strings.TrimPrefix(prop.Value, "mailto:"), "MAILTO:"))
+		}
+	}
+
+	// Timestamps
+	if p, ok := vevent.prop("CREATED"); ok {
+		event.CreatedAt, _, _ = parseICalTime(p, zones)
+	}
+	if p, ok := vevent.prop("LAST-MODIFIED"); ok {
+		event.UpdatedAt, _, _ = parseICalTime(p, zones)
+	} else if p, ok := vevent.prop("DTSTAMP"); ok {
+		event.UpdatedAt, _, _ = parseICalTime(p, zones)
+	}
+
+	return event, nil
+}
+
+// ============================================================================
+// HTTP (basic and digest auth)
+// ============================================================================
+
+// propfind runs a PROPFIND with the given <d:prop> body
+func (s *CalDAVSyncer) propfind(ctx context.Context, target, depth, prop string) (*davMultistatus, error) {
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="%s">%s</d:propfind>`, caldavNS, prop)
+	return s.multistatus(ctx, "PROPFIND", target, depth, body)
+}
+
+// multistatus sends a WebDAV request and decodes its 207 response
+func (s *CalDAVSyncer) multistatus(ctx context.Context, method, target, depth, body string) (*davMultistatus, error) {
+	resp, err := s.do(ctx, method, target, depth, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: caldav server returned 401", errInvalidToken)
+	case resp.StatusCode != http.StatusMultiStatus:
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("caldav %s %s returned %d: %s", method, target, resp.StatusCode, string(data))
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	return &ms, nil
+}
+
+// do sends one request, answering a digest challenge with a single retry.
+// Basic credentials go with the first request until the server asks for digest.
+func (s *CalDAVSyncer) do(ctx context.Context, method, target, depth, body string) (*http.Response, error) {
+	resp, err := s.send(ctx, method, target, depth, body)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || s.user == "" {
+		return resp, err
+	}
+
+	var challenge *digestChallenge
+	for _, h := range resp.Header.Values("WWW-Authenticate") {
+		if c, ok := parseDigestChallenge(h); ok {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	s.authMu.Lock()
+	s.digest, s.nc = challenge, 0
+	s.authMu.Unlock()
+	return s.send(ctx, method, target, depth, body)
+}
+
+func (s *CalDAVSyncer) send(ctx context.Context, method, target, depth, body string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", depth)
+
+	if s.user != "" {
+		s.authMu.Lock()
+		if s.digest != nil {
+			s.nc++
+			req.Header.Set("Authorization", s.digest.authorization(method, req.URL.RequestURI(), s.user, s.password, s.nc))
+		} else {
+			req.SetBasicAuth(s.user, s.password)
+		}
+		s.authMu.Unlock()
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav %s failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+// digestChallenge is a parsed WWW-Authenticate: Digest header (RFC 7616)
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // MD5 (default), MD5-sess, SHA-256, SHA-256-sess
+	qop       bool   // server offered qop=auth
+}
+
+// parseDigestChallenge parses a Digest challenge; ok is false for other schemes
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+
+	c := &digestChallenge{algorithm: "MD5"}
+	for _, param := range splitQuoted(rest, ',') {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(key) {
+		case "realm":
+			c.realm = value
+		case "nonce":
+			c.nonce = value
+		case "opaque":
+			c.opaque = value
+		case "algorithm":
+			c.algorithm = value
+		case "qop":
+			for _, q := range strings.Split(value, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = true
+				}
+			}
+		}
+	}
+	return c, c.nonce != ""
+}
+
+// authorization builds the Authorization header for one request
+func (c *digestChallenge) authorization(method, uri, user, password string, nc int) string {
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		return digestHash(newHash, s)
+	}
+
+	cnonceBytes := make([]byte, 8)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	count := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(user + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var response string
+	if c.qop {
+		response = h(ha1 + ":" + c.nonce + ":" + count + ":" + cnonce + ":auth:" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		user, c.realm, c.nonce, uri, c.algorithm, response)
+	if c.qop {
+		header += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s"`, count, cnonce)
+	}
+	if c.opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, c.opaque)
+	}
+	return header
+}
+
+func digestHash(newHash func() hash.Hash, s string) string {
+	h := newHash()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
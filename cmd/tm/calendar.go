@@ -3,20 +3,30 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
 const (
 	calendarBucket     = "calendar_events"
 	calendarMetaBucket = "calendar_meta"
+
+	// calendarNamesTTL is how long the ListCalendars name lookup is reused across syncs
+	calendarNamesTTL = 1 * time.Hour
+
+	// calendarMaxAttendees is requested when re-fetching an event whose guest list Google truncated
+	calendarMaxAttendees = 500
 )
 
 // CalendarEvent represents a stored calendar event
@@ -31,11 +41,21 @@ type CalendarEvent struct {
 	End         time.Time `json:"end"`
 	AllDay      bool      `json:"all_day"`
 	Attendees   []string  `json:"attendees"`
+	Guests      int       `json:"guests,omitempty"`            // guest count (a lower bound when Omitted)
+	Omitted     bool      `json:"attendees_omitted,omitempty"` // Attendees is still partial after re-fetching
 	MeetLink    string    `json:"meet_link"`
+	HtmlLink    string    `json:"html_link,omitempty"` // event page in Google Calendar
 	Status      string    `json:"status"` // confirmed, tentative, cancelled
+	ColorID     string    `json:"color_id,omitempty"`  // Google event colorId ("" = the calendar's color)
+	Color       string    `json:"color,omitempty"`     // its name in Google Calendar: tomato, basil, ...
+	ColorHex    string    `json:"color_hex,omitempty"` // its background from the Colors.Get palette
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Verb        string    `json:"-"` // transient: created, updated, cancelled (not stored)
+	Choice      string    `json:"-"` // transient: calendar label from calendar_names (not stored)
+	Collection  string    `json:"-"` // transient: target collection from calendar_collection (not stored)
+	Footer      string    `json:"-"` // transient: link-back footer template ("" = off, not stored)
+	Category    string    `json:"-"` // transient: calendar_color_categories label for Color (not stored)
 }
 
 // ToMarkdown returns the event as markdown with YAML frontmatter
@@ -43,15 +63,22 @@ func (e CalendarEvent) ToMarkdown() string {
 	var b strings.Builder
 
 	// YAML frontmatter
+	collection := e.Collection
+	if collection == "" {
+		collection = "Calendar"
+	}
 	b.WriteString("---\n")
-	b.WriteString("collection: Calendar\n")
+	b.WriteString(fmt.Sprintf("collection: %s\n", collection))
 	b.WriteString(fmt.Sprintf("external_id: %s\n", e.ID))
 	if e.Verb != "" {
 		b.WriteString(fmt.Sprintf("verb: %s\n", e.Verb))
 	}
 	b.WriteString(fmt.Sprintf("title: %s\n", e.Title))
-	// Normalize calendar name to match choice IDs
-	calendarChoice := normalizeCalendarName(e.CalendarID, e.CalendarName)
+	// Explicit calendar_names mapping wins; otherwise normalize to match choice IDs
+	calendarChoice := e.Choice
+	if calendarChoice == "" {
+		calendarChoice = normalizeCalendarName(e.CalendarID, e.CalendarName)
+	}
 	b.WriteString(fmt.Sprintf("calendar: %s\n", calendarChoice))
 	b.WriteString(fmt.Sprintf("start: %d\n", e.Start.Unix()))
 	b.WriteString(fmt.Sprintf("end: %d\n", e.End.Unix()))
@@ -62,12 +89,31 @@ func (e CalendarEvent) ToMarkdown() string {
 		b.WriteString(fmt.Sprintf("location: %s\n", e.Location))
 	}
 	if len(e.Attendees) > 0 {
-		b.WriteString(fmt.Sprintf("attendees: %s\n", strings.Join(e.Attendees, ", ")))
+		attendees := strings.Join(e.Attendees, ", ")
+		if e.Omitted {
+			attendees += ", + more"
+		}
+		b.WriteString(fmt.Sprintf("attendees: %s\n", attendees))
+	}
+	if e.Guests > 0 {
+		b.WriteString(fmt.Sprintf("guests: %d\n", e.Guests))
 	}
 	if e.MeetLink != "" {
 		b.WriteString(fmt.Sprintf("meet_link: %s\n", e.MeetLink))
 	}
+	if e.HtmlLink != "" {
+		b.WriteString(fmt.Sprintf("html_link: %s\n", e.HtmlLink))
+	}
 	b.WriteString(fmt.Sprintf("status: %s\n", e.Status))
+	if e.Color != "" {
+		b.WriteString(fmt.Sprintf("color: %s\n", e.Color))
+		if e.ColorHex != "" {
+			b.WriteString(fmt.Sprintf("color_hex: %s\n", e.ColorHex))
+		}
+	}
+	if e.Category != "" {
+		b.WriteString(fmt.Sprintf("category: %s\n", e.Category))
+	}
 	b.WriteString("---\n\n")
 
 	// Body (description)
@@ -75,7 +121,9 @@ func (e CalendarEvent) ToMarkdown() string {
 		b.WriteString(e.Description)
 	}
 
-	return b.String()
+	writeFooter(&b, e.Footer, "Open in Calendar", e.HtmlLink)
+
+	return filterFrontmatter(b.String())
 }
 
 // CalendarInfo represents a user's calendar
@@ -88,9 +136,25 @@ type CalendarInfo struct {
 
 // CalendarSyncer handles syncing Google Calendar events
 type CalendarSyncer struct {
-	service   *calendar.Service
-	db        *bolt.DB
-	calendars []string // Calendar IDs to sync
+	service     *calendar.Service
+	db          *bolt.DB
+	calendars   []string          // Calendar IDs to sync
+	minDuration time.Duration     // Skip timed events shorter than this (0 = keep all)
+	concurrency int               // Calendars fetched in parallel
+	names       map[string]string // Calendar ID -> choice label (calendar_names)
+	collection  string            // calendar_collection override ("" = Calendar)
+	footer      string            // link-back footer template ("" = off)
+	syncLabel   string            // require_sync_label: only events whose description has #label ("" = all)
+
+	calendarNames   map[string]string // calendar ID -> display name (persisted as calendarNamesKey)
+	calendarNamesAt time.Time
+	eventColors     map[string]string // event colorId -> hex (persisted as eventColorsKey)
+	eventColorsAt   time.Time
+	categories      map[string]string // calendar_color_categories: color name or id -> category
+
+	unchangedCount   // for /status
+	startupWatermark // calendar_since_startup
+	deliveryLead     // calendar_deliver_before
 }
 
 // CalendarTokens holds OAuth tokens for Google Calendar
@@ -126,7 +190,7 @@ func NewCalendarSyncer(tokens *CalendarTokens, calendars []string, dataDir strin
 
 	// Open bbolt database
 	dbPath := filepath.Join(dataDir, "calendar.db")
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	db, err := openBolt(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -147,9 +211,10 @@ func NewCalendarSyncer(tokens *CalendarTokens, calendars []string, dataDir strin
 	}
 
 	return &CalendarSyncer{
-		service:   srv,
-		db:        db,
-		calendars: calendars,
+		service:     srv,
+		db:          db,
+		calendars:   calendars,
+		concurrency: defaultSyncConcurrency,
 	}, nil
 }
 
@@ -158,6 +223,26 @@ func (s *CalendarSyncer) Close() error {
 	return s.db.Close()
 }
 
+// CachedCount implements Syncer: the number of cached events
+func (s *CalendarSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, calendarBucket)
+}
+
+// Prune implements pruner: drops events that ended before cutoff. The cutoff
+// never reaches into the sync window, or pruned events would come back as new.
+func (s *CalendarSyncer) Prune(cutoff time.Time) (int, error) {
+	if windowStart := time.Now().AddDate(0, 0, -7); cutoff.After(windowStart) {
+		cutoff = windowStart
+	}
+	return pruneBucket(s.db, calendarBucket, func(v []byte) bool {
+		var event CalendarEvent
+		if err := json.Unmarshal(v, &event); err != nil {
+			return false
+		}
+		return event.End.Before(cutoff)
+	})
+}
+
 // ClearCache clears all cached events from the database
 func (s *CalendarSyncer) ClearCache() error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -201,6 +286,128 @@ func (s *CalendarSyncer) ListCalendars(ctx context.Context) ([]CalendarInfo, err
 	return calendars, nil
 }
 
+// calendarNamesKey stores the cached name lookup in calendarMetaBucket
+const calendarNamesKey = "calendar_names"
+
+// cachedCalendarNames is the calendar_names entry in calendarMetaBucket
+type cachedCalendarNames struct {
+	Names     map[string]string `json:"names"`
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+// lookupCalendarNames returns calendar ID -> display name, calling ListCalendars
+// at most once per calendarNamesTTL. The result is kept in calendarMetaBucket so
+// restarts reuse it, and a failed refresh falls back to the stale names rather
+// than blanking the calendar in the frontmatter. Only SyncChanges calls it, and
+// the scheduler serializes those, so no locking is needed.
+func (s *CalendarSyncer) lookupCalendarNames(ctx context.Context) map[string]string {
+	if s.calendarNames == nil {
+		s.loadCalendarNames()
+	}
+	if s.calendarNames != nil && time.Since(s.calendarNamesAt) < calendarNamesTTL {
+		return s.calendarNames
+	}
+
+	calendars, err := s.ListCalendars(ctx)
+	if err != nil {
+		logger.Warn("calendar names lookup failed, using cached names", "error", err, "cached", len(s.calendarNames))
+		return s.calendarNames
+	}
+
+	names := make(map[string]string, len(calendars))
+	for _, cal := range calendars {
+		names[cal.ID] = cal.Name
+	}
+	s.calendarNames = names
+	s.calendarNamesAt = time.Now()
+	s.storeCalendarNames()
+	return names
+}
+
+// loadCalendarNames reads the persisted name lookup, if any
+func (s *CalendarSyncer) loadCalendarNames() {
+	s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(calendarMetaBucket)).Get([]byte(calendarNamesKey))
+		if data == nil {
+			return nil
+		}
+		var cached cachedCalendarNames
+		if err := json.Unmarshal(data, &cached); err != nil {
+			return nil
+		}
+		s.calendarNames = cached.Names
+		s.calendarNamesAt = cached.FetchedAt
+		return nil
+	})
+}
+
+// storeCalendarNames persists the current name lookup
+func (s *CalendarSyncer) storeCalendarNames() {
+	data, err := json.Marshal(cachedCalendarNames{Names: s.calendarNames, FetchedAt: s.calendarNamesAt})
+	if err != nil {
+		return
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(calendarMetaBucket)).Put([]byte(calendarNamesKey), data)
+	})
+	if err != nil {
+		logger.Warn("failed to cache calendar names", "error", err)
+	}
+}
+
+// CalendarRename is a calendar whose display name changed since the last lookup
+type CalendarRename struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CalendarNamesChange is what RefreshCalendarNames found compared to the cached names
+type CalendarNamesChange struct {
+	Calendars []CalendarInfo   `json:"calendars"` // everything the account can see now
+	Added     []CalendarInfo   `json:"added"`
+	Removed   []CalendarInfo   `json:"removed"` // Name is the last cached name
+	Renamed   []CalendarRename `json:"renamed"`
+}
+
+// RefreshCalendarNames re-fetches the calendar list regardless of calendarNamesTTL,
+// replaces the cached names, and reports what changed. Callers must not run it
+// concurrently with SyncChanges (the server holds the scheduler's lock).
+func (s *CalendarSyncer) RefreshCalendarNames(ctx context.Context) (*CalendarNamesChange, error) {
+	if s.calendarNames == nil {
+		s.loadCalendarNames()
+	}
+
+	calendars, err := s.ListCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &CalendarNamesChange{Calendars: calendars}
+	names := make(map[string]string, len(calendars))
+	for _, cal := range calendars {
+		names[cal.ID] = cal.Name
+		old, known := s.calendarNames[cal.ID]
+		switch {
+		case !known:
+			change.Added = append(change.Added, cal)
+		case old != cal.Name:
+			change.Renamed = append(change.Renamed, CalendarRename{ID: cal.ID, From: old, To: cal.Name})
+		}
+	}
+	for id, name := range s.calendarNames {
+		if _, ok := names[id]; !ok {
+			change.Removed = append(change.Removed, CalendarInfo{ID: id, Name: name})
+		}
+	}
+	sort.Slice(change.Removed, func(i, j int) bool { return change.Removed[i].ID < change.Removed[j].ID })
+
+	s.calendarNames = names
+	s.calendarNamesAt = time.Now()
+	s.storeCalendarNames()
+	return change, nil
+}
+
 // CalendarSyncResult contains sync statistics
 type CalendarSyncResult struct {
 	Created   []CalendarEvent
@@ -210,8 +417,8 @@ type CalendarSyncResult struct {
 	Errors    []error
 }
 
-// Sync fetches events and returns changes
-func (s *CalendarSyncer) Sync(ctx context.Context) (*CalendarSyncResult, error) {
+// SyncChanges fetches events and returns changes
+func (s *CalendarSyncer) SyncChanges(ctx context.Context) (*CalendarSyncResult, error) {
 	result := &CalendarSyncResult{
 		Created:   make([]CalendarEvent, 0),
 		Updated:   make([]CalendarEvent, 0),
@@ -219,23 +426,26 @@ func (s *CalendarSyncer) Sync(ctx context.Context) (*CalendarSyncResult, error)
 		Errors:    make([]error, 0),
 	}
 
-	// Get calendar names for display
-	calendarNames := make(map[string]string)
-	if calendars, err := s.ListCalendars(ctx); err == nil {
-		for _, cal := range calendars {
-			calendarNames[cal.ID] = cal.Name
-		}
-	}
+	calendarNames := s.lookupCalendarNames(ctx)
+	s.lookupEventColors(ctx)
 
-	for _, calendarID := range s.calendars {
-		events, err := s.syncCalendar(ctx, calendarID, calendarNames[calendarID])
+	fetched := fetchConcurrently(ctx, s.calendars, s.concurrency, func(ctx context.Context, calendarID string) ([]CalendarEvent, error) {
+		return s.syncCalendar(ctx, calendarID, calendarNames[calendarID])
+	})
+
+	for _, f := range fetched {
+		calendarID, events, err := f.key, f.value, f.err
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Errorf("failed to sync %s: %w", calendarID, err))
 			continue
 		}
 
 		for _, event := range events {
-			upsertResult, err := s.upsert(event)
+			if s.syncLabel != "" && !hasHashtag(event.Description, s.syncLabel) {
+				continue
+			}
+
+			upsertResult, err := upsertCalendarEvent(s.db, event)
 			if err != nil {
 				result.Errors = append(result.Errors, err)
 				continue
@@ -258,6 +468,27 @@ func (s *CalendarSyncer) Sync(ctx context.Context) (*CalendarSyncResult, error)
 	return result, nil
 }
 
+// hasHashtag reports whether text contains #tag as a whole word, ignoring case
+func hasHashtag(text, tag string) bool {
+	text, tag = strings.ToLower(text), "#"+strings.ToLower(tag)
+	for i := strings.Index(text, tag); i >= 0; {
+		end := i + len(tag)
+		if end == len(text) || !isTagChar(text[end]) {
+			return true
+		}
+		next := strings.Index(text[end:], tag)
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+	return false
+}
+
+func isTagChar(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z'
+}
+
 func (s *CalendarSyncer) syncCalendar(ctx context.Context, calendarID, calendarName string) ([]CalendarEvent, error) {
 	// Fetch events from 1 week ago to 12 weeks ahead
 	now := time.Now()
@@ -286,25 +517,112 @@ func (s *CalendarSyncer) syncCalendar(ctx context.Context, calendarID, calendarN
 			"google_id", item.Id,
 			"title", item.Summary,
 			"recurring_id", item.RecurringEventId)
+		// Large meetings come back with a truncated guest list; fetch the full one
+		if item.AttendeesOmitted {
+			full, err := s.service.Events.Get(calendarID, item.Id).
+				Context(ctx).
+				MaxAttendees(calendarMaxAttendees).
+				Do()
+			if err != nil {
+				logger.Warn("calendar sync: couldn't fetch full guest list", "title", item.Summary, "error", err)
+			} else {
+				item = full
+			}
+		}
 		event := s.convertEvent(calendarID, calendarName, item)
+		// Filter before caching so changing the threshold doesn't strand entries
+		if s.tooShort(event) {
+			logger.Debug("calendar sync: skipping short event",
+				"title", event.Title,
+				"duration", event.End.Sub(event.Start))
+			continue
+		}
 		result = append(result, event)
 	}
 
 	return result, nil
 }
 
+// sinceStartup is implemented by calendar syncers: tm serve passes its start
+// time so a restart doesn't re-send events that were already over
+type sinceStartup interface {
+	setSince(time.Time)
+}
+
+// startupWatermark skips events that ended, and last changed, before tm serve
+// started. They're still cached, so a later edit is an update as usual; they
+// just aren't queued. Zero since (calendar_since_startup=false, tm sync) = off.
+type startupWatermark struct {
+	since time.Time
+}
+
+func (w *startupWatermark) setSince(t time.Time) {
+	w.since = t
+}
+
+// beforeStartup reports whether the event was over before the server started
+func (w *startupWatermark) beforeStartup(event CalendarEvent) bool {
+	return !w.since.IsZero() && event.End.Before(w.since) && event.UpdatedAt.Before(w.since)
+}
+
+// deliveryLead is calendar_deliver_before: instead of arriving on sync, an
+// upcoming event's record is snoozed (QueueItem.DeliverAfter) until this long
+// before it starts, so meeting notes show up just in time. Zero = off.
+type deliveryLead struct {
+	lead time.Duration
+}
+
+// deliverAfter is when the event's queue item should be delivered ("" = now).
+// All-day events, cancellations and events already inside the lead time go now.
+func (d deliveryLead) deliverAfter(event CalendarEvent, now time.Time) string {
+	if d.lead <= 0 || event.AllDay || event.Status == "cancelled" {
+		return ""
+	}
+	at := event.Start.Add(-d.lead)
+	if !at.After(now) {
+		return ""
+	}
+	return at.Format(time.RFC3339)
+}
+
+// tooShort reports whether a timed event falls under the minimum duration.
+// Zero-duration reminders are always dropped; all-day events never are.
+func (s *CalendarSyncer) tooShort(event CalendarEvent) bool {
+	if event.AllDay {
+		return false
+	}
+	duration := event.End.Sub(event.Start)
+	if duration <= 0 {
+		return true
+	}
+	return duration < s.minDuration
+}
+
 func (s *CalendarSyncer) convertEvent(calendarID, calendarName string, item *calendar.Event) CalendarEvent {
 	id := fmt.Sprintf("gcal_%s", item.Id)
+	if key := seriesInstanceKey(item); key != "" && key != item.Id {
+		// An edited occurrence can come back under a new id; keep it on the
+		// series instance it replaces so it's an update, not a second event
+		logger.Debug("calendar sync: edited recurring instance",
+			"google_id", item.Id,
+			"instance", key,
+			"title", item.Summary)
+		id = fmt.Sprintf("gcal_%s", key)
+	}
 
 	event := CalendarEvent{
 		ID:           id,
 		CalendarID:   calendarID,
 		CalendarName: calendarName,
+		Choice:       s.names[strings.ToLower(calendarID)],
 		Title:        item.Summary,
 		Description:  item.Description,
 		Location:     item.Location,
 		Status:       item.Status,
+		HtmlLink:     item.HtmlLink,
+		ColorID:      item.ColorId,
 	}
+	event.Color, event.ColorHex = eventColor(item.ColorId, s.eventColors)
 
 	// Parse start/end times
 	if item.Start != nil {
@@ -324,6 +642,8 @@ func (s *CalendarSyncer) convertEvent(calendarID, calendarName string, item *cal
 	}
 
 	// Extract attendees
+	event.Guests = len(item.Attendees)
+	event.Omitted = item.AttendeesOmitted
 	for _, attendee := range item.Attendees {
 		if attendee.DisplayName != "" {
 			event.Attendees = append(event.Attendees, attendee.DisplayName)
@@ -355,16 +675,40 @@ func (s *CalendarSyncer) convertEvent(calendarID, calendarName string, item *cal
 	return event
 }
 
+// seriesInstanceKey returns the id Google gives an unmodified occurrence of a
+// recurring series: {recurringEventId}_{original start}, e.g.
+// abc123_20240105T100000Z, or abc123_20240105 for all-day series. Returns ""
+// for events that aren't part of a series.
+func seriesInstanceKey(item *calendar.Event) string {
+	if item.RecurringEventId == "" || item.OriginalStartTime == nil {
+		return ""
+	}
+	original := item.OriginalStartTime
+	if original.DateTime != "" {
+		t, err := time.Parse(time.RFC3339, original.DateTime)
+		if err != nil {
+			return ""
+		}
+		return item.RecurringEventId + "_" + t.UTC().Format("20060102T150405Z")
+	}
+	if original.Date != "" {
+		return item.RecurringEventId + "_" + strings.ReplaceAll(original.Date, "-", "")
+	}
+	return ""
+}
+
 // CalendarUpsertResult contains the result of an upsert operation
 type CalendarUpsertResult struct {
 	Action string // created, updated, cancelled, unchanged
 	Verb   string // created, updated, cancelled
 }
 
-func (s *CalendarSyncer) upsert(event CalendarEvent) (*CalendarUpsertResult, error) {
+// upsertCalendarEvent stores event in db's calendarBucket and reports what
+// changed. Shared by the Google and CalDAV syncers.
+func upsertCalendarEvent(db *bolt.DB, event CalendarEvent) (*CalendarUpsertResult, error) {
 	result := &CalendarUpsertResult{}
 
-	err := s.db.Update(func(tx *bolt.Tx) error {
+	err := db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(calendarBucket))
 
 		existing := b.Get([]byte(event.ID))
@@ -463,12 +807,13 @@ func needsCalendarUpdate(old, new CalendarEvent) bool {
 	return false
 }
 
-// GetTodayEvents returns events for today
+// GetTodayEvents returns events for today in displayLocation
 func (s *CalendarSyncer) GetTodayEvents() ([]CalendarEvent, error) {
 	var events []CalendarEvent
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	now := time.Now().In(displayLocation)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, displayLocation)
 	endOfDay := startOfDay.AddDate(0, 0, 1)
+	today := now.Format("2006-01-02")
 
 	err := s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(calendarBucket))
@@ -477,6 +822,14 @@ func (s *CalendarSyncer) GetTodayEvents() ([]CalendarEvent, error) {
 			if err := json.Unmarshal(v, &event); err != nil {
 				return err
 			}
+			// All-day events are stored as UTC dates (end exclusive): compare
+			// dates, or a zone behind UTC would pick up tomorrow's
+			if event.AllDay {
+				if event.Start.Format("2006-01-02") <= today && today < event.End.Format("2006-01-02") {
+					events = append(events, event)
+				}
+				return nil
+			}
 			// Include if event overlaps with today
 			if event.Start.Before(endOfDay) && event.End.After(startOfDay) {
 				events = append(events, event)
@@ -524,11 +877,40 @@ func (s *CalendarSyncer) GeneratePlanMyDay() (string, error) {
 		return "## Calendar\n\nNo events today.\n", nil
 	}
 
+	// All-day events have no meaningful time (they'd all read 00:00)
+	var allDay, timed []CalendarEvent
+	for _, event := range events {
+		if event.AllDay {
+			allDay = append(allDay, event)
+		} else {
+			timed = append(timed, event)
+		}
+	}
+	sort.Slice(allDay, func(i, j int) bool {
+		return allDay[i].Title < allDay[j].Title
+	})
+	sort.Slice(timed, func(i, j int) bool {
+		return timed[i].Start.Before(timed[j].Start)
+	})
+
 	var b strings.Builder
 	b.WriteString("## Calendar\n\n")
 
-	for _, event := range events {
-		timeStr := event.Start.Format("15:04")
+	if len(allDay) > 0 {
+		b.WriteString("### All Day\n")
+		for _, event := range allDay {
+			b.WriteString(fmt.Sprintf("- [[%s]]\n", event.Title))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(timed) == 0 {
+		b.WriteString("No timed events today.\n")
+		return b.String(), nil
+	}
+
+	for _, event := range timed {
+		timeStr := event.Start.In(displayLocation).Format("15:04")
 		b.WriteString(fmt.Sprintf("### %s [[%s]]\n", timeStr, event.Title))
 
 		if len(event.Attendees) > 0 {
@@ -547,36 +929,34 @@ func (s *CalendarSyncer) GeneratePlanMyDay() (string, error) {
 	return b.String(), nil
 }
 
-// StartPeriodicSync runs sync every interval and calls onChange with new/updated events
-func (s *CalendarSyncer) StartPeriodicSync(ctx context.Context, interval time.Duration, onChange func([]CalendarEvent)) {
-	ticker := time.NewTicker(interval)
-
-	go func() {
-		defer ticker.Stop()
-
-		// Initial sync
-		s.doSync(onChange)
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Info("Calendar sync stopped")
-				return
-			case <-ticker.C:
-				s.doSync(onChange)
-			}
-		}
-	}()
+// Name implements Syncer
+func (s *CalendarSyncer) Name() string {
+	return "calendar"
 }
 
-func (s *CalendarSyncer) doSync(onChange func([]CalendarEvent)) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// Verify implements verifier: lists calendars (a refresh failure or 401/403 is an invalid token)
+func (s *CalendarSyncer) Verify(ctx context.Context) error {
+	_, err := s.service.CalendarList.List().MaxResults(1).Context(ctx).Do()
+	if err == nil {
+		return nil
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden) {
+		return fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	return err
+}
 
-	result, err := s.Sync(ctx)
+// Sync implements Syncer: fetches changes and renders them as queue items.
+// CalendarSyncer has no metaStore, so its items are never held for quiet hours.
+func (s *CalendarSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
 	if err != nil {
-		logger.Error("Calendar sync failed", "error", err)
-		return
+		return nil, err
 	}
 
 	logger.Info("Calendar sync complete",
@@ -585,18 +965,68 @@ func (s *CalendarSyncer) doSync(onChange func([]CalendarEvent)) {
 		"cancelled", len(result.Cancelled),
 		"unchanged", result.Unchanged,
 		"errors", len(result.Errors))
+	s.unchanged = result.Unchanged
 
-	// Notify about changes
 	var changes []CalendarEvent
 	changes = append(changes, result.Created...)
 	changes = append(changes, result.Updated...)
 	changes = append(changes, result.Cancelled...)
 
-	if len(changes) > 0 {
-		onChange(changes)
+	items := make([]QueueItem, 0, len(changes))
+	past := 0
+	for _, event := range changes {
+		if s.beforeStartup(event) {
+			past++
+			continue
+		}
+		item := s.queueItem(event)
+		item.DeliverAfter = s.deliverAfter(event, time.Now())
+		items = append(items, item)
+	}
+	if past > 0 {
+		logger.Info("calendar sync: not queueing events that ended before startup", "count", past)
+	}
+	return items, nil
+}
+
+// queueItem renders an event with the configured collection, footer and color category
+func (s *CalendarSyncer) queueItem(event CalendarEvent) QueueItem {
+	event.Collection = s.collection
+	event.Footer = s.footer
+	event.Category = s.colorCategory(event)
+	return QueueItem{
+		ID:         fmt.Sprintf("cal-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      event.Title,
+		Content:    event.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Priority:   priorityHigh,
+		Source:     "calendar",
+		ExternalID: event.ID,
+		SourceTime: event.End,
+		Verb:       event.Verb,
 	}
 }
 
+// Replay implements replayer: the cached event as created (or cancelled)
+func (s *CalendarSyncer) Replay(id string) (QueueItem, error) {
+	event, err := loadCached[CalendarEvent](s.db, calendarBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	event.Choice = s.names[strings.ToLower(event.CalendarID)]
+	event.Verb = "created"
+	if event.Status == "cancelled" {
+		event.Verb = "cancelled"
+	}
+	return s.queueItem(event), nil
+}
+
+// ReplayIDs implements replayer
+func (s *CalendarSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, calendarBucket)
+}
+
 // normalizeCalendarName converts calendar ID/name to a choice label
 func normalizeCalendarName(calID, calName string) string {
 	// Primary calendar
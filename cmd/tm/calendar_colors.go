@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// eventColorsKey stores the cached Colors.Get event palette in calendarMetaBucket
+	eventColorsKey = "event_colors"
+
+	// eventColorsTTL is how long the palette is reused; Google hardly ever changes it
+	eventColorsTTL = 24 * time.Hour
+)
+
+// googleEventColorNames are the names Google Calendar shows for each event
+// colorId. The API only returns hex values, so the names live here.
+var googleEventColorNames = map[string]string{
+	"1":  "lavender",
+	"2":  "sage",
+	"3":  "grape",
+	"4":  "flamingo",
+	"5":  "banana",
+	"6":  "tangerine",
+	"7":  "peacock",
+	"8":  "graphite",
+	"9":  "blueberry",
+	"10": "basil",
+	"11": "tomato",
+}
+
+// cachedEventColors is the event_colors entry in calendarMetaBucket
+type cachedEventColors struct {
+	Colors    map[string]string `json:"colors"` // colorId -> background hex
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+// lookupEventColors returns the event palette (colorId -> hex), calling
+// Colors.Get at most once per eventColorsTTL. Like lookupCalendarNames it's
+// persisted across restarts, falls back to the stale palette on errors, and
+// is only called from SyncChanges.
+func (s *CalendarSyncer) lookupEventColors(ctx context.Context) map[string]string {
+	if s.eventColors == nil {
+		s.db.View(func(tx *bolt.Tx) error {
+			var cached cachedEventColors
+			if data := tx.Bucket([]byte(calendarMetaBucket)).Get([]byte(eventColorsKey)); data != nil && json.Unmarshal(data, &cached) == nil {
+				s.eventColors, s.eventColorsAt = cached.Colors, cached.FetchedAt
+			}
+			return nil
+		})
+	}
+	if s.eventColors != nil && time.Since(s.eventColorsAt) < eventColorsTTL {
+		return s.eventColors
+	}
+
+	palette, err := s.service.Colors.Get().Context(ctx).Do()
+	if err != nil {
+		logger.Warn("calendar color palette lookup failed, using cached palette", "error", err, "cached", len(s.eventColors))
+		return s.eventColors
+	}
+
+	colors := make(map[string]string, len(palette.Event))
+	for id, def := range palette.Event {
+		colors[id] = def.Background
+	}
+	s.eventColors, s.eventColorsAt = colors, time.Now()
+
+	data, err := json.Marshal(cachedEventColors{Colors: colors, FetchedAt: s.eventColorsAt})
+	if err == nil {
+		err = s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(calendarMetaBucket)).Put([]byte(eventColorsKey), data)
+		})
+	}
+	if err != nil {
+		logger.Warn("failed to cache calendar color palette", "error", err)
+	}
+	return colors
+}
+
+// eventColor names an event's colorId and looks up its hex in the palette.
+// Events without their own color (they use the calendar's) return "".
+func eventColor(colorID string, palette map[string]string) (name, hex string) {
+	if colorID == "" {
+		return "", ""
+	}
+	name = googleEventColorNames[colorID]
+	if name == "" {
+		name = "color-" + colorID
+	}
+	return name, palette[colorID]
+}
+
+// colorCategory is the calendar_color_categories label for an event color,
+// matched by name (tomato) or colorId (11)
+func (s *CalendarSyncer) colorCategory(event CalendarEvent) string {
+	if event.Color == "" {
+		return ""
+	}
+	if category := s.categories[strings.ToLower(event.Color)]; category != "" {
+		return category
+	}
+	return s.categories[event.ColorID]
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFilePath returns ~/.config/tm/config
+func configFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "tm", "config")
+}
+
+// readConfigValue returns the raw (unexpanded) value of key in the config file.
+// Like loadConfig, the first uncommented key= line wins.
+func readConfigValue(key string) (string, bool, error) {
+	data, err := os.ReadFile(configFilePath())
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, key+"=") {
+			return strings.TrimPrefix(line, key+"="), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// writeConfigValue sets key=value in the config file, rewriting the first
+// key= line in place and dropping any later duplicates. Comments and other
+// lines are preserved. unset removes every key= line instead.
+func writeConfigValue(key, value string, unset bool) error {
+	path := configFilePath()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(string(data), "\n")
+	}
+
+	var out []string
+	found := false
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			out = append(out, line)
+			continue
+		}
+		if !unset && !found {
+			out = append(out, key+"="+value)
+		}
+		found = true
+	}
+	if !found && !unset {
+		// Keep a trailing newline trailing
+		if n := len(out); n > 0 && out[n-1] == "" {
+			out = append(out[:n-1], key+"="+value, "")
+		} else {
+			out = append(out, key+"="+value)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(out, "\n")), 0600)
+}
+
+// runConfig handles `tm config get|set|unset <key> [value]`
+func runConfig(args []string) {
+	usage := func() {
+		fmt.Println("Usage: tm config get <key>")
+		fmt.Println("       tm config set <key> <value>")
+		fmt.Println("       tm config unset <key>")
+	}
+
+	if len(args) < 2 {
+		usage()
+		return
+	}
+	cmd, key := args[0], args[1]
+	if key == "" || strings.ContainsAny(key, "= \t#") {
+		fmt.Fprintf(os.Stderr, "Error: invalid key %q\n", key)
+		os.Exit(1)
+	}
+
+	switch cmd {
+	case "get":
+		value, ok, err := readConfigValue(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		fmt.Println(value)
+
+	case "set":
+		if len(args) < 3 {
+			usage()
+			return
+		}
+		value := strings.Join(args[2:], " ")
+		if err := writeConfigValue(key, value, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf(em("✓ %s=%s\n"), key, value)
+
+	case "unset":
+		if err := writeConfigValue(key, "", true); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf(em("✓ Unset %s\n"), key)
+
+	default:
+		usage()
+	}
+}
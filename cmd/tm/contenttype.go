@@ -0,0 +1,46 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Content types a push is tagged with (QueueItem.ContentType). Markdown is
+// parsed by the plugin into headings, lists and formatting; text is inserted
+// line by line exactly as written, so a stray "#" or "*" stays a character.
+const (
+	contentTypeText     = "text"
+	contentTypeMarkdown = "markdown"
+	contentTypeAuto     = "auto" // --content-type / content_type only: detect per push
+)
+
+// validContentType reports whether v is a --content-type value ("" = unset)
+func validContentType(v string) bool {
+	switch v {
+	case "", contentTypeAuto, contentTypeText, contentTypeMarkdown:
+		return true
+	}
+	return false
+}
+
+// markdownLineRe matches lines that only make sense as markdown: headings,
+// list items, tasks, quotes, code fences and table rows
+var markdownLineRe = regexp.MustCompile(`^\s*(#{1,6}\s|[-*+]\s|\d+\.\s|>\s?|` + "```" + `|\|.*\|\s*$)`)
+
+// markdownInlineRe matches inline markdown: links, bold, inline code
+var markdownInlineRe = regexp.MustCompile("\\[[^\\]]+\\]\\([^)]+\\)|\\*\\*[^*]+\\*\\*|`[^`]+`")
+
+// detectContentType guesses whether content is markdown or plain text.
+// Frontmatter or any block or inline markdown makes it markdown; everything
+// else (log lines, command output, prose) is text.
+func detectContentType(content string) string {
+	if strings.HasPrefix(content, "---\n") {
+		return contentTypeMarkdown
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if markdownLineRe.MatchString(line) || markdownInlineRe.MatchString(line) {
+			return contentTypeMarkdown
+		}
+	}
+	return contentTypeText
+}
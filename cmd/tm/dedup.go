@@ -0,0 +1,130 @@
+package main
+
+import "strings"
+
+// dedupKeys is dedup_key: per-source templates like {external_id} or
+// {title}:{start}. When a new item's key matches one still waiting in the
+// queue, the new item replaces it, so the plugin only sees the latest.
+// Keys are compared across sources ({source} names the item's own); sources
+// without a template (and no * entry) are never deduplicated.
+type dedupKeys map[string]string
+
+// parseDedupKeys parses dedup_key: source:template pairs, comma separated.
+// A template without a source, or with source *, applies to every source.
+func parseDedupKeys(s string) dedupKeys {
+	keys := make(dedupKeys)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		source, tmpl := "*", pair
+		if !strings.HasPrefix(pair, "{") {
+			var ok bool
+			if source, tmpl, ok = strings.Cut(pair, ":"); !ok {
+				continue
+			}
+		}
+		source, tmpl = strings.ToLower(strings.TrimSpace(source)), strings.TrimSpace(tmpl)
+		if source == "" || !strings.Contains(tmpl, "{") {
+			continue
+		}
+		keys[source] = tmpl
+	}
+	return keys
+}
+
+// key evaluates the item's template against its frontmatter. Returns "" when
+// the source has no template or a field it names is missing or empty, so
+// items that can't be told apart are never merged. Parts of a --stream-large
+// record or a split Readwise document share their external_id, so they're
+// never merged either.
+func (d dedupKeys) key(item QueueItem) string {
+	if item.Part > 0 {
+		return ""
+	}
+	source := statsSource(item)
+	tmpl, ok := d[source]
+	if !ok {
+		if tmpl, ok = d["*"]; !ok {
+			return ""
+		}
+	}
+
+	fields := frontmatterFields(item.Content)
+	fallback := map[string]string{
+		"external_id": item.ExternalID,
+		"title":       item.Title,
+		"collection":  item.Collection,
+		"action":      item.Action,
+	}
+	for k, v := range fallback {
+		if fields[k] == "" {
+			fields[k] = v
+		}
+	}
+	fields["source"] = source
+
+	var b strings.Builder
+	for {
+		open := strings.Index(tmpl, "{")
+		if open < 0 {
+			b.WriteString(tmpl)
+			break
+		}
+		end := strings.Index(tmpl[open:], "}")
+		if end < 0 {
+			b.WriteString(tmpl)
+			break
+		}
+		value := fields[tmpl[open+1:open+end]]
+		if value == "" {
+			return ""
+		}
+		b.WriteString(tmpl[:open])
+		b.WriteString(value)
+		tmpl = tmpl[open+end+1:]
+	}
+	return b.String()
+}
+
+// frontmatterFields returns the top-level key: value pairs of md's
+// frontmatter. Nested and list values are skipped.
+func frontmatterFields(md string) map[string]string {
+	fields := make(map[string]string)
+	if !strings.HasPrefix(md, "---\n") {
+		return fields
+	}
+	end := strings.Index(md[4:], "\n---\n")
+	if end < 0 {
+		return fields
+	}
+	for _, line := range strings.Split(md[4:4+end], "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "- ") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+// replaceDuplicate drops the queued items with the same dedup key as item,
+// which is about to be queued in their place. Call with s.mu held.
+func (s *Server) replaceDuplicate(item QueueItem) {
+	if len(s.dedup) == 0 {
+		return
+	}
+	key := s.dedup.key(item)
+	if key == "" {
+		return
+	}
+	for id, queued := range s.queue {
+		if id == item.ID || s.dedup.key(queued) != key {
+			continue
+		}
+		s.unqueue(id)
+		s.stats.forget(queued)
+		logger.Debug("replaced queued duplicate", "dedup_key", key, "replaced", id, "request_id", item.RequestID)
+	}
+}
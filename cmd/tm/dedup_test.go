@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupKeepsReadwiseParts(t *testing.T) {
+	doc := HighlightedDocument{Document: ReadwiseDocument{ID: "doc1", Title: "Book", Category: "book"}, IsNew: true}
+	for i := range 5 {
+		doc.Highlights = append(doc.Highlights, ReadwiseDocument{ID: fmt.Sprint(i), Content: fmt.Sprintf("highlight %d", i)})
+	}
+	rw := &ReadwiseSyncer{maxHighlightsPerItem: 2}
+	parts := rw.partItems(doc, "highlighted")
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want 3", len(parts))
+	}
+
+	s := newTestServer(nil)
+	s.dedup = parseDedupKeys("{external_id}")
+	s.queueChanges(nil, parts)
+	if len(s.queue) != 3 {
+		t.Errorf("%d of 3 parts queued: dedup_key merged parts of one document", len(s.queue))
+	}
+
+	// The whole document synced again still replaces a waiting single-part version
+	rw.maxHighlightsPerItem = 0
+	s = newTestServer(nil)
+	s.dedup = parseDedupKeys("{external_id}")
+	s.queueChanges(nil, rw.partItems(doc, "highlighted"))
+	s.queueChanges(nil, rw.partItems(doc, "updated"))
+	if len(s.queue) != 1 {
+		t.Errorf("%d items queued, want the newer version only", len(s.queue))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout bounds `tm serve --drain` when --timeout isn't given
+const defaultDrainTimeout = 10 * time.Minute
+
+// RunOnce runs every syncer once, in parallel, and returns the names of those that failed
+func (sc *Scheduler) RunOnce(ctx context.Context) []string {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	for _, e := range sc.list() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc.run(ctx, e)
+
+			e.mu.Lock()
+			ok := e.failures == 0
+			e.mu.Unlock()
+			if !ok {
+				mu.Lock()
+				failed = append(failed, e.syncer.Name())
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// drain backs `tm serve --drain`: run each sync once, wait until everything is
+// delivered, then exit. Delivered means the plugin took every item, or, with
+// forward_url, every item was forwarded. Exits 1 on a failed sync or forward,
+// or when timeout passes first.
+func (s *Server) drain(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	failed := s.scheduler.RunOnce(ctx)
+	if len(failed) > 0 {
+		logger.Error("drain: sync failed", "sources", failed)
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for !s.drained() {
+		select {
+		case <-ctx.Done():
+			s.mu.RLock()
+			queued := len(s.queue)
+			s.mu.RUnlock()
+			logger.Error("drain timed out", "timeout", timeout, "queued", queued)
+			os.Exit(1)
+		case <-ticker.C:
+		}
+	}
+
+	if len(failed) > 0 || s.forward.Failed() > 0 {
+		os.Exit(1)
+	}
+	logger.Info("drain complete")
+	os.Exit(0)
+}
+
+// drained reports whether every queued item has been delivered, and acked
+// when leased. Snoozed items can't be, so they don't hold up the exit.
+func (s *Server) drained() bool {
+	if s.forward != nil {
+		return s.forward.Pending() == 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.queue) == s.snoozedCount(time.Now()) && len(s.inflight) == 0
+}
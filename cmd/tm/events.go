@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SyncEvent is one syncer lifecycle event on /events
+type SyncEvent struct {
+	Type     string    `json:"type"` // start, finish, error, backoff
+	Source   string    `json:"source"`
+	Time     time.Time `json:"time"`
+	Items    int       `json:"items,omitempty"`    // finish: items queued
+	Duration string    `json:"duration,omitempty"` // finish, error: how long the run took
+	Error    string    `json:"error,omitempty"`    // error
+	Failures int       `json:"failures,omitempty"` // error, backoff: consecutive failures
+	RetryIn  string    `json:"retry_in,omitempty"` // backoff: delay until the next run
+}
+
+// eventHub fans syncer lifecycle events out to /events subscribers
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan SyncEvent]struct{}
+}
+
+func (h *eventHub) subscribe() chan SyncEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs == nil {
+		h.subs = make(map[chan SyncEvent]struct{})
+	}
+	ch := make(chan SyncEvent, 64)
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan SyncEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+}
+
+// publish never blocks a sync: slow subscribers miss events instead
+func (h *eventHub) publish(event SyncEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// handleEvents is a read-only SSE feed of syncer lifecycle events (start,
+// finish, error, backoff) for dashboards. It carries no queue items.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errCodeUnsupported, "SSE not supported")
+		return
+	}
+
+	events := s.events.subscribe()
+	defer s.events.unsubscribe(events)
+
+	fmt.Fprintf(w, "event: connected\ndata: {}\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+
+		case <-ticker.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
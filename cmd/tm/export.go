@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// State archives (tm export / tm import-state) carry every sync cache, so a
+// new machine picks up where the old one stopped instead of resyncing (and
+// re-queueing) everything. Bump stateArchiveVersion when the layout changes;
+// import-state refuses archives newer than it understands.
+const (
+	stateArchiveFormat  = "tm-state"
+	stateArchiveVersion = 1
+)
+
+// stateArchive is the JSON written by tm export
+type stateArchive struct {
+	Format     string                `json:"format"`  // always stateArchiveFormat
+	Version    int                   `json:"version"` // stateArchiveVersion at export time
+	ExportedAt time.Time             `json:"exported_at"`
+	Secrets    bool                  `json:"secrets"`          // config secrets and OAuth tokens included
+	Config     string                `json:"config,omitempty"` // the config file, secrets commented out unless Secrets
+	Databases  map[string]archivedDB `json:"databases"`        // cache file name (github.db) -> buckets
+	Files      map[string]string     `json:"files,omitempty"`  // other state files by name (import manifest, OAuth tokens)
+}
+
+// archivedDB is one bolt cache: bucket -> key -> value. Values are base64 in
+// the JSON, so binary entries survive the round trip.
+type archivedDB map[string]map[string][]byte
+
+// stateFiles are the non-bolt state files in the data dir; secret ones are
+// only exported with --include-secrets
+var stateFiles = []struct {
+	name   string
+	secret bool
+}{
+	{importManifestName, false},
+	{"google.json", true},
+	{"strava.json", true},
+	{"reddit.json", true},
+	{"spotify.json", true},
+}
+
+// isSecretKey reports whether a config key holds a credential (token,
+// github_token, caldav_password, lastfm_api_key, ...). *_file keys are paths.
+func isSecretKey(key string) bool {
+	if strings.HasSuffix(key, "_file") {
+		return false
+	}
+	for _, suffix := range []string{"token", "token_next", "_secret", "password", "_api_key"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConfig comments out every secret in a config file, keeping its key
+// so the value is easy to fill back in
+func redactConfig(config string) string {
+	lines := strings.Split(config, "\n")
+	for i, line := range lines {
+		key, _, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && !strings.HasPrefix(key, "#") && isSecretKey(key) {
+			lines[i] = "# " + key + "=<redacted by tm export>"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportDB reads every bucket of a bolt file
+func exportDB(path string) (archivedDB, error) {
+	db, err := openBolt(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	out := make(archivedDB)
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			entries := make(map[string][]byte)
+			err := b.ForEach(func(k, v []byte) error {
+				if v == nil {
+					logger.Warn("skipping nested bucket", "db", filepath.Base(path), "bucket", string(name), "key", string(k))
+					return nil
+				}
+				entries[string(k)] = append([]byte(nil), v...)
+				return nil
+			})
+			out[string(name)] = entries
+			return err
+		})
+	})
+	return out, err
+}
+
+// importDB writes the archived buckets into a bolt file, replacing those buckets
+func importDB(path string, buckets archivedDB) error {
+	db, err := openBolt(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for name, entries := range buckets {
+			if tx.Bucket([]byte(name)) != nil {
+				if err := tx.DeleteBucket([]byte(name)); err != nil {
+					return err
+				}
+			}
+			b, err := tx.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+			for k, v := range entries {
+				if err := b.Put([]byte(k), v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// runExport implements `tm export [--out backup.json] [--include-secrets]`
+func runExport(args []string) {
+	out := ""
+	secrets := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--out", "-o":
+			if i+1 < len(args) {
+				out = args[i+1]
+				i++
+				continue
+			}
+			fmt.Println("Usage: tm export [--out backup.json] [--include-secrets]")
+			return
+		case "--include-secrets":
+			secrets = true
+		default:
+			fmt.Println("Usage: tm export [--out backup.json] [--include-secrets]")
+			return
+		}
+	}
+
+	if serverRunning() {
+		fmt.Fprintln(os.Stderr, "Error: tm serve is running and holds the caches; stop it first")
+		os.Exit(1)
+	}
+
+	archive := stateArchive{
+		Format:     stateArchiveFormat,
+		Version:    stateArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Secrets:    secrets,
+		Databases:  make(map[string]archivedDB),
+		Files:      make(map[string]string),
+	}
+
+	if data, err := os.ReadFile(configFilePath()); err == nil {
+		archive.Config = string(data)
+		if !secrets {
+			archive.Config = redactConfig(archive.Config)
+		}
+	} else if !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	dataDir := tmDataDir()
+	paths, _ := filepath.Glob(filepath.Join(dataDir, "*.db"))
+	sort.Strings(paths)
+	for _, path := range paths {
+		db, err := exportDB(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		archive.Databases[filepath.Base(path)] = db
+	}
+
+	for _, f := range stateFiles {
+		if f.secret && !secrets {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dataDir, f.name))
+		if err != nil {
+			continue
+		}
+		archive.Files[f.name] = string(data)
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if out == "" {
+		os.Stdout.Write(append(data, '\n'))
+		return
+	}
+	if err := os.WriteFile(out, append(data, '\n'), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf(em("✓ Exported %d caches and %d state files to %s\n"), len(archive.Databases), len(archive.Files), out)
+	if !secrets {
+		fmt.Println("  Secrets were left out; re-add them to the config (or export with --include-secrets)")
+	}
+}
+
+// runImportState implements `tm import-state backup.json [--force]`. It
+// refuses to overwrite existing caches, state files or config without --force.
+func runImportState(args []string) {
+	var path string
+	force := false
+	for _, arg := range args {
+		switch arg {
+		case "--force":
+			force = true
+		default:
+			path = arg
+		}
+	}
+	if path == "" {
+		fmt.Println("Usage: tm import-state backup.json [--force]")
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var archive stateArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s isn't a tm export: %v\n", path, err)
+		os.Exit(1)
+	}
+	if archive.Format != stateArchiveFormat {
+		fmt.Fprintf(os.Stderr, "Error: %s isn't a tm export (format %q)\n", path, archive.Format)
+		os.Exit(1)
+	}
+	if archive.Version > stateArchiveVersion {
+		fmt.Fprintf(os.Stderr, "Error: %s is format version %d; this tm reads up to %d, upgrade it first\n", path, archive.Version, stateArchiveVersion)
+		os.Exit(1)
+	}
+
+	if serverRunning() {
+		fmt.Fprintln(os.Stderr, "Error: tm serve is running and holds the caches; stop it first")
+		os.Exit(1)
+	}
+
+	// Only plain names: an archive must not write outside the data dir
+	dataDir := tmDataDir()
+	target := func(name string) string {
+		if name != filepath.Base(name) || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+			fmt.Fprintf(os.Stderr, "Error: invalid file name %q in archive\n", name)
+			os.Exit(1)
+		}
+		return filepath.Join(dataDir, name)
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+
+	// Check everything first, so a refusal leaves nothing half-imported
+	var conflicts []string
+	for name := range archive.Databases {
+		if !strings.HasSuffix(name, ".db") {
+			fmt.Fprintf(os.Stderr, "Error: invalid cache name %q in archive\n", name)
+			os.Exit(1)
+		}
+		if exists(target(name)) {
+			conflicts = append(conflicts, name)
+		}
+	}
+	for name := range archive.Files {
+		if exists(target(name)) {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if archive.Config != "" && exists(configFilePath()) {
+		conflicts = append(conflicts, "config")
+	}
+	if len(conflicts) > 0 && !force {
+		sort.Strings(conflicts)
+		fmt.Fprintf(os.Stderr, "Error: already present: %s (use --force to replace)\n", strings.Join(conflicts, ", "))
+		os.Exit(1)
+	}
+
+	names := make([]string, 0, len(archive.Databases))
+	for name := range archive.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := importDB(target(name), archive.Databases[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf(em("✓ %s\n"), name)
+	}
+	for name, content := range archive.Files {
+		if err := os.WriteFile(target(name), []byte(content), 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf(em("✓ %s\n"), name)
+	}
+	if archive.Config != "" {
+		if err := os.WriteFile(configFilePath(), []byte(archive.Config), 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(em("✓ config"))
+	}
+
+	fmt.Printf("Restored the state exported %s.\n", archive.ExportedAt.In(displayLocation).Format("2006-01-02 15:04"))
+	if !archive.Secrets && archive.Config != "" {
+		fmt.Println("Secrets weren't exported: fill in the commented-out keys in the config, and run tm auth again for OAuth sources.")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// defaultFooterTemplate is used when footer=true and no footer_template is set
+const defaultFooterTemplate = "[{label}]({url})"
+
+// writeFooter ends a record with a link back to its source, e.g.
+// "[View on GitHub](https://github.com/...)". tmpl supports {label} and {url};
+// nothing is written when tmpl is "" (footers off) or the record has no URL.
+func writeFooter(b *strings.Builder, tmpl, label, url string) {
+	if tmpl == "" || url == "" {
+		return
+	}
+
+	// Separate from the body by exactly one blank line
+	switch s := b.String(); {
+	case strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		b.WriteString("\n")
+	default:
+		b.WriteString("\n\n")
+	}
+	b.WriteString(strings.NewReplacer("{label}", label, "{url}", url).Replace(tmpl))
+	b.WriteString("\n")
+}
+
+// truncateBody shortens body to about max characters, cutting at a word
+// boundary and ending with "… [read more](url)" so the full text is one click
+// away. Bodies within the limit, and every body when max is 0, are unchanged.
+func truncateBody(body string, max int, url string) string {
+	runes := []rune(body)
+	if max <= 0 || len(runes) <= max {
+		return body
+	}
+
+	// Back up to the last space, unless that would drop more than half the text
+	cut := max
+	for i := max; i > max/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	short := strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+
+	// Don't leave a code block open around the link
+	if strings.Count(short, "```")%2 == 1 {
+		short += "\n```\n"
+	}
+
+	if url == "" {
+		return short + "…"
+	}
+	return short + "… [read more](" + url + ")"
+}
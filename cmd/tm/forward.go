@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Headers sent with every forwarded item. Receivers verify with:
+//
+//	expected = "sha256=" + hex(HMAC-SHA256(forward_secret, timestamp + "." + body))
+//
+// compare it to X-TM-Signature-256 in constant time, and reject requests whose
+// X-TM-Timestamp is more than a few minutes old so captured requests can't be replayed.
+const (
+	forwardSignatureHeader = "X-TM-Signature-256"
+	forwardTimestampHeader = "X-TM-Timestamp"
+)
+
+// Forwarder POSTs every queued item to forward_url as JSON, signed with
+// forward_secret when one is set
+type Forwarder struct {
+	url    string
+	secret string
+	client *http.Client
+
+	pending atomic.Int64 // sends in flight
+	failed  atomic.Int64 // sends that failed since startup
+}
+
+// NewForwarder creates a forwarder for url
+func NewForwarder(url, secret string) *Forwarder {
+	return &Forwarder{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send forwards item in the background; failures are logged, not retried
+func (f *Forwarder) Send(item QueueItem) {
+	if f == nil {
+		return
+	}
+	f.pending.Add(1)
+	go func() {
+		defer f.pending.Add(-1)
+		if err := f.post(item); err != nil {
+			f.failed.Add(1)
+			logger.Warn("forward failed", "url", f.url, "id", item.ID, "request_id", item.RequestID, "error", err)
+		}
+	}()
+}
+
+// Pending is the number of items still being forwarded
+func (f *Forwarder) Pending() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.pending.Load()
+}
+
+// Failed is the number of items that couldn't be forwarded
+func (f *Forwarder) Failed() int64 {
+	if f == nil {
+		return 0
+	}
+	return f.failed.Load()
+}
+
+func (f *Forwarder) post(item QueueItem) error {
+	body, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", f.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestIDHeader, item.RequestID)
+	if f.secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(forwardTimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(forwardSignatureHeader, signForward(f.secret, ts, body))
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("forward_url returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signForward returns "sha256=<hex>" of HMAC-SHA256 over "<timestamp>.<body>"
+func signForward(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package main
+
+import "strings"
+
+// frontmatterRequired are never filtered: the plugin routes on collection,
+// deduplicates on external_id, and joins multi-part records on part
+var frontmatterRequired = []string{"collection", "external_id", "part"}
+
+// frontmatterFilter is frontmatter_allow / frontmatter_deny, applied by every
+// ToMarkdown so workspaces with stricter schemas only get keys they support.
+// Both empty (the default) emits everything.
+var frontmatterFilter struct {
+	allow []string
+	deny  []string
+}
+
+// setFrontmatterFilter applies frontmatter_allow and frontmatter_deny from the config
+func setFrontmatterFilter(config Config) {
+	if len(config.FrontmatterAllow) > 0 && len(config.FrontmatterDeny) > 0 {
+		logger.Warn("frontmatter_allow and frontmatter_deny both set; keys must pass both")
+	}
+	frontmatterFilter.allow = config.FrontmatterAllow
+	frontmatterFilter.deny = config.FrontmatterDeny
+}
+
+// frontmatterKeyAllowed reports whether key passes the allow and deny lists
+func frontmatterKeyAllowed(key string) bool {
+	if containsFold(frontmatterRequired, key) {
+		return true
+	}
+	if len(frontmatterFilter.allow) > 0 && !containsFold(frontmatterFilter.allow, key) {
+		return false
+	}
+	return !containsFold(frontmatterFilter.deny, key)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFrontmatter drops the frontmatter keys the filter doesn't allow from
+// md. Indented or list lines under a key go with it. Without a filter, or
+// without frontmatter, md is returned unchanged.
+func filterFrontmatter(md string) string {
+	if len(frontmatterFilter.allow) == 0 && len(frontmatterFilter.deny) == 0 {
+		return md
+	}
+	if !strings.HasPrefix(md, "---\n") {
+		return md
+	}
+	end := strings.Index(md[4:], "\n---\n")
+	if end < 0 {
+		return md
+	}
+	header, rest := md[4:4+end+1], md[4+end+1:]
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	keep := true
+	for _, line := range strings.SplitAfter(header, "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "- ") {
+			key, _, _ := strings.Cut(line, ":")
+			keep = frontmatterKeyAllowed(strings.TrimSpace(key))
+		}
+		if keep {
+			b.WriteString(line)
+		}
+	}
+	b.WriteString(rest)
+	return b.String()
+}
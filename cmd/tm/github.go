@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v66/github"
@@ -14,8 +18,18 @@ import (
 
 const (
 	githubBucket   = "github_issues"
+	githubIDBucket = "github_ids" // GitHub's global issue ID -> cache key (survives transfers)
 	metaBucket     = "meta"
 	syncIntervalKey = "last_sync"
+	seededPrefix    = "seeded_" // meta key per repo/scope once its first sync has run
+
+	// githubPRFilesMax is the default row cap for github_pr_files tables
+	githubPRFilesMax = 20
+
+	// githubMaxPages caps how many pages of 100 one listing follows per sync,
+	// so a huge repo can't turn every poll into hundreds of requests
+	// (github_backfill imports what lies beyond)
+	githubMaxPages = 10
 )
 
 // GitHubIssue represents a stored issue/PR
@@ -34,7 +48,28 @@ type GitHubIssue struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 	ClosedAt  *time.Time `json:"closedAt,omitempty"`
 	Merged    bool      `json:"merged,omitempty"`
-	Verb      string    `json:"-"` // transient: opened, closed, merged, updated (not stored)
+	Draft     bool      `json:"draft,omitempty"`
+	GitHubID  int64     `json:"githubId,omitempty"` // global issue ID, stable across transfers
+	RepoURL   string    `json:"repoUrl,omitempty"`  // API URL of the owning repository
+	Reactions int       `json:"reactions,omitempty"` // total reactions (issues only; PR lists don't include them)
+	ThumbsUp  int       `json:"thumbsUp,omitempty"`
+	Verb      string    `json:"-"` // transient: opened, closed, merged, transferred, reacted, updated (not stored)
+	Collection string   `json:"-"` // transient: target collection from github_collection (not stored)
+	Footer     string   `json:"-"` // transient: link-back footer template ("" = off, not stored)
+	StatusIcons bool    `json:"-"` // transient: github_status_icons, prefix the title with statusIcon (not stored)
+	LinkedPR   *GitHubIssue `json:"-"` // transient: github_link_prs, the PR that closes this issue, rendered in the same record (not stored)
+
+	Files     []GitHubFileChange `json:"-"` // transient: github_pr_files table for a changed PR (not stored)
+	MaxBody   int                `json:"-"` // transient: max_body_chars, truncate Body past this (0 = never)
+	MoreFiles bool               `json:"-"` // transient: the PR changes more files than Files lists
+}
+
+// GitHubFileChange is one row of a PR's Changed Files table
+type GitHubFileChange struct {
+	Path      string
+	Status    string // added, removed, modified, renamed, ...
+	Additions int
+	Deletions int
 }
 
 // ToMarkdown returns the issue as markdown with YAML frontmatter
@@ -42,13 +77,20 @@ func (i GitHubIssue) ToMarkdown() string {
 	var b strings.Builder
 
 	// YAML frontmatter
+	collection := i.Collection
+	if collection == "" {
+		collection = "GitHub"
+	}
 	b.WriteString("---\n")
-	b.WriteString("collection: GitHub\n")
+	b.WriteString(fmt.Sprintf("collection: %s\n", collection))
 	b.WriteString(fmt.Sprintf("external_id: %s\n", i.ID))
 	if i.Verb != "" {
 		b.WriteString(fmt.Sprintf("verb: %s\n", i.Verb))
 	}
-	b.WriteString(fmt.Sprintf("title: %s\n", i.Title))
+	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(i.displayTitle())))
+	if i.StatusIcons {
+		b.WriteString(fmt.Sprintf("status_icon: %s\n", i.statusIcon()))
+	}
 	b.WriteString(fmt.Sprintf("repo: %s\n", i.Repo))
 	b.WriteString(fmt.Sprintf("number: %d\n", i.Number))
 	b.WriteString(fmt.Sprintf("type: %s\n", i.Type))
@@ -61,26 +103,122 @@ func (i GitHubIssue) ToMarkdown() string {
 	if i.Merged {
 		b.WriteString("merged: true\n")
 	}
+	if i.Reactions > 0 {
+		b.WriteString(fmt.Sprintf("reactions: %d\n", i.Reactions))
+		b.WriteString(fmt.Sprintf("thumbs_up: %d\n", i.ThumbsUp))
+	}
 	b.WriteString(fmt.Sprintf("created: %s\n", i.CreatedAt.Format(time.RFC3339)))
 	b.WriteString(fmt.Sprintf("updated: %s\n", i.UpdatedAt.Format(time.RFC3339)))
 	if i.ClosedAt != nil {
 		b.WriteString(fmt.Sprintf("closed: %s\n", i.ClosedAt.Format(time.RFC3339)))
 	}
+	if i.LinkedPR != nil {
+		b.WriteString(fmt.Sprintf("linked_pr: %d\n", i.LinkedPR.Number))
+		b.WriteString(fmt.Sprintf("pr_state: %s\n", prState(*i.LinkedPR)))
+		b.WriteString(fmt.Sprintf("pr_url: %s\n", i.LinkedPR.URL))
+	}
 	b.WriteString("---\n\n")
 
-	// Body
+	// Body (under its own heading when the PR shares the record)
+	if i.LinkedPR != nil {
+		b.WriteString("## Issue\n\n")
+	}
 	if i.Body != "" {
-		b.WriteString(i.Body)
+		b.WriteString(truncateBody(i.Body, i.MaxBody, i.URL))
 	}
 
-	return b.String()
+	if len(i.Files) > 0 {
+		writeChangedFiles(&b, i.Files, i.MoreFiles)
+	}
+	if i.LinkedPR != nil {
+		writeLinkedPR(&b, i.LinkedPR, i.MaxBody)
+	}
+
+	writeFooter(&b, i.Footer, "View on GitHub", i.URL)
+
+	return filterFrontmatter(b.String())
+}
+
+// statusIcon is the state emoji shown with github_status_icons
+func (i GitHubIssue) statusIcon() string {
+	switch {
+	case i.Merged:
+		return "🟣"
+	case i.State == "closed":
+		return "🔴"
+	case i.Draft:
+		return "📝"
+	default:
+		return "🟢"
+	}
+}
+
+// displayTitle is the title as sent to Thymer: prefixed with the state emoji
+// when github_status_icons is on
+func (i GitHubIssue) displayTitle() string {
+	if !i.StatusIcons {
+		return i.Title
+	}
+	return i.statusIcon() + " " + i.Title
+}
+
+// writeChangedFiles renders a PR's files as a "## Changed Files" table
+func writeChangedFiles(b *strings.Builder, files []GitHubFileChange, more bool) {
+	// Separate from the body by exactly one blank line
+	switch s := b.String(); {
+	case strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		b.WriteString("\n")
+	default:
+		b.WriteString("\n\n")
+	}
+	b.WriteString("## Changed Files\n\n")
+	b.WriteString("| File | + | - |\n")
+	b.WriteString("|------|--:|--:|\n")
+	var additions, deletions int
+	for _, f := range files {
+		path := "`" + strings.ReplaceAll(f.Path, "|", "\\|") + "`"
+		if f.Status != "" && f.Status != "modified" {
+			path += " (" + f.Status + ")"
+		}
+		b.WriteString(fmt.Sprintf("| %s | %d | %d |\n", path, f.Additions, f.Deletions))
+		additions += f.Additions
+		deletions += f.Deletions
+	}
+	total := fmt.Sprintf("%d files", len(files))
+	if more {
+		total = fmt.Sprintf("first %d files", len(files))
+	}
+	b.WriteString(fmt.Sprintf("| **%s** | **%d** | **%d** |\n", total, additions, deletions))
 }
 
 // GitHubSyncer handles syncing GitHub issues/PRs
 type GitHubSyncer struct {
-	client *github.Client
-	db     *bolt.DB
-	repos  []string
+	client      *github.Client
+	db          *bolt.DB
+	repos       []string
+	scopes      []string // github_scope filters across all repos; replaces repos when set
+	concurrency int      // repos fetched in parallel
+
+	reactionPriority int    // issues with at least this many reactions jump the queue (0 = off)
+	collection       string // github_collection override ("" = GitHub)
+	footer           string // link-back footer template ("" = off)
+	htmlToMarkdown   bool   // html_to_markdown: convert HTML in bodies
+	syncLabel        string // require_sync_label: only issues with this label are synced ("" = all)
+	prFiles          int    // github_pr_files: changed PRs list up to this many files (0 = off)
+	maxBody          int    // max_body_chars: truncate long bodies (0 = never)
+	statusIcons      bool   // github_status_icons: state emoji in titles and status_icon frontmatter
+	linkPRs          bool   // github_link_prs: a PR that closes an issue shares the issue's record
+
+	initialWindow time.Duration // github_initial_window: first sync of a repo only queues issues updated within this (0 = all)
+	retention     time.Duration // cache_retention: closed issues older than this are pruned and never re-queued (0 = keep)
+	backfill      bool          // github_backfill: import each repo's full history, githubBackfillPages per sync
+
+	backfillMu     sync.Mutex
+	backfillStaged map[string]githubBackfillCursor // per repo: cursor after this sync's batch, saved once it's cached
+
+	unchangedCount // for /status
+	backfillState  // for /status and tm status
 }
 
 // NewGitHubSyncer creates a new syncer
@@ -89,7 +227,7 @@ func NewGitHubSyncer(token string, repos []string, dataDir string) (*GitHubSynce
 
 	// Open bbolt database
 	dbPath := filepath.Join(dataDir, "github.db")
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	db, err := openBolt(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -99,6 +237,12 @@ func NewGitHubSyncer(token string, repos []string, dataDir string) (*GitHubSynce
 		if _, err := tx.CreateBucketIfNotExists([]byte(githubBucket)); err != nil {
 			return err
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(githubIDBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(githubLinkBucket)); err != nil {
+			return err
+		}
 		if _, err := tx.CreateBucketIfNotExists([]byte(metaBucket)); err != nil {
 			return err
 		}
@@ -110,9 +254,10 @@ func NewGitHubSyncer(token string, repos []string, dataDir string) (*GitHubSynce
 	}
 
 	return &GitHubSyncer{
-		client: client,
-		db:     db,
-		repos:  repos,
+		client:      client,
+		db:          db,
+		repos:       repos,
+		concurrency: defaultSyncConcurrency,
 	}, nil
 }
 
@@ -121,27 +266,134 @@ func (s *GitHubSyncer) Close() error {
 	return s.db.Close()
 }
 
+// CachedCount implements Syncer: the number of cached issues/PRs
+func (s *GitHubSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, githubBucket)
+}
+
+// Prune implements pruner: drops issues/PRs closed and untouched since cutoff,
+// along with their transfer index entries. SyncChanges won't re-queue them.
+func (s *GitHubSyncer) Prune(cutoff time.Time) (int, error) {
+	var n int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(githubBucket))
+		ids := tx.Bucket([]byte(githubIDBucket))
+
+		var stale []GitHubIssue
+		b.ForEach(func(k, v []byte) error {
+			var issue GitHubIssue
+			if err := json.Unmarshal(v, &issue); err != nil {
+				return nil
+			}
+			if closedBefore(issue, cutoff) {
+				stale = append(stale, issue)
+			}
+			return nil
+		})
+
+		for _, issue := range stale {
+			if err := b.Delete([]byte(issue.ID)); err != nil {
+				return err
+			}
+			if issue.GitHubID != 0 {
+				key := []byte(strconv.FormatInt(issue.GitHubID, 10))
+				if string(ids.Get(key)) == issue.ID {
+					if err := ids.Delete(key); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		n = len(stale)
+		return nil
+	})
+	return n, err
+}
+
+// closedBefore reports whether issue was closed and last updated before cutoff
+func closedBefore(issue GitHubIssue, cutoff time.Time) bool {
+	return issue.State == "closed" && issue.UpdatedAt.Before(cutoff) &&
+		(issue.ClosedAt == nil || issue.ClosedAt.Before(cutoff))
+}
+
 // ClearCache clears all cached issues from the database
 func (s *GitHubSyncer) ClearCache() error {
 	return s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("github_issues"))
-		if b == nil {
-			return nil
+		for _, name := range []string{githubBucket, githubIDBucket, githubLinkBucket} {
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				continue
+			}
+
+			var keysToDelete [][]byte
+			b.ForEach(func(k, v []byte) error {
+				keysToDelete = append(keysToDelete, k)
+				return nil
+			})
+
+			for _, k := range keysToDelete {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
 		}
 
-		var keysToDelete [][]byte
+		// Forget which repos were seeded so the next sync applies github_initial_window again
+		if meta := tx.Bucket([]byte(metaBucket)); meta != nil {
+			var seeded [][]byte
+			c := meta.Cursor()
+			prefix := []byte(seededPrefix)
+			for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), seededPrefix); k, _ = c.Next() {
+				seeded = append(seeded, k)
+			}
+			for _, k := range seeded {
+				if err := meta.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		s.forgetBackfill("")
+		return clearBackfill(tx, "")
+	})
+}
+
+// ClearCacheFor implements partialClearer: forgets one repo's issues/PRs so
+// only that repo is re-queued on the next sync
+func (s *GitHubSyncer) ClearCacheFor(repo string) (int, error) {
+	var n int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(githubBucket))
+		ids := tx.Bucket([]byte(githubIDBucket))
+
+		var stale []GitHubIssue
 		b.ForEach(func(k, v []byte) error {
-			keysToDelete = append(keysToDelete, k)
+			var issue GitHubIssue
+			if err := json.Unmarshal(v, &issue); err != nil {
+				return nil
+			}
+			if strings.EqualFold(issue.Repo, repo) {
+				stale = append(stale, issue)
+			}
 			return nil
 		})
 
-		for _, k := range keysToDelete {
-			if err := b.Delete(k); err != nil {
+		for _, issue := range stale {
+			if err := b.Delete([]byte(issue.ID)); err != nil {
 				return err
 			}
+			if issue.GitHubID != 0 {
+				ids.Delete([]byte(strconv.FormatInt(issue.GitHubID, 10)))
+			}
 		}
-		return nil
+		n = len(stale)
+
+		s.forgetBackfill(repo)
+		if err := clearBackfill(tx, repo); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(metaBucket)).Delete([]byte(seededPrefix + repo))
 	})
+	return n, err
 }
 
 // SyncResult contains sync statistics
@@ -152,28 +404,61 @@ type SyncResult struct {
 	Errors    []error
 }
 
-// Sync fetches issues/PRs and returns changes
-func (s *GitHubSyncer) Sync(ctx context.Context) (*SyncResult, error) {
+// SyncChanges fetches issues/PRs and returns changes
+func (s *GitHubSyncer) SyncChanges(ctx context.Context) (*SyncResult, error) {
 	result := &SyncResult{
 		Created: make([]GitHubIssue, 0),
 		Updated: make([]GitHubIssue, 0),
 		Errors:  make([]error, 0),
 	}
 
-	for _, repo := range s.repos {
-		issues, err := s.syncRepo(ctx, repo)
+	// github_scope: ask GitHub for issues involving the user instead of whole repos
+	keys, fetch := s.repos, s.syncRepo
+	if len(s.scopes) > 0 {
+		keys, fetch = s.scopes, s.syncScope
+	}
+	fetched := fetchConcurrently(ctx, keys, s.concurrency, fetch)
+
+	for _, f := range fetched {
+		repo, issues, err := f.key, f.value, f.err
 		if err != nil {
+			logger.Warn("GitHub repo sync failed", "repo", repo, "error", err)
 			result.Errors = append(result.Errors, fmt.Errorf("failed to sync %s: %w", repo, err))
 			continue
 		}
 
+		// First sync of this repo: cache everything as seen, but only queue recent activity
+		seeding := s.initialWindow > 0 && !s.seeded(repo)
+		// github_backfill keeps bringing in old history after the first sync; the window applies to it too
+		windowed := seeding || (s.initialWindow > 0 && s.backfill)
+		cutoff := time.Now().Add(-s.initialWindow)
+		skipped := 0
+
+		created, updated, failed := len(result.Created), len(result.Updated), len(result.Errors)
 		for _, issue := range issues {
+			// Unlabeled issues aren't cached, so adding the label later syncs them as new
+			if s.syncLabel != "" && !hasLabel(issue.Labels, s.syncLabel) {
+				continue
+			}
+
 			upsertResult, err := s.upsert(issue)
 			if err != nil {
 				result.Errors = append(result.Errors, err)
 				continue
 			}
 
+			if windowed && upsertResult.Action == "created" && issue.UpdatedAt.Before(cutoff) {
+				skipped++
+				result.Unchanged++
+				continue
+			}
+
+			// Pruned by cache_retention but still in the listing: re-cached, not re-queued
+			if s.retention > 0 && upsertResult.Action == "created" && closedBefore(issue, time.Now().Add(-s.retention)) {
+				result.Unchanged++
+				continue
+			}
+
 			issue.Verb = upsertResult.Verb
 			switch upsertResult.Action {
 			case "created":
@@ -184,11 +469,50 @@ func (s *GitHubSyncer) Sync(ctx context.Context) (*SyncResult, error) {
 				result.Unchanged++
 			}
 		}
+
+		// github_backfill: the batch is cached now, unless an upsert failed
+		if err := s.commitBackfillCursor(repo, len(result.Errors) == failed); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+
+		logger.Debug("GitHub repo synced",
+			"repo", repo,
+			"fetched", len(issues),
+			"created", len(result.Created)-created,
+			"updated", len(result.Updated)-updated)
+
+		if seeding {
+			if err := s.markSeeded(repo); err != nil {
+				result.Errors = append(result.Errors, err)
+			}
+			if skipped > 0 {
+				logger.Info("GitHub initial import limited to recent activity",
+					"repo", repo,
+					"window", s.initialWindow,
+					"cached_only", skipped)
+			}
+		}
 	}
 
 	return result, nil
 }
 
+// seeded reports whether repo (or scope) has completed its first sync
+func (s *GitHubSyncer) seeded(repo string) bool {
+	var found bool
+	s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket([]byte(metaBucket)).Get([]byte(seededPrefix+repo)) != nil
+		return nil
+	})
+	return found
+}
+
+func (s *GitHubSyncer) markSeeded(repo string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte(seededPrefix+repo), []byte(time.Now().Format(time.RFC3339)))
+	})
+}
+
 func (s *GitHubSyncer) syncRepo(ctx context.Context, repo string) ([]GitHubIssue, error) {
 	parts := strings.Split(repo, "/")
 	if len(parts) != 2 {
@@ -198,38 +522,102 @@ func (s *GitHubSyncer) syncRepo(ctx context.Context, repo string) ([]GitHubIssue
 
 	var issues []GitHubIssue
 
-	// Fetch issues
+	// Fetch issues, following pages up to githubMaxPages
 	issueOpts := &github.IssueListByRepoOptions{
-		State: "all",
+		State:       "all",
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	ghIssues, _, err := s.client.Issues.ListByRepo(ctx, owner, name, issueOpts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list issues: %w", err)
-	}
+	for page := 1; page != 0; {
+		if page > githubMaxPages {
+			logger.Warn("GitHub issue listing truncated, older issues need github_backfill", "repo", repo, "pages", githubMaxPages)
+			break
+		}
+		issueOpts.Page = page
+		ghIssues, resp, err := s.client.Issues.ListByRepo(ctx, owner, name, issueOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issues: %w", err)
+		}
 
-	for _, issue := range ghIssues {
-		// Skip pull requests (they have PullRequestLinks)
-		if issue.PullRequestLinks != nil {
-			continue
+		for _, issue := range ghIssues {
+			// Skip pull requests (they have PullRequestLinks)
+			if issue.PullRequestLinks != nil {
+				continue
+			}
+			issues = append(issues, s.convertIssue(repo, issue))
 		}
-		issues = append(issues, s.convertIssue(repo, issue))
+		page = resp.NextPage
 	}
 
-	// Fetch PRs
+	// Fetch PRs, same page cap
 	prOpts := &github.PullRequestListOptions{
-		State: "all",
+		State:       "all",
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	prs, _, err := s.client.PullRequests.List(ctx, owner, name, prOpts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list PRs: %w", err)
+	for page := 1; page != 0; {
+		if page > githubMaxPages {
+			logger.Warn("GitHub PR listing truncated, older PRs need github_backfill", "repo", repo, "pages", githubMaxPages)
+			break
+		}
+		prOpts.Page = page
+		prs, resp, err := s.client.PullRequests.List(ctx, owner, name, prOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PRs: %w", err)
+		}
+
+		for _, pr := range prs {
+			issues = append(issues, s.convertPR(repo, pr))
+		}
+		page = resp.NextPage
+	}
+
+	// github_backfill: the rest of the history, a batch per sync. A failed
+	// batch is retried next sync and doesn't hold up the recent activity.
+	if s.backfill {
+		older, err := s.backfillRepo(ctx, repo, owner, name)
+		if err != nil {
+			logger.Warn("GitHub backfill batch failed", "repo", repo, "error", err)
+		}
+		issues = append(issues, older...)
+	}
+
+	return issues, nil
+}
+
+// syncScope fetches issues and PRs across all accessible repos matching one
+// filter (mentioned, subscribed, assigned), like GitHub's Issues dashboard
+func (s *GitHubSyncer) syncScope(ctx context.Context, scope string) ([]GitHubIssue, error) {
+	opts := &github.IssueListOptions{
+		Filter:      scope,
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	for _, pr := range prs {
-		issues = append(issues, s.convertPR(repo, pr))
+	var issues []GitHubIssue
+	for page := 1; page != 0; {
+		if page > githubMaxPages {
+			logger.Warn("GitHub scope listing truncated, and github_backfill doesn't cover github_scope", "scope", scope, "pages", githubMaxPages)
+			break
+		}
+		opts.Page = page
+		ghIssues, resp, err := s.client.Issues.List(ctx, true, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s issues: %w", scope, err)
+		}
+
+		for _, issue := range ghIssues {
+			repo := issue.GetRepository().GetFullName()
+			if repo == "" {
+				continue
+			}
+			gi := s.convertIssue(repo, issue)
+			if issue.PullRequestLinks != nil {
+				gi.Type = "pull_request"
+			}
+			issues = append(issues, gi)
+		}
+		page = resp.NextPage
 	}
 
 	return issues, nil
@@ -256,6 +644,11 @@ func (s *GitHubSyncer) convertIssue(repo string, issue *github.Issue) GitHubIssu
 		Labels:    labels,
 		CreatedAt: issue.GetCreatedAt().Time,
 		UpdatedAt: issue.GetUpdatedAt().Time,
+		GitHubID:  issue.GetID(),
+		RepoURL:   issue.GetRepositoryURL(),
+		Reactions: issue.GetReactions().GetTotalCount(),
+		ThumbsUp:  issue.GetReactions().GetPlusOne(),
+		Draft:     issue.GetDraft(),
 	}
 
 	if issue.GetUser() != nil {
@@ -290,6 +683,7 @@ func (s *GitHubSyncer) convertPR(repo string, pr *github.PullRequest) GitHubIssu
 		URL:       pr.GetHTMLURL(),
 		Labels:    labels,
 		Merged:    pr.GetMerged(),
+		Draft:     pr.GetDraft(),
 		CreatedAt: pr.GetCreatedAt().Time,
 		UpdatedAt: pr.GetUpdatedAt().Time,
 	}
@@ -309,7 +703,7 @@ func (s *GitHubSyncer) convertPR(repo string, pr *github.PullRequest) GitHubIssu
 // UpsertResult contains the result of an upsert operation
 type UpsertResult struct {
 	Action string // created, updated, unchanged
-	Verb   string // opened, closed, merged, reopened, updated
+	Verb   string // opened, closed, merged, reopened, transferred, reacted, updated
 }
 
 func (s *GitHubSyncer) upsert(issue GitHubIssue) (*UpsertResult, error) {
@@ -317,19 +711,41 @@ func (s *GitHubSyncer) upsert(issue GitHubIssue) (*UpsertResult, error) {
 
 	err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(githubBucket))
+		ids := tx.Bucket([]byte(githubIDBucket))
 
 		existing := b.Get([]byte(issue.ID))
 		if existing == nil {
-			// New issue - verb is current state
 			data, err := json.Marshal(issue)
 			if err != nil {
 				return err
 			}
+
+			// Transferred issue - same GitHub ID cached under another repo's key
+			if oldKey := transferredFrom(b, ids, issue); oldKey != "" {
+				if err := b.Delete([]byte(oldKey)); err != nil {
+					return err
+				}
+				result.Action = "updated"
+				result.Verb = "transferred"
+				if err := indexGitHubID(ids, issue); err != nil {
+					return err
+				}
+				return b.Put([]byte(issue.ID), data)
+			}
+
+			// New issue - verb is current state
 			result.Action = "created"
 			result.Verb = stateToVerb(issue.State, issue.Merged)
+			if err := indexGitHubID(ids, issue); err != nil {
+				return err
+			}
 			return b.Put([]byte(issue.ID), data)
 		}
 
+		if err := indexGitHubID(ids, issue); err != nil {
+			return err
+		}
+
 		// Check if changed
 		var old GitHubIssue
 		if err := json.Unmarshal(existing, &old); err != nil {
@@ -345,6 +761,8 @@ func (s *GitHubSyncer) upsert(issue GitHubIssue) (*UpsertResult, error) {
 			// Determine verb based on what changed
 			if old.State != issue.State || old.Merged != issue.Merged {
 				result.Verb = stateToVerb(issue.State, issue.Merged)
+			} else if old.Title == issue.Title && !issue.UpdatedAt.After(old.UpdatedAt) && reactionsChanged(old, issue) {
+				result.Verb = "reacted"
 			} else {
 				result.Verb = "updated"
 			}
@@ -358,6 +776,53 @@ func (s *GitHubSyncer) upsert(issue GitHubIssue) (*UpsertResult, error) {
 	return result, err
 }
 
+// transferredFrom returns the cache key of a stale entry for the same GitHub
+// issue under a different repo, or "" if the issue was not transferred.
+func transferredFrom(b, ids *bolt.Bucket, issue GitHubIssue) string {
+	if issue.GitHubID == 0 {
+		return ""
+	}
+	oldKey := ids.Get([]byte(strconv.FormatInt(issue.GitHubID, 10)))
+	if oldKey == nil || string(oldKey) == issue.ID {
+		return ""
+	}
+
+	data := b.Get(oldKey)
+	if data == nil {
+		return ""
+	}
+	var old GitHubIssue
+	if err := json.Unmarshal(data, &old); err != nil {
+		return ""
+	}
+	if old.RepoURL == issue.RepoURL {
+		return ""
+	}
+	return string(oldKey)
+}
+
+// indexGitHubID records which cache key holds a given GitHub issue ID
+func indexGitHubID(ids *bolt.Bucket, issue GitHubIssue) error {
+	if issue.GitHubID == 0 {
+		return nil
+	}
+	key := []byte(strconv.FormatInt(issue.GitHubID, 10))
+	if string(ids.Get(key)) == issue.ID {
+		return nil
+	}
+	return ids.Put(key, []byte(issue.ID))
+}
+
+// hasLabel reports whether labels contains label, ignoring case
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
 func stateToVerb(state string, merged bool) string {
 	if merged {
 		return "merged"
@@ -385,7 +850,12 @@ func needsUpdate(old, new GitHubIssue) bool {
 	if new.UpdatedAt.After(old.UpdatedAt) {
 		return true
 	}
-	return false
+	// Reactions don't bump updated_at
+	return reactionsChanged(old, new)
+}
+
+func reactionsChanged(old, new GitHubIssue) bool {
+	return old.Reactions != new.Reactions || old.ThumbsUp != new.ThumbsUp
 }
 
 // GetAll returns all stored issues
@@ -407,43 +877,137 @@ func (s *GitHubSyncer) GetAll() ([]GitHubIssue, error) {
 	return issues, err
 }
 
-// StartPeriodicSync runs sync every interval and calls onChange with new/updated issues
-func (s *GitHubSyncer) StartPeriodicSync(ctx context.Context, interval time.Duration, onChange func([]GitHubIssue)) {
-	ticker := time.NewTicker(interval)
+// Name implements Syncer
+func (s *GitHubSyncer) Name() string {
+	return "github"
+}
 
-	go func() {
-		defer ticker.Stop()
+// Verify implements verifier: fetches the authenticated user
+func (s *GitHubSyncer) Verify(ctx context.Context) error {
+	_, resp, err := s.client.Users.Get(ctx, "")
+	if err != nil {
+		// Rate limits are 403s too, but say nothing about the token
+		var rateErr *github.RateLimitError
+		var abuseErr *github.AbuseRateLimitError
+		if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+			return fmt.Errorf("rate limited, token not checked: %w", err)
+		}
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return fmt.Errorf("%w: %v", errInvalidToken, err)
+		}
+		return err
+	}
+	return nil
+}
 
-		// Initial sync
-		s.doSync(onChange)
+// metaStore implements heldStore
+func (s *GitHubSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, metaBucket
+}
 
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Info("GitHub sync stopped")
-				return
-			case <-ticker.C:
-				s.doSync(onChange)
-			}
+// Sync implements Syncer: fetches changes and renders them as queue items
+func (s *GitHubSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("GitHub sync complete", "created", len(result.Created), "updated", len(result.Updated), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	s.unchanged = result.Unchanged
+
+	changes := append(result.Created, result.Updated...)
+	for i := range changes {
+		if s.prFiles > 0 && changes[i].Type == "pull_request" {
+			s.attachPRFiles(ctx, &changes[i])
 		}
-	}()
-}
+	}
+	if s.linkPRs {
+		changes = s.linkPullRequests(changes)
+	}
 
-func (s *GitHubSyncer) doSync(onChange func([]GitHubIssue)) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	items := make([]QueueItem, 0, len(changes))
+	for _, issue := range changes {
+		items = append(items, s.queueItem(issue))
+	}
+	return items, nil
+}
 
-	result, err := s.Sync(ctx)
+// attachPRFiles fetches the files a PR changes, up to s.prFiles. Only PRs that
+// changed this sync get here, so quiet repos cost no extra calls. A failed
+// lookup leaves the PR without a table.
+func (s *GitHubSyncer) attachPRFiles(ctx context.Context, issue *GitHubIssue) {
+	owner, repo, ok := strings.Cut(issue.Repo, "/")
+	if !ok {
+		return
+	}
+	files, resp, err := s.client.PullRequests.ListFiles(ctx, owner, repo, issue.Number, &github.ListOptions{PerPage: 100})
 	if err != nil {
-		logger.Error("GitHub sync failed", "error", err)
+		logger.Warn("couldn't list PR files", "repo", issue.Repo, "number", issue.Number, "error", err)
 		return
 	}
 
-	logger.Debug("GitHub sync complete", "created", len(result.Created), "updated", len(result.Updated), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	issue.MoreFiles = resp.NextPage != 0 || len(files) > s.prFiles
+	if len(files) > s.prFiles {
+		files = files[:s.prFiles]
+	}
+	for _, f := range files {
+		issue.Files = append(issue.Files, GitHubFileChange{
+			Path:      f.GetFilename(),
+			Status:    f.GetStatus(),
+			Additions: f.GetAdditions(),
+			Deletions: f.GetDeletions(),
+		})
+	}
+}
 
-	// Notify about changes
-	if len(result.Created) > 0 || len(result.Updated) > 0 {
-		changes := append(result.Created, result.Updated...)
-		onChange(changes)
+// queueItem renders an issue with the configured collection, footer and priority
+func (s *GitHubSyncer) queueItem(issue GitHubIssue) QueueItem {
+	issue.Collection = s.collection
+	issue.Footer = s.footer
+	issue.MaxBody = s.maxBody
+	issue.StatusIcons = s.statusIcons
+	if s.htmlToMarkdown {
+		issue.Body = htmlToMarkdown(issue.Body)
+		if issue.LinkedPR != nil {
+			pr := *issue.LinkedPR
+			pr.Body = htmlToMarkdown(pr.Body)
+			issue.LinkedPR = &pr
+		}
+	}
+	item := QueueItem{
+		ID:         fmt.Sprintf("gh-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      issue.displayTitle(),
+		Content:    issue.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Source:     "github",
+		ExternalID: issue.ID,
+		SourceTime: issue.UpdatedAt,
+		Verb:       issue.Verb,
 	}
+	if s.reactionPriority > 0 && issue.Reactions >= s.reactionPriority {
+		item.Priority = priorityHigh
+	}
+	return item
+}
+
+// Replay implements replayer: the cached issue, with the verb of its current state
+func (s *GitHubSyncer) Replay(id string) (QueueItem, error) {
+	issue, err := loadCached[GitHubIssue](s.db, githubBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	issue.Verb = stateToVerb(issue.State, issue.Merged)
+	if s.linkPRs {
+		if record, linked := s.linkedRecord(issue); linked {
+			record.Verb = stateToVerb(record.State, record.Merged)
+			issue = record
+		}
+	}
+	return s.queueItem(issue), nil
+}
+
+// ReplayIDs implements replayer
+func (s *GitHubSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, githubBucket)
 }
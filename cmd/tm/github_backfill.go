@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// backfillPrefix is the meta key per repo holding its github_backfill cursor
+	backfillPrefix = "backfill_"
+
+	// githubBackfillPages is how many pages of 100 each sync backfills per repo,
+	// so a huge repo arrives over many syncs instead of in one flood
+	githubBackfillPages = 5
+)
+
+// githubBackfillCursor is where a repo's backfill continues. Issues and PRs
+// are listed oldest first, so new activity never shifts the pages still to come.
+type githubBackfillCursor struct {
+	IssuePage int  `json:"issue_page"` // next page of issues (0 = done)
+	PRPage    int  `json:"pr_page"`    // next page of PRs (0 = done)
+	Done      int  `json:"done"`       // issues and PRs imported so far
+	Total     int  `json:"total"`      // issues and PRs in the repo when the backfill started
+	Complete  bool `json:"complete"`
+}
+
+func (c githubBackfillCursor) progress() backfillProgress {
+	return backfillProgress{Done: c.Done, Total: c.Total, Unit: "issues", Complete: c.Complete}
+}
+
+// backfillCursor loads a repo's cursor; ok is false before its first backfill
+func (s *GitHubSyncer) backfillCursor(repo string) (cursor githubBackfillCursor, ok bool) {
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(metaBucket)).Get([]byte(backfillPrefix + repo)); v != nil {
+			ok = json.Unmarshal(v, &cursor) == nil
+		}
+		return nil
+	})
+	return cursor, ok
+}
+
+func (s *GitHubSyncer) saveBackfillCursor(repo string, cursor githubBackfillCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte(backfillPrefix+repo), data)
+	})
+}
+
+// stageBackfillCursor records where repo's backfill continues after the
+// pages fetched in this sync. It's only saved by commitBackfillCursor, once
+// SyncChanges has cached those pages.
+func (s *GitHubSyncer) stageBackfillCursor(repo string, cursor githubBackfillCursor) {
+	s.backfillMu.Lock()
+	defer s.backfillMu.Unlock()
+	if s.backfillStaged == nil {
+		s.backfillStaged = make(map[string]githubBackfillCursor)
+	}
+	s.backfillStaged[repo] = cursor
+}
+
+// commitBackfillCursor saves the cursor staged for repo when its batch was
+// cached, or drops it so the batch is fetched again next sync
+func (s *GitHubSyncer) commitBackfillCursor(repo string, cached bool) error {
+	s.backfillMu.Lock()
+	cursor, ok := s.backfillStaged[repo]
+	delete(s.backfillStaged, repo)
+	s.backfillMu.Unlock()
+	if !ok || !cached {
+		return nil
+	}
+
+	if err := s.saveBackfillCursor(repo, cursor); err != nil {
+		return err
+	}
+	s.setBackfill(repo, cursor.progress())
+	if cursor.Complete {
+		logger.Info("GitHub backfill complete", "repo", repo, "imported", cursor.Done)
+	}
+	return nil
+}
+
+// backfillRepo fetches the next githubBackfillPages pages of a repo's full
+// history (github_backfill), issues first, then PRs. The cursor only moves
+// on once SyncChanges has cached what was fetched, so a restart or a
+// timed-out sync fetches an unfinished batch again rather than skipping it.
+// Once both listings are exhausted the repo is only synced incrementally.
+func (s *GitHubSyncer) backfillRepo(ctx context.Context, repo, owner, name string) ([]GitHubIssue, error) {
+	cursor, ok := s.backfillCursor(repo)
+	if !ok {
+		cursor = githubBackfillCursor{IssuePage: 1, PRPage: 1}
+		// One search for the size of the repo, for tm status; a failure only loses the estimate
+		result, _, err := s.client.Search.Issues(ctx, "repo:"+repo, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+		if err != nil {
+			logger.Warn("GitHub backfill couldn't count issues", "repo", repo, "error", err)
+		} else {
+			cursor.Total = result.GetTotal()
+		}
+		logger.Info("GitHub backfill started", "repo", repo, "total", cursor.Total)
+	}
+	s.setBackfill(repo, cursor.progress())
+	if cursor.Complete {
+		return nil, nil
+	}
+
+	var issues []GitHubIssue
+	defer func() { s.stageBackfillCursor(repo, cursor) }()
+	for page := 0; page < githubBackfillPages && !cursor.Complete; page++ {
+		if cursor.IssuePage > 0 {
+			ghIssues, resp, err := s.client.Issues.ListByRepo(ctx, owner, name, &github.IssueListByRepoOptions{
+				State:       "all",
+				Sort:        "created",
+				Direction:   "asc",
+				ListOptions: github.ListOptions{Page: cursor.IssuePage, PerPage: 100},
+			})
+			if err != nil {
+				return issues, fmt.Errorf("failed to backfill issues: %w", err)
+			}
+			for _, issue := range ghIssues {
+				// PRs come from the PR listing, which knows whether they merged
+				if issue.PullRequestLinks != nil {
+					continue
+				}
+				issues = append(issues, s.convertIssue(repo, issue))
+				cursor.Done++
+			}
+			cursor.IssuePage = resp.NextPage
+		} else {
+			prs, resp, err := s.client.PullRequests.List(ctx, owner, name, &github.PullRequestListOptions{
+				State:       "all",
+				Sort:        "created",
+				Direction:   "asc",
+				ListOptions: github.ListOptions{Page: cursor.PRPage, PerPage: 100},
+			})
+			if err != nil {
+				return issues, fmt.Errorf("failed to backfill PRs: %w", err)
+			}
+			for _, pr := range prs {
+				issues = append(issues, s.convertPR(repo, pr))
+				cursor.Done++
+			}
+			cursor.PRPage = resp.NextPage
+		}
+		cursor.Complete = cursor.IssuePage == 0 && cursor.PRPage == 0
+	}
+
+	logger.Debug("GitHub backfill batch", "repo", repo, "done", cursor.Done, "total", cursor.Total)
+	return issues, nil
+}
+
+// clearBackfill deletes the backfill cursors of every repo (repo == "") or
+// one repo, so the next sync imports the full history again. Call inside
+// the Update that clears the cache.
+func clearBackfill(tx *bolt.Tx, repo string) error {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return nil
+	}
+	if repo != "" {
+		return meta.Delete([]byte(backfillPrefix + repo))
+	}
+
+	var keys [][]byte
+	c := meta.Cursor()
+	for k, _ := c.Seek([]byte(backfillPrefix)); k != nil && strings.HasPrefix(string(k), backfillPrefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if err := meta.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// githubLinkBucket records which PR closes which issue for github_link_prs:
+// "pr/<pr id>" -> issue id and "issue/<issue id>" -> pr id
+const githubLinkBucket = "github_links"
+
+// closingRefRe matches GitHub's closing keywords: "Fixes #12", "closes owner/repo#12"
+var closingRefRe = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:([\w.-]+/[\w.-]+))?#(\d+)\b`)
+
+// closingIssue returns the cache id of the first issue in the PR's own repo
+// that its body says it closes
+func closingIssue(pr GitHubIssue) (string, bool) {
+	for _, m := range closingRefRe.FindAllStringSubmatch(pr.Body, -1) {
+		if m[1] != "" && !strings.EqualFold(m[1], pr.Repo) {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil || n == pr.Number {
+			continue
+		}
+		return fmt.Sprintf("github_%s_%d", strings.ReplaceAll(pr.Repo, "/", "_"), n), true
+	}
+	return "", false
+}
+
+// linkedRecord returns the combined record a change belongs to: for a PR that
+// closes a cached issue, that issue with the PR attached; for an issue with a
+// linked PR, the issue with it attached. ok is false for anything unlinked,
+// which is queued as its own record.
+func (s *GitHubSyncer) linkedRecord(change GitHubIssue) (GitHubIssue, bool) {
+	if change.Type == "pull_request" {
+		issueID, ok := closingIssue(change)
+		if !ok {
+			return change, false
+		}
+		issue, err := loadCached[GitHubIssue](s.db, githubBucket, issueID)
+		if err != nil || issue.Type != "issue" {
+			return change, false
+		}
+		if err := s.storeLink(change.ID, issue.ID); err != nil {
+			logger.Warn("couldn't store PR link", "pr", change.ID, "issue", issue.ID, "error", err)
+		}
+		issue.LinkedPR = &change
+		issue.Verb = change.Verb
+		if issue.Verb == "opened" {
+			issue.Verb = "linked"
+		}
+		return issue, true
+	}
+
+	prID := s.linkFor("issue/" + change.ID)
+	if prID == "" {
+		return change, false
+	}
+	pr, err := loadCached[GitHubIssue](s.db, githubBucket, prID)
+	if err != nil {
+		return change, false
+	}
+	change.LinkedPR = &pr
+	return change, true
+}
+
+// linkPullRequests folds PRs that close an issue into the issue's record, so
+// the pair arrives as one. A record changed from both sides is queued once.
+func (s *GitHubSyncer) linkPullRequests(changes []GitHubIssue) []GitHubIssue {
+	records := make([]GitHubIssue, 0, len(changes))
+	seen := make(map[string]int) // combined record id -> index in records
+	for _, change := range changes {
+		record, linked := s.linkedRecord(change)
+		if !linked {
+			records = append(records, change)
+			continue
+		}
+		if i, ok := seen[record.ID]; ok {
+			// Both sides changed: keep the more telling verb (a merge or close over "updated")
+			if records[i].Verb == "updated" {
+				records[i].Verb = record.Verb
+			}
+			if record.LinkedPR != nil && len(record.LinkedPR.Files) > 0 {
+				records[i].LinkedPR = record.LinkedPR
+			}
+			continue
+		}
+		seen[record.ID] = len(records)
+		records = append(records, record)
+	}
+	return records
+}
+
+// storeLink records that the PR closes the issue, replacing the issue's older link
+func (s *GitHubSyncer) storeLink(prID, issueID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(githubLinkBucket))
+		if err := b.Put([]byte("pr/"+prID), []byte(issueID)); err != nil {
+			return err
+		}
+		return b.Put([]byte("issue/"+issueID), []byte(prID))
+	})
+}
+
+// linkFor returns the id stored under key ("" if none)
+func (s *GitHubSyncer) linkFor(key string) string {
+	var id string
+	s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(githubLinkBucket)); b != nil {
+			id = string(b.Get([]byte(key)))
+		}
+		return nil
+	})
+	return id
+}
+
+// writeLinkedPR renders the PR section of a combined issue+PR record
+func writeLinkedPR(b *strings.Builder, pr *GitHubIssue, maxBody int) {
+	switch s := b.String(); {
+	case strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		b.WriteString("\n")
+	default:
+		b.WriteString("\n\n")
+	}
+	b.WriteString(fmt.Sprintf("## Pull Request [#%d](%s): %s\n\n", pr.Number, pr.URL, pr.Title))
+	b.WriteString(fmt.Sprintf("%s by @%s\n", prState(*pr), pr.Author))
+	if pr.Body != "" {
+		b.WriteString("\n")
+		b.WriteString(truncateBody(pr.Body, maxBody, pr.URL))
+	}
+	if len(pr.Files) > 0 {
+		writeChangedFiles(b, pr.Files, pr.MoreFiles)
+	}
+}
+
+// prState is open, draft, merged or closed
+func prState(pr GitHubIssue) string {
+	switch {
+	case pr.Merged:
+		return "merged"
+	case pr.State == "open" && pr.Draft:
+		return "draft"
+	}
+	return pr.State
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// filterIssues keeps issues matching state (open/closed) and repo (owner/name);
+// empty filters match everything. The result is most recently updated first.
+func filterIssues(issues []GitHubIssue, state, repo string) []GitHubIssue {
+	var kept []GitHubIssue
+	for _, issue := range issues {
+		if state != "" && issue.State != state {
+			continue
+		}
+		if repo != "" && !strings.EqualFold(issue.Repo, repo) {
+			continue
+		}
+		kept = append(kept, issue)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].UpdatedAt.After(kept[j].UpdatedAt) })
+	return kept
+}
+
+// handleGitHubIssues returns the cached GitHub issues as JSON
+// (GET /github/issues?state=open&repo=owner/name). It never calls GitHub.
+func (s *Server) handleGitHubIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "GET only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	syncer, ok := s.scheduler.Get("github").(*GitHubSyncer)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errCodeNotConfigured, "github sync not configured")
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+	if state != "" && state != "open" && state != "closed" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "state must be open or closed")
+		return
+	}
+
+	issues, err := syncer.GetAll()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+		return
+	}
+	issues = filterIssues(issues, state, r.URL.Query().Get("repo"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"count": len(issues), "issues": issues})
+}
+
+// runGitHubList implements `tm github list [--open|--closed] [--repo owner/name] [--json]`
+func runGitHubList(args []string) {
+	var state, repo string
+	asJSON := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--open":
+			state = "open"
+		case "--closed":
+			state = "closed"
+		case "--json":
+			asJSON = true
+		case "--repo":
+			if i+1 < len(args) {
+				repo = args[i+1]
+				i++
+				continue
+			}
+			fallthrough
+		default:
+			fmt.Println("Usage: tm github list [--open|--closed] [--repo owner/name] [--json]")
+			return
+		}
+	}
+
+	// Prefer the running server (it holds the cache lock); without one, read the cache directly
+	var issues []GitHubIssue
+	var err error
+	if serverRunning() {
+		issues, err = githubIssuesHTTP(state, repo)
+	} else {
+		issues, err = githubIssuesLocal(state, repo)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(issues)
+		return
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("No cached issues match")
+		return
+	}
+	for _, issue := range issues {
+		kind := "issue"
+		if issue.Type == "pull_request" {
+			kind = "PR"
+		}
+		state := issue.State
+		if issue.Merged {
+			state = "merged"
+		}
+		fmt.Printf("%-30s %-6s %-5s %s\n", fmt.Sprintf("%s#%d", issue.Repo, issue.Number), state, kind, issue.Title)
+	}
+	fmt.Printf("\n%d cached issues\n", len(issues))
+}
+
+// githubIssuesHTTP asks the running server for its cached issues
+func githubIssuesHTTP(state, repo string) ([]GitHubIssue, error) {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+
+	params := neturl.Values{"token": {token}}
+	if state != "" {
+		params.Set("state", state)
+	}
+	if repo != "" {
+		params.Set("repo", repo)
+	}
+
+	resp, err := http.Get(url + "/github/issues?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s", apiErrorMessage(body))
+	}
+
+	var result struct {
+		Issues []GitHubIssue `json:"issues"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Issues, nil
+}
+
+// githubIssuesLocal reads github.db directly when no server is running
+func githubIssuesLocal(state, repo string) ([]GitHubIssue, error) {
+	syncer, closeFn, err := openLocalSyncer("github")
+	if err == errNotConfigured {
+		return nil, fmt.Errorf("github sync is not configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	issues, err := syncer.(*GitHubSyncer).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return filterIssues(issues, state, repo), nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphqlStatusError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		invalid bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, nil, true},
+		{"forbidden", http.StatusForbidden, nil, true},
+		{"primary rate limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, false},
+		{"secondary rate limit", http.StatusForbidden, map[string]string{"Retry-After": "60"}, false},
+		{"server error", http.StatusBadGateway, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			for k, v := range tt.header {
+				rec.Header().Set(k, v)
+			}
+			rec.WriteHeader(tt.status)
+
+			err := graphqlStatusError(rec.Result())
+			if err == nil {
+				t.Fatal("no error for a failed request")
+			}
+			if got := errors.Is(err, errInvalidToken); got != tt.invalid {
+				t.Errorf("errors.Is(%v, errInvalidToken) = %v, want %v", err, got, tt.invalid)
+			}
+			if isAuthError(err) != tt.invalid {
+				t.Errorf("isAuthError(%v) = %v, want %v", err, !tt.invalid, tt.invalid)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(http.StatusOK)
+	if err := graphqlStatusError(rec.Result()); err != nil {
+		t.Errorf("200: %v", err)
+	}
+}
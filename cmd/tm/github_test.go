@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGitHub serves the repo issue and PR listings the syncer reads. Each
+// repo has pages pages of one issue and one PR; pages < 0 keeps offering a
+// next page forever.
+type fakeGitHub struct {
+	pages int
+
+	mu       sync.Mutex
+	requests map[string]int // path -> requests served
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	f.mu.Unlock()
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	if f.pages < 0 || page < f.pages {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+
+	updated := time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/issues"):
+		fmt.Fprintf(w, `[{"number":%d,"title":"Issue %d","state":"open","updated_at":%q,"created_at":%q,"repository":{"full_name":"owner/repo"}}]`, page*2, page, updated, updated)
+	case strings.HasSuffix(r.URL.Path, "/pulls"):
+		fmt.Fprintf(w, `[{"number":%d,"title":"PR %d","state":"open","updated_at":%q,"created_at":%q}]`, page*2+1, page, updated, updated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) served(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+// newTestGitHubSyncer points a GitHubSyncer at fake
+func newTestGitHubSyncer(t *testing.T, fake *fakeGitHub, repos []string) *GitHubSyncer {
+	t.Helper()
+	fake.requests = make(map[string]int)
+	return githubSyncerFor(t, fake, repos)
+}
+
+// githubSyncerFor points a GitHubSyncer at an API served by h
+func githubSyncerFor(t *testing.T, h http.Handler, repos []string) *GitHubSyncer {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	s, err := NewGitHubSyncer("tk", repos, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	base, _ := url.Parse(srv.URL + "/")
+	s.client.BaseURL = base
+	return s
+}
+
+func TestFetchConcurrently(t *testing.T) {
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	var running, peak atomic.Int32
+	results := fetchConcurrently(context.Background(), keys, 4, func(ctx context.Context, key string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if key == "key7" {
+			return "", fmt.Errorf("failed")
+		}
+		return "value of " + key, nil
+	})
+
+	if len(results) != len(keys) {
+		t.Fatalf("got %d results, want %d", len(results), len(keys))
+	}
+	for i, r := range results {
+		if r.key != keys[i] {
+			t.Errorf("result %d is for %s, want %s (results keep key order)", i, r.key, keys[i])
+		}
+		if r.key == "key7" {
+			if r.err == nil {
+				t.Error("key7's error was lost")
+			}
+			continue
+		}
+		if r.err != nil || r.value != "value of "+r.key {
+			t.Errorf("%s: value %q, err %v", r.key, r.value, r.err)
+		}
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("%d fetches ran at once, want at most 4", p)
+	}
+}
+
+func TestGitHubSyncConcurrentRepos(t *testing.T) {
+	var repos []string
+	for i := range 20 {
+		repos = append(repos, fmt.Sprintf("owner/repo%d", i))
+	}
+	s := newTestGitHubSyncer(t, &fakeGitHub{pages: 1}, repos)
+	s.concurrency = 8
+
+	result, err := s.SyncChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("sync errors: %v", result.Errors)
+	}
+
+	found := make(map[string]int)
+	for _, issue := range result.Created {
+		found[issue.Repo]++
+	}
+	for _, repo := range repos {
+		if found[repo] != 2 {
+			t.Errorf("%s: %d issues and PRs synced, want 2", repo, found[repo])
+		}
+	}
+}
+
+func TestGitHubSyncFollowsPages(t *testing.T) {
+	fake := &fakeGitHub{pages: 3}
+	s := newTestGitHubSyncer(t, fake, []string{"owner/repo"})
+
+	issues, err := s.syncRepo(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 6 {
+		t.Errorf("got %d issues and PRs from 3 pages each, want 6", len(issues))
+	}
+	if n := fake.served("/repos/owner/repo/issues"); n != 3 {
+		t.Errorf("%d issue pages requested, want 3", n)
+	}
+}
+
+func TestGitHubSyncStopsAtMaxPages(t *testing.T) {
+	fake := &fakeGitHub{pages: -1}
+	s := newTestGitHubSyncer(t, fake, []string{"owner/repo"})
+
+	issues, err := s.syncRepo(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2*githubMaxPages {
+		t.Errorf("got %d issues and PRs, want %d", len(issues), 2*githubMaxPages)
+	}
+	for _, path := range []string{"/repos/owner/repo/issues", "/repos/owner/repo/pulls"} {
+		if n := fake.served(path); n != githubMaxPages {
+			t.Errorf("%s: %d pages requested, want the cap of %d", path, n, githubMaxPages)
+		}
+	}
+}
+
+func TestGitHubScopeStopsAtMaxPages(t *testing.T) {
+	fake := &fakeGitHub{pages: -1}
+	s := newTestGitHubSyncer(t, fake, nil)
+
+	issues, err := s.syncScope(context.Background(), "assigned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != githubMaxPages {
+		t.Errorf("got %d issues, want %d", len(issues), githubMaxPages)
+	}
+	if n := fake.served("/issues"); n != githubMaxPages {
+		t.Errorf("%d scope pages requested, want the cap of %d", n, githubMaxPages)
+	}
+}
+
+func TestGitHubUpsertSeesReactionOnlyChanges(t *testing.T) {
+	s := newTestGitHubSyncer(t, &fakeGitHub{}, nil)
+	s.reactionPriority = 3
+
+	updated := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	issue := GitHubIssue{ID: "github_owner_repo_1", Repo: "owner/repo", Number: 1, Title: "Bug", State: "open", UpdatedAt: updated, Reactions: 1}
+	if _, err := s.upsert(issue); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same updated_at: GitHub doesn't bump it for reactions
+	issue.Reactions, issue.ThumbsUp = 3, 2
+	result, err := s.upsert(issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Action != "updated" || result.Verb != "reacted" {
+		t.Errorf("reaction-only change: action %q verb %q, want updated/reacted", result.Action, result.Verb)
+	}
+	if cached, _ := loadCached[GitHubIssue](s.db, githubBucket, issue.ID); cached.Reactions != 3 {
+		t.Errorf("cached reactions = %d, want 3", cached.Reactions)
+	}
+	issue.Verb = result.Verb
+	if item := s.queueItem(issue); item.Priority != priorityHigh {
+		t.Errorf("issue that reached github_reaction_priority queued at priority %v", item.Priority)
+	}
+
+	if result, _ := s.upsert(issue); result.Action != "unchanged" {
+		t.Errorf("same reactions again: action %q, want unchanged", result.Action)
+	}
+}
+
+func TestGitHubVerifyRateLimitIsNotInvalidToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		body    string
+		invalid bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, nil, `{"message":"Bad credentials"}`, true},
+		{"forbidden", http.StatusForbidden, nil, `{"message":"Resource not accessible by integration"}`, true},
+		{"primary rate limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "9999999999"},
+			`{"message":"API rate limit exceeded"}`, false},
+		{"secondary rate limit", http.StatusForbidden, map[string]string{"Retry-After": "60"},
+			`{"message":"You have exceeded a secondary rate limit","documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := githubSyncerFor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}), nil)
+
+			err := s.Verify(context.Background())
+			if err == nil {
+				t.Fatal("Verify succeeded")
+			}
+			if got := errors.Is(err, errInvalidToken); got != tt.invalid {
+				t.Errorf("errors.Is(%v, errInvalidToken) = %v, want %v", err, got, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestGitHubBackfillCursorSavedAfterUpsert(t *testing.T) {
+	s := newTestGitHubSyncer(t, &fakeGitHub{pages: 3}, []string{"owner/repo"})
+	s.backfill = true
+
+	// Fetched but not cached: a crash here must not skip these pages
+	if _, err := s.backfillRepo(context.Background(), "owner/repo", "owner", "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.backfillCursor("owner/repo"); ok {
+		t.Error("backfill cursor saved before its pages were cached")
+	}
+	s.commitBackfillCursor("owner/repo", false)
+
+	result, err := s.SyncChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("sync errors: %v", result.Errors)
+	}
+	cursor, ok := s.backfillCursor("owner/repo")
+	if !ok || cursor.Done == 0 {
+		t.Errorf("backfill cursor after the batch was cached: %+v (saved %v)", cursor, ok)
+	}
+}
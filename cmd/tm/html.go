@@ -0,0 +1,58 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// codePattern matches fenced blocks and inline code spans, which are left untouched
+	codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+	htmlBreak   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlPara    = regexp.MustCompile(`(?i)</?(p|div|ul|ol|h[1-6]|blockquote|table|tr)(\s[^>]*)?>`)
+	htmlItem    = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+	htmlBold    = regexp.MustCompile(`(?i)</?(b|strong)(\s[^>]*)?>`)
+	htmlItalic  = regexp.MustCompile(`(?i)</?(i|em)(\s[^>]*)?>`)
+	htmlCode    = regexp.MustCompile(`(?i)</?code(\s[^>]*)?>`)
+	htmlLink    = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+
+	// htmlTag only matches real HTML element names so generics like Vec<String> survive
+	htmlTag = regexp.MustCompile(`(?i)</?(a|abbr|b|blockquote|br|caption|code|dd|del|details|div|dl|dt|em|font|h[1-6]|hr|i|img|ins|kbd|li|mark|ol|p|picture|pre|s|small|source|span|strike|strong|sub|summary|sup|table|tbody|td|tfoot|th|thead|tr|u|ul|video)(\s[^>]*)?/?>`)
+)
+
+// htmlToMarkdown converts common inline HTML (links, bold, italics, lists,
+// breaks) to markdown, strips any other tags, and decodes entities.
+// Code blocks and spans are passed through as-is.
+func htmlToMarkdown(s string) string {
+	if !strings.Contains(s, "<") && !strings.Contains(s, "&") {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range codePattern.FindAllStringIndex(s, -1) {
+		b.WriteString(convertHTML(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(convertHTML(s[last:]))
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(b.String(), "\n\n"))
+}
+
+func convertHTML(s string) string {
+	s = htmlComment.ReplaceAllString(s, "")
+	s = htmlLink.ReplaceAllString(s, "[$2]($1)")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlItem.ReplaceAllString(s, "\n- ")
+	s = htmlPara.ReplaceAllString(s, "\n\n")
+	s = htmlBold.ReplaceAllString(s, "**")
+	s = htmlItalic.ReplaceAllString(s, "_")
+	s = htmlCode.ReplaceAllString(s, "`")
+	s = htmlTag.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icalProp is one iCalendar content line: NAME;PARAM=value:VALUE
+type icalProp struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icalComponent is a BEGIN:x ... END:x block (VCALENDAR, VEVENT, VTIMEZONE, ...)
+type icalComponent struct {
+	Name     string
+	Props    []icalProp
+	Children []*icalComponent
+}
+
+// prop returns the first property called name
+func (c *icalComponent) prop(name string) (icalProp, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return icalProp{}, false
+}
+
+// text returns the unescaped value of the first property called name ("" if missing)
+func (c *icalComponent) text(name string) string {
+	p, ok := c.prop(name)
+	if !ok {
+		return ""
+	}
+	return icalText.Replace(p.Value)
+}
+
+// icalText undoes RFC 5545 TEXT escaping
+var icalText = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+// parseICal parses an iCalendar document (RFC 5545) into its component tree
+func parseICal(data string) (*icalComponent, error) {
+	// Unfold: a line starting with a space or tab continues the previous one
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	root := &icalComponent{}
+	stack := []*icalComponent{root}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, ok := parseICalLine(line)
+		if !ok {
+			continue
+		}
+		current := stack[len(stack)-1]
+		switch prop.Name {
+		case "BEGIN":
+			child := &icalComponent{Name: strings.ToUpper(prop.Value)}
+			current.Children = append(current.Children, child)
+			stack = append(stack, child)
+		case "END":
+			if len(stack) == 1 || current.Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("unexpected END:%s", prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			current.Props = append(current.Props, prop)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// parseICalLine splits a content line at the first colon outside quotes
+func parseICalLine(line string) (icalProp, bool) {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ':':
+			if inQuotes {
+				continue
+			}
+			parts := splitQuoted(line[:i], ';')
+			prop := icalProp{Name: strings.ToUpper(parts[0]), Value: line[i+1:]}
+			for _, param := range parts[1:] {
+				if key, value, ok := strings.Cut(param, "="); ok {
+					if prop.Params == nil {
+						prop.Params = make(map[string]string)
+					}
+					prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+				}
+			}
+			return prop, true
+		}
+	}
+	return icalProp{}, false
+}
+
+// splitQuoted splits s on sep, ignoring separators inside double quotes
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// icalZones maps each TZID in a calendar to a location: the IANA zone of the
+// same name when there is one (most servers), else a fixed zone built from the
+// VTIMEZONE's standard offset (Outlook-style names like "W. Europe Standard Time")
+func icalZones(cal *icalComponent) map[string]*time.Location {
+	zones := make(map[string]*time.Location)
+	for _, vtz := range cal.Children {
+		if vtz.Name != "VTIMEZONE" {
+			continue
+		}
+		tzid := vtz.text("TZID")
+		if tzid == "" {
+			continue
+		}
+		if loc := loadICalZone(tzid); loc != nil {
+			zones[tzid] = loc
+			continue
+		}
+		for _, rule := range vtz.Children {
+			if rule.Name != "STANDARD" {
+				continue
+			}
+			if offset, ok := parseUTCOffset(rule.text("TZOFFSETTO")); ok {
+				zones[tzid] = time.FixedZone(tzid, offset)
+				break
+			}
+		}
+	}
+	return zones
+}
+
+// loadICalZone loads tzid as an IANA zone, also accepting vendor-prefixed ids
+// like "/mozilla.org/20050126_1/Europe/Berlin"
+func loadICalZone(tzid string) *time.Location {
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for i := range parts {
+		if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return loc
+		}
+	}
+	return nil
+}
+
+// parseUTCOffset parses a TZOFFSETTO value (+0100, -0530, +013000) into seconds
+func parseUTCOffset(s string) (int, bool) {
+	if len(s) != 5 && len(s) != 7 || (s[0] != '+' && s[0] != '-') {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(s[1:3])
+	minutes, err2 := strconv.Atoi(s[3:5])
+	seconds := 0
+	var err3 error
+	if len(s) == 7 {
+		seconds, err3 = strconv.Atoi(s[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	offset := hours*3600 + minutes*60 + seconds
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, true
+}
+
+// parseICalTime parses a DATE or DATE-TIME property. UTC ("Z") times are
+// absolute; TZID times use zones; floating times use displayLocation. Dates
+// are midnight UTC, like all-day events from Google.
+func parseICalTime(p icalProp, zones map[string]*time.Location) (t time.Time, allDay bool, err error) {
+	value := p.Value
+	if p.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.Parse("20060102", value)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := displayLocation
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if zone, ok := zones[tzid]; ok {
+			loc = zone
+		} else if zone := loadICalZone(tzid); zone != nil {
+			loc = zone
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseICalDuration parses an RFC 5545 duration such as PT1H30M, P1D or -P1W
+func parseICalDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	num := 0
+	digits := false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			num = num*10 + int(c-'0')
+			digits = true
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		switch {
+		case c == 'W' && !inTime:
+			d += time.Duration(num) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(num) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(num) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(num) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(num) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		num, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	return sign * d, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	importManifestName = "import_manifest.json"
+	importDelay        = 250 * time.Millisecond // between pushes, so the queue isn't flooded
+)
+
+// importHeading matches the first markdown heading, used as the record title
+var importHeading = regexp.MustCompile(`(?m)^#\s+(.+)$`)
+
+// importManifest maps an imported file's absolute path to its content hash,
+// so re-running an import skips files that haven't changed
+type importManifest map[string]string
+
+// runImport pushes every .md file under a directory as a `create` action
+func runImport(args []string) {
+	var dir string
+	collection := "Inbox"
+	dryRun := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--collection", "-c":
+			if i+1 < len(args) {
+				collection = args[i+1]
+				i++
+			}
+		case "--dry-run":
+			dryRun = true
+		default:
+			dir = args[i]
+		}
+	}
+	if dir == "" {
+		fmt.Println("Usage: tm import <dir> [--collection Archive] [--dry-run]")
+		return
+	}
+
+	config := loadConfig()
+	if !dryRun && (config.URL == "" || config.Token == "") {
+		fmt.Fprintln(os.Stderr, "Error: THYMER_URL and THYMER_TOKEN required")
+		os.Exit(1)
+	}
+
+	files, err := findMarkdownFiles(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	manifestPath := filepath.Join(tmDataDir(), importManifestName)
+	manifest := loadImportManifest(manifestPath)
+
+	var imported, skipped, failed int
+	for i, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError reading %s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if manifest[path] == hash {
+			skipped++
+			continue
+		}
+
+		title, body := importTitle(path, string(data))
+		if dryRun {
+			fmt.Printf(em("would import %s → %q in %s\n"), path, title, collection)
+			imported++
+			continue
+		}
+
+		item := QueueItem{
+			Action:     "create",
+			Collection: collection,
+			Title:      title,
+			Content:    body,
+			CreatedAt:  time.Now().Format(time.RFC3339),
+		}
+		if err := sendToQueue(config, item); err != nil {
+			fmt.Fprintf(os.Stderr, "\nError importing %s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		imported++
+		manifest[path] = hash
+		if err := saveImportManifest(manifestPath, manifest); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: couldn't update manifest: %v\n", err)
+		}
+
+		printImportProgress(i+1, len(files))
+		time.Sleep(importDelay)
+	}
+	if !dryRun && imported > 0 {
+		fmt.Fprintln(os.Stderr) // end the progress bar line
+	}
+
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf(em("✓ %s %d files into %s (%d already imported, %d failed)\n"), verb, imported, collection, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findMarkdownFiles returns the absolute paths of .md files under dir, in walk order
+func findMarkdownFiles(dir string) ([]string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// importTitle takes the title from the first heading (removing it from the
+// body, as the plugin does for Inbox notes) or else the file name
+func importTitle(path, content string) (string, string) {
+	if m := importHeading.FindStringSubmatchIndex(content); m != nil {
+		title := strings.TrimSpace(content[m[2]:m[3]])
+		body := strings.TrimSpace(content[:m[0]] + content[m[1]:])
+		return title, body
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), content
+}
+
+func printImportProgress(done, total int) {
+	const width = 30
+	filled := done * width / total
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %d/%d", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), done, total)
+}
+
+func loadImportManifest(path string) importManifest {
+	manifest := importManifest{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &manifest)
+	}
+	return manifest
+}
+
+func saveImportManifest(path string, manifest importManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	jiraBucket     = "jira_issues"
+	jiraMetaBucket = "jira_meta"
+	jiraPageSize   = 100
+	jiraMaxPages   = 50
+)
+
+// JiraIssue represents a stored Jira issue
+type JiraIssue struct {
+	ID             string    `json:"id"`  // jira_PROJ-123
+	Key            string    `json:"key"` // PROJ-123
+	Project        string    `json:"project"`
+	Summary        string    `json:"summary"`
+	Description    string    `json:"description"`
+	Status         string    `json:"status"`          // To Do, In Progress, Done, ...
+	StatusCategory string    `json:"status_category"` // new, indeterminate, done
+	Priority       string    `json:"priority"`
+	IssueType      string    `json:"issue_type"`
+	Assignee       string    `json:"assignee"`
+	Reporter       string    `json:"reporter"`
+	Labels         []string  `json:"labels"`
+	URL            string    `json:"url"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Verb           string    `json:"-"` // transient: created, started, resolved, reopened, updated (not stored)
+}
+
+// ToMarkdown returns the issue as markdown with YAML frontmatter
+func (i JiraIssue) ToMarkdown() string {
+	var b strings.Builder
+
+	// YAML frontmatter
+	b.WriteString("---\n")
+	b.WriteString("collection: Jira\n")
+	b.WriteString(fmt.Sprintf("external_id: %s\n", i.ID))
+	if i.Verb != "" {
+		b.WriteString(fmt.Sprintf("verb: %s\n", i.Verb))
+	}
+	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(i.Key+" "+i.Summary)))
+	b.WriteString(fmt.Sprintf("key: %s\n", i.Key))
+	b.WriteString(fmt.Sprintf("project: %s\n", i.Project))
+	b.WriteString(fmt.Sprintf("status: %s\n", i.Status))
+	if i.Priority != "" {
+		b.WriteString(fmt.Sprintf("priority: %s\n", i.Priority))
+	}
+	if i.IssueType != "" {
+		b.WriteString(fmt.Sprintf("type: %s\n", i.IssueType))
+	}
+	if i.Assignee != "" {
+		b.WriteString(fmt.Sprintf("assignee: %s\n", i.Assignee))
+	}
+	if i.Reporter != "" {
+		b.WriteString(fmt.Sprintf("reporter: %s\n", i.Reporter))
+	}
+	if len(i.Labels) > 0 {
+		b.WriteString(fmt.Sprintf("labels: [%s]\n", strings.Join(i.Labels, ", ")))
+	}
+	b.WriteString(fmt.Sprintf("url: %s\n", i.URL))
+	b.WriteString(fmt.Sprintf("created: %s\n", i.CreatedAt.Format(time.RFC3339)))
+	b.WriteString(fmt.Sprintf("updated: %s\n", i.UpdatedAt.Format(time.RFC3339)))
+	b.WriteString("---\n\n")
+
+	// Body
+	if i.Description != "" {
+		b.WriteString(i.Description)
+	}
+
+	return filterFrontmatter(b.String())
+}
+
+// JiraSyncer handles syncing Jira issues matched by a JQL query
+type JiraSyncer struct {
+	client  *http.Client
+	db      *bolt.DB
+	baseURL string
+	email   string
+	token   string
+	jql     string
+
+	unchangedCount // for /status
+}
+
+// NewJiraSyncer creates a new syncer
+func NewJiraSyncer(baseURL, email, token, jql string, dataDir string) (*JiraSyncer, error) {
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "jira.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(jiraBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(jiraMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &JiraSyncer{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		db:      db,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		email:   email,
+		token:   token,
+		jql:     jql,
+	}, nil
+}
+
+// Close closes the database
+func (s *JiraSyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of cached issues
+func (s *JiraSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, jiraBucket)
+}
+
+// ClearCache clears all cached issues from the database
+func (s *JiraSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(jiraBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// JiraSyncResult contains sync statistics
+type JiraSyncResult struct {
+	Created   []JiraIssue
+	Updated   []JiraIssue
+	Unchanged int
+	Errors    []error
+}
+
+// SyncChanges runs the JQL query and returns changes
+func (s *JiraSyncer) SyncChanges(ctx context.Context) (*JiraSyncResult, error) {
+	result := &JiraSyncResult{
+		Created: make([]JiraIssue, 0),
+		Updated: make([]JiraIssue, 0),
+		Errors:  make([]error, 0),
+	}
+
+	issues, err := s.search(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		upsertResult, err := s.upsert(issue)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+
+		issue.Verb = upsertResult.Verb
+		switch upsertResult.Action {
+		case "created":
+			result.Created = append(result.Created, issue)
+		case "updated":
+			result.Updated = append(result.Updated, issue)
+		case "unchanged":
+			result.Unchanged++
+		}
+	}
+
+	return result, nil
+}
+
+// jiraSearchResponse is the subset of /rest/api/3/search/jql we use
+type jiraSearchResponse struct {
+	Issues        []jiraAPIIssue `json:"issues"`
+	NextPageToken string         `json:"nextPageToken"`
+	IsLast        bool           `json:"isLast"`
+}
+
+type jiraAPIIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string          `json:"summary"`
+		Description json.RawMessage `json:"description"` // Atlassian Document Format
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+		Reporter *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"reporter"`
+		Labels  []string `json:"labels"`
+		Created string   `json:"created"`
+		Updated string   `json:"updated"`
+	} `json:"fields"`
+}
+
+// search runs the configured JQL, following nextPageToken until the last page
+func (s *JiraSyncer) search(ctx context.Context) ([]JiraIssue, error) {
+	var issues []JiraIssue
+	var pageToken string
+
+	for page := 0; page < jiraMaxPages; page++ {
+		params := url.Values{}
+		params.Set("jql", s.jql)
+		params.Set("maxResults", fmt.Sprintf("%d", jiraPageSize))
+		params.Set("fields", "summary,description,status,priority,issuetype,project,assignee,reporter,labels,created,updated")
+		if pageToken != "" {
+			params.Set("nextPageToken", pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/rest/api/3/search/jql?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(s.email, s.token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search issues: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("jira API returned %d: %s", resp.StatusCode, string(body))
+		}
+
+		var apiResp jiraSearchResponse
+		err = json.NewDecoder(resp.Body).Decode(&apiResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range apiResp.Issues {
+			issues = append(issues, s.convertIssue(issue))
+		}
+
+		if apiResp.IsLast || apiResp.NextPageToken == "" {
+			return issues, nil
+		}
+		pageToken = apiResp.NextPageToken
+	}
+
+	logger.Warn("Jira search hit page limit", "pages", jiraMaxPages, "issues", len(issues))
+	return issues, nil
+}
+
+func (s *JiraSyncer) convertIssue(issue jiraAPIIssue) JiraIssue {
+	f := issue.Fields
+
+	ji := JiraIssue{
+		ID:             "jira_" + issue.Key,
+		Key:            issue.Key,
+		Project:        f.Project.Key,
+		Summary:        f.Summary,
+		Description:    adfToText(f.Description),
+		Status:         f.Status.Name,
+		StatusCategory: f.Status.StatusCategory.Key,
+		IssueType:      f.IssueType.Name,
+		Labels:         f.Labels,
+		URL:            s.baseURL + "/browse/" + issue.Key,
+	}
+
+	if f.Priority != nil {
+		ji.Priority = f.Priority.Name
+	}
+	if f.Assignee != nil {
+		ji.Assignee = f.Assignee.DisplayName
+	}
+	if f.Reporter != nil {
+		ji.Reporter = f.Reporter.DisplayName
+	}
+
+	// Jira timestamps look like 2024-01-15T10:30:00.000+0000
+	ji.CreatedAt, _ = time.Parse("2006-01-02T15:04:05.000-0700", f.Created)
+	ji.UpdatedAt, _ = time.Parse("2006-01-02T15:04:05.000-0700", f.Updated)
+
+	return ji
+}
+
+// adfToText flattens an Atlassian Document Format body into plain paragraphs
+func adfToText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	type adfNode struct {
+		Type    string            `json:"type"`
+		Text    string            `json:"text"`
+		Content []json.RawMessage `json:"content"`
+	}
+
+	var b strings.Builder
+	var walk func(json.RawMessage)
+	walk = func(data json.RawMessage) {
+		var node adfNode
+		if err := json.Unmarshal(data, &node); err != nil {
+			return
+		}
+		if node.Type == "text" {
+			b.WriteString(node.Text)
+		}
+		if node.Type == "hardBreak" {
+			b.WriteString("\n")
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+		switch node.Type {
+		case "paragraph", "heading", "codeBlock", "listItem":
+			b.WriteString("\n\n")
+		}
+	}
+	walk(raw)
+
+	return strings.TrimSpace(b.String())
+}
+
+// JiraUpsertResult contains the result of an upsert operation
+type JiraUpsertResult struct {
+	Action string // created, updated, unchanged
+	Verb   string // created, started, resolved, reopened, updated
+}
+
+func (s *JiraSyncer) upsert(issue JiraIssue) (*JiraUpsertResult, error) {
+	result := &JiraUpsertResult{}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(jiraBucket))
+
+		existing := b.Get([]byte(issue.ID))
+		if existing == nil {
+			data, err := json.Marshal(issue)
+			if err != nil {
+				return err
+			}
+			result.Action = "created"
+			result.Verb = "created"
+			return b.Put([]byte(issue.ID), data)
+		}
+
+		// Check if changed
+		var old JiraIssue
+		if err := json.Unmarshal(existing, &old); err != nil {
+			return err
+		}
+
+		if needsJiraUpdate(old, issue) {
+			data, err := json.Marshal(issue)
+			if err != nil {
+				return err
+			}
+			result.Action = "updated"
+			// Determine verb based on status transition
+			if old.Status != issue.Status {
+				result.Verb = jiraStatusToVerb(old.StatusCategory, issue.StatusCategory)
+			} else {
+				result.Verb = "updated"
+			}
+			return b.Put([]byte(issue.ID), data)
+		}
+
+		result.Action = "unchanged"
+		return nil
+	})
+
+	return result, err
+}
+
+// jiraStatusToVerb maps a status category transition to a journal verb
+func jiraStatusToVerb(oldCategory, newCategory string) string {
+	switch newCategory {
+	case "done":
+		return "resolved"
+	case "indeterminate":
+		if oldCategory == "done" {
+			return "reopened"
+		}
+		return "started"
+	case "new":
+		if oldCategory != "new" {
+			return "reopened"
+		}
+	}
+	return "updated"
+}
+
+func needsJiraUpdate(old, new JiraIssue) bool {
+	if old.Status != new.Status {
+		return true
+	}
+	if old.Summary != new.Summary {
+		return true
+	}
+	if old.Priority != new.Priority {
+		return true
+	}
+	if new.UpdatedAt.After(old.UpdatedAt) {
+		return true
+	}
+	return false
+}
+
+// Name implements Syncer
+func (s *JiraSyncer) Name() string {
+	return "jira"
+}
+
+// metaStore implements heldStore
+func (s *JiraSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, jiraMetaBucket
+}
+
+// Sync implements Syncer: runs the JQL query and renders changes as queue items
+func (s *JiraSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Jira sync complete", "created", len(result.Created), "updated", len(result.Updated), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	s.unchanged = result.Unchanged
+
+	changes := append(result.Created, result.Updated...)
+	items := make([]QueueItem, 0, len(changes))
+	for _, issue := range changes {
+		items = append(items, issue.queueItem())
+	}
+	return items, nil
+}
+
+func (i JiraIssue) queueItem() QueueItem {
+	return QueueItem{
+		ID:         fmt.Sprintf("jira-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      i.Summary,
+		Content:    i.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Source:     "jira",
+		ExternalID: i.ID,
+		SourceTime: i.UpdatedAt,
+		Verb:       i.Verb,
+	}
+}
+
+// Replay implements replayer: the cached issue as created
+func (s *JiraSyncer) Replay(id string) (QueueItem, error) {
+	issue, err := loadCached[JiraIssue](s.db, jiraBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	issue.Verb = "created"
+	return issue.queueItem(), nil
+}
+
+// ReplayIDs implements replayer
+func (s *JiraSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, jiraBucket)
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	lastfmBucket     = "lastfm_scrobbles"
+	lastfmMetaBucket = "lastfm_meta"
+	lastfmAPIBase    = "https://ws.audioscrobbler.com/2.0/"
+	lastfmPageSize   = 200 // API maximum for user.getRecentTracks
+	lastfmMaxPages   = 5   // per sync; a longer backlog is caught up on the next runs
+
+	// lastfmInitialWindow is how far back the first sync (or one after a resync)
+	// looks, so it doesn't import years of listening history
+	lastfmInitialWindow = 24 * time.Hour
+)
+
+// Last.fm API error codes that mean the key itself is bad
+const (
+	lastfmErrInvalidKey   = 10
+	lastfmErrSuspendedKey = 26
+)
+
+// LastfmScrobble is one stored scrobble, keyed by its ID in lastfmBucket
+type LastfmScrobble struct {
+	ID          string    `json:"id"` // lastfm_{unix timestamp}
+	Track       string    `json:"track"`
+	Artist      string    `json:"artist"`
+	Album       string    `json:"album,omitempty"`
+	URL         string    `json:"url"`
+	ScrobbledAt time.Time `json:"scrobbled_at"`
+}
+
+// Lifelog renders the scrobble as a one-line lifelog, e.g. "🎵 Song — Artist"
+func (s LastfmScrobble) Lifelog() string {
+	return fmt.Sprintf("🎵 %s — %s", s.Track, s.Artist)
+}
+
+// LastfmSyncer logs a Last.fm user's scrobbles as lifelog entries
+type LastfmSyncer struct {
+	client *http.Client
+	db     *bolt.DB
+	apiKey string
+	user   string
+
+	unchangedCount // for /status
+}
+
+// NewLastfmSyncer creates a new syncer
+func NewLastfmSyncer(apiKey, user, dataDir string) (*LastfmSyncer, error) {
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "lastfm.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(lastfmBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(lastfmMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &LastfmSyncer{
+		client: &http.Client{Timeout: 30 * time.Second},
+		db:     db,
+		apiKey: apiKey,
+		user:   user,
+	}, nil
+}
+
+// Close closes the database
+func (s *LastfmSyncer) Close() error {
+	return s.db.Close()
+}
+
+// Name implements Syncer
+func (s *LastfmSyncer) Name() string {
+	return "lastfm"
+}
+
+// metaStore implements heldStore
+func (s *LastfmSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, lastfmMetaBucket
+}
+
+// CachedCount implements Syncer: the number of stored scrobbles
+func (s *LastfmSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, lastfmBucket)
+}
+
+// ClearCache forgets every scrobble and the watermark, so the next sync starts
+// over from lastfmInitialWindow ago
+func (s *LastfmSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket([]byte(lastfmMetaBucket)); meta != nil {
+			if err := meta.Delete([]byte("last_scrobble")); err != nil {
+				return err
+			}
+		}
+
+		b := tx.Bucket([]byte(lastfmBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Prune implements pruner: drops scrobbles from before cutoff
+func (s *LastfmSyncer) Prune(cutoff time.Time) (int, error) {
+	return pruneBucket(s.db, lastfmBucket, func(v []byte) bool {
+		var scrobble LastfmScrobble
+		if err := json.Unmarshal(v, &scrobble); err != nil {
+			return false
+		}
+		return scrobble.ScrobbledAt.Before(cutoff)
+	})
+}
+
+// Verify implements verifier: looks up the configured user
+func (s *LastfmSyncer) Verify(ctx context.Context) error {
+	var resp struct {
+		User struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+	err := s.call(ctx, url.Values{"method": {"user.getinfo"}, "user": {s.user}}, &resp)
+	var apiErr *lastfmError
+	if errors.As(err, &apiErr) && (apiErr.Code == lastfmErrInvalidKey || apiErr.Code == lastfmErrSuspendedKey) {
+		return fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	return err
+}
+
+// lastfmError is the error body the API returns, e.g. {"error":10,"message":"Invalid API key"}
+type lastfmError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *lastfmError) Error() string {
+	return fmt.Sprintf("last.fm API error %d: %s", e.Code, e.Message)
+}
+
+// call performs a GET against the API with the key and JSON format added,
+// decoding either the result into v or the API error
+func (s *LastfmSyncer) call(ctx context.Context, params url.Values, v interface{}) error {
+	params.Set("api_key", s.apiKey)
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", lastfmAPIBase+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(resp.Body); err != nil {
+		return err
+	}
+
+	// Errors come back as {"error":N,...}, sometimes with a 200
+	var apiErr lastfmError
+	if json.Unmarshal(body.Bytes(), &apiErr) == nil && apiErr.Code != 0 {
+		return &apiErr
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("last.fm API returned %d: %s", resp.StatusCode, body.String())
+	}
+
+	return json.Unmarshal(body.Bytes(), v)
+}
+
+// lastfmTrack is the subset of a user.getRecentTracks track we use
+type lastfmTrack struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Artist struct {
+		Text string `json:"#text"`
+	} `json:"artist"`
+	Album struct {
+		Text string `json:"#text"`
+	} `json:"album"`
+	Date *struct {
+		UTS string `json:"uts"`
+	} `json:"date"` // nil for the track playing right now
+}
+
+// lastfmRecentTracks is one page of user.getRecentTracks
+type lastfmRecentTracks struct {
+	RecentTracks struct {
+		Track json.RawMessage `json:"track"`
+		Attr  struct {
+			TotalPages string `json:"totalPages"`
+		} `json:"@attr"`
+	} `json:"recenttracks"`
+}
+
+// tracks decodes the page's tracks. The API returns a bare object instead of
+// an array when there is exactly one.
+func (p lastfmRecentTracks) tracks() ([]lastfmTrack, error) {
+	raw := bytes.TrimSpace(p.RecentTracks.Track)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var track lastfmTrack
+		if err := json.Unmarshal(raw, &track); err != nil {
+			return nil, err
+		}
+		return []lastfmTrack{track}, nil
+	}
+	var tracks []lastfmTrack
+	err := json.Unmarshal(raw, &tracks)
+	return tracks, err
+}
+
+// fetchPage returns one page of scrobbles in [from, to], newest first, and the page count
+func (s *LastfmSyncer) fetchPage(ctx context.Context, from, to time.Time, page int) ([]lastfmTrack, int, error) {
+	params := url.Values{}
+	params.Set("method", "user.getrecenttracks")
+	params.Set("user", s.user)
+	params.Set("limit", strconv.Itoa(lastfmPageSize))
+	params.Set("page", strconv.Itoa(page))
+	params.Set("from", strconv.FormatInt(from.Unix(), 10))
+	params.Set("to", strconv.FormatInt(to.Unix(), 10))
+
+	var resp lastfmRecentTracks
+	if err := s.call(ctx, params, &resp); err != nil {
+		return nil, 0, fmt.Errorf("failed to list recent tracks: %w", err)
+	}
+	tracks, err := resp.tracks()
+	if err != nil {
+		return nil, 0, err
+	}
+	pages, _ := strconv.Atoi(resp.RecentTracks.Attr.TotalPages)
+	return tracks, pages, nil
+}
+
+// fetchSince returns scrobbles after the watermark, oldest first. The window
+// is pinned with "to" so new scrobbles don't shift pages mid-sync. When more
+// than lastfmMaxPages are waiting, only the oldest pages are fetched; the
+// rest follow on the next runs.
+func (s *LastfmSyncer) fetchSince(ctx context.Context, after time.Time) ([]lastfmTrack, error) {
+	from := after.Add(time.Second)
+	to := time.Now()
+
+	newest, pages, err := s.fetchPage(ctx, from, to, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var pageNums []int
+	var tracks []lastfmTrack
+	if pages <= lastfmMaxPages {
+		tracks = newest
+		for page := 2; page <= pages; page++ {
+			pageNums = append(pageNums, page)
+		}
+	} else {
+		logger.Info("Last.fm backlog larger than one sync, catching up", "pages", pages, "max_pages", lastfmMaxPages)
+		for page := pages - lastfmMaxPages + 1; page <= pages; page++ {
+			pageNums = append(pageNums, page)
+		}
+	}
+
+	for _, page := range pageNums {
+		more, _, err := s.fetchPage(ctx, from, to, page)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, more...)
+	}
+
+	// Pages run newest to oldest; flip so the watermark only moves forward
+	for i, j := 0, len(tracks)-1; i < j; i, j = i+1, j-1 {
+		tracks[i], tracks[j] = tracks[j], tracks[i]
+	}
+	return tracks, nil
+}
+
+// record stores a scrobble, returning false if it was already known
+func (s *LastfmSyncer) record(scrobble LastfmScrobble) (bool, error) {
+	var isNew bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(lastfmBucket))
+		if b.Get([]byte(scrobble.ID)) != nil {
+			return nil
+		}
+		isNew = true
+
+		data, err := json.Marshal(scrobble)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(scrobble.ID), data)
+	})
+	return isNew, err
+}
+
+func (s *LastfmSyncer) getLastScrobble() time.Time {
+	var t time.Time
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(lastfmMetaBucket))
+		if v := b.Get([]byte("last_scrobble")); v != nil {
+			if uts, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				t = time.Unix(uts, 0)
+			}
+		}
+		return nil
+	})
+	return t
+}
+
+func (s *LastfmSyncer) setLastScrobble(t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(lastfmMetaBucket))
+		return b.Put([]byte("last_scrobble"), []byte(strconv.FormatInt(t.Unix(), 10)))
+	})
+}
+
+// Sync implements Syncer: queues a lifelog entry for every scrobble newer than
+// the stored watermark, stamped with when it was scrobbled
+func (s *LastfmSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	after := s.getLastScrobble()
+	if after.IsZero() {
+		after = time.Now().Add(-lastfmInitialWindow)
+	}
+
+	tracks, err := s.fetchSince(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []QueueItem
+	var latest time.Time
+	unchanged := 0
+	for _, track := range tracks {
+		if track.Date == nil {
+			continue // now playing: scrobbled (with a date) once it finishes
+		}
+		uts, err := strconv.ParseInt(track.Date.UTS, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		scrobble := LastfmScrobble{
+			ID:          fmt.Sprintf("lastfm_%d", uts),
+			Track:       track.Name,
+			Artist:      track.Artist.Text,
+			Album:       track.Album.Text,
+			URL:         track.URL,
+			ScrobbledAt: time.Unix(uts, 0),
+		}
+		if scrobble.ScrobbledAt.After(latest) {
+			latest = scrobble.ScrobbledAt
+		}
+
+		isNew, err := s.record(scrobble)
+		if err != nil {
+			return nil, err
+		}
+		if !isNew {
+			unchanged++
+			continue
+		}
+		items = append(items, scrobble.queueItem())
+	}
+
+	if !latest.IsZero() {
+		if err := s.setLastScrobble(latest); err != nil {
+			logger.Warn("failed to save Last.fm watermark", "error", err)
+		}
+	}
+
+	s.unchanged = unchanged
+	logger.Debug("Last.fm sync complete", "scrobbles", len(items), "unchanged", unchanged)
+	return items, nil
+}
+
+func (s LastfmScrobble) queueItem() QueueItem {
+	return QueueItem{
+		ID:         fmt.Sprintf("lastfm-%d", time.Now().UnixNano()),
+		Action:     "lifelog",
+		Content:    s.Lifelog(),
+		CreatedAt:  s.ScrobbledAt.Format(time.RFC3339),
+		Source:     "lastfm",
+		ExternalID: s.ID,
+		SourceTime: s.ScrobbledAt,
+		Verb:       "scrobbled",
+	}
+}
+
+// Replay implements replayer
+func (s *LastfmSyncer) Replay(id string) (QueueItem, error) {
+	scrobble, err := loadCached[LastfmScrobble](s.db, lastfmBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	return scrobble.queueItem(), nil
+}
+
+// ReplayIDs implements replayer
+func (s *LastfmSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, lastfmBucket)
+}
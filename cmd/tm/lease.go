@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultAckLease is how long an ?ack=true consumer has to POST /ack an
+// item before it goes back to the queue (ack_lease changes it)
+const defaultAckLease = 60 * time.Second
+
+// leasedItem is an item handed to an ?ack=true consumer and not acked yet.
+// It stays in queue.db until acked, so a restart delivers it again.
+type leasedItem struct {
+	item    QueueItem
+	expires time.Time
+}
+
+// maxAckLease caps ?lease=, so a consumer can't hide an item for good
+const maxAckLease = time.Hour
+
+// leaseFor is the lease a /stream or /pending request asks for: ?lease=2m,
+// or ack_lease for plain ?ack=true. 0 means no lease (items are dropped
+// when sent).
+func (s *Server) leaseFor(r *http.Request) (time.Duration, error) {
+	q := r.URL.Query()
+	v := q.Get("lease")
+	if v == "" {
+		if q.Get("ack") == "true" {
+			return s.ackLease, nil
+		}
+		return 0, nil
+	}
+	d, err := parseDuration(v)
+	if err != nil || d <= 0 || d > maxAckLease {
+		return 0, fmt.Errorf("lease wants a duration up to %s (30s, 2m), got %q", maxAckLease, v)
+	}
+	return d, nil
+}
+
+// lease moves a popped item to the in-flight set instead of dropping it.
+// Call with s.mu held, after taking the item out of s.queue.
+func (s *Server) lease(item QueueItem, expires time.Time) {
+	s.inflight[item.ID] = leasedItem{item: item, expires: expires}
+}
+
+// expireLeases returns items whose lease ran out to the queue, keeping their
+// place in the drain order. With ack_max_expiries set, an item that has run
+// out that often is dropped instead, so one that always breaks the consumer
+// doesn't loop forever; the audit log keeps a "dropped" entry for it. Call
+// with s.mu held.
+func (s *Server) expireLeases(now time.Time) {
+	for id, leased := range s.inflight {
+		if now.Before(leased.expires) {
+			continue
+		}
+		delete(s.inflight, id)
+		s.expiries[id]++
+		if s.maxExpiries > 0 && s.expiries[id] >= s.maxExpiries {
+			logger.Warn("dropping item never acked", "id", id, "leases", s.expiries[id], "request_id", leased.item.RequestID)
+			s.unqueue(id)
+			s.stats.forget(leased.item)
+			s.audit.Record(AuditEntry{Source: leased.item.Source, ExternalID: leased.item.ExternalID, Verb: "dropped", Title: leased.item.Title})
+			continue
+		}
+		s.queue[id] = leased.item
+		logger.Warn("lease expired, requeued", "id", id, "request_id", leased.item.RequestID)
+	}
+}
+
+// ack completes the delivery of a leased item. Call with s.mu held.
+func (s *Server) ack(id string) bool {
+	leased, ok := s.inflight[id]
+	if !ok {
+		return false
+	}
+	delete(s.inflight, id)
+	s.unqueue(id)
+	s.stats.recordDelivered(leased.item)
+	return true
+}
+
+// handleAck confirms that an ?ack=true consumer has written an item
+// (POST /ack?id=...). An unknown id was acked already or its lease ran out.
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "id is required")
+		return
+	}
+
+	s.mu.Lock()
+	acked := s.ack(id)
+	s.mu.Unlock()
+	if !acked {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "no item in flight with id "+id)
+		return
+	}
+	logger.Debug("acked", "id", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "id": id})
+}
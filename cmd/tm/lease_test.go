@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// expireTimes leases the queue's only item and lets the lease run out n times
+func expireTimes(s *Server, n int) {
+	for range n {
+		if s.pop(time.Minute) == nil {
+			return
+		}
+		s.mu.Lock()
+		s.expireLeases(time.Now().Add(2 * time.Minute))
+		s.mu.Unlock()
+	}
+}
+
+func TestExpireLeasesRedeliversByDefault(t *testing.T) {
+	s := newTestServer(nil)
+	s.queueChanges(nil, []QueueItem{{ID: "a", Content: "x"}})
+
+	expireTimes(s, 10)
+	if _, ok := s.queue["a"]; !ok {
+		t.Fatal("item dropped after expired leases without ack_max_expiries")
+	}
+	item := s.pop(time.Minute)
+	if item == nil || item.LeaseDeadline == "" {
+		t.Fatalf("popped %+v, want a leased item", item)
+	}
+	s.mu.Lock()
+	acked := s.ack("a")
+	s.mu.Unlock()
+	if !acked || len(s.queue) != 0 || len(s.inflight) != 0 {
+		t.Errorf("after ack: acked=%v queued=%d in flight=%d", acked, len(s.queue), len(s.inflight))
+	}
+}
+
+func TestExpireLeasesMaxExpiries(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestServer(openTestStore(t, dir))
+	s.maxExpiries = 3
+	s.queueChanges(nil, []QueueItem{{ID: "a", Content: "x"}})
+
+	expireTimes(s, 2)
+	if _, ok := s.queue["a"]; !ok {
+		t.Fatal("item dropped before ack_max_expiries")
+	}
+	expireTimes(s, 1)
+	if len(s.queue) != 0 || len(s.inflight) != 0 {
+		t.Errorf("queued=%d in flight=%d after ack_max_expiries, want the item dropped", len(s.queue), len(s.inflight))
+	}
+	if s = restartedServer(t, s.store, dir); len(s.queue) != 0 {
+		t.Error("dropped item came back after a restart")
+	}
+}
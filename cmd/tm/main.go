@@ -12,24 +12,30 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
-
-	bolt "go.etcd.io/bbolt"
 )
 
-var logger *slog.Logger
+// logger defaults to info-level text on stderr so syncers are safe to use
+// outside runServer (CLI commands); runServer replaces it with the -v aware one.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
 
 const (
 	LocalServerPort = "19501"
@@ -37,27 +43,194 @@ const (
 )
 
 type Config struct {
-	URL                string
-	Token              string
-	GitHubToken        string
-	GitHubRepos        []string
-	ReadwiseToken      string
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleCalendars    []string
+	URL                    string // first target; url= may list several (see Targets)
+	Token                  string
+	TokenNext              string // token_next: also accepted by tm serve during a rotation
+	Targets                []Target
+	GitHubToken            string
+	GitHubRepos            []string
+	ReadwiseToken          string
+	GoogleClientID         string
+	GoogleClientSecret     string
+	GoogleCalendars        []string
+	CalendarMinDuration    string
+	CalendarIncludePast    bool   // calendar_since_startup=false: also queue events that ended before tm serve started
+	CalendarDeliverBefore  string // deliver upcoming events' records this long before they start ("" = on sync)
+	CalDAVURL              string // calendar, calendar home or server URL (Fastmail, iCloud, Nextcloud)
+	CalDAVUser             string
+	CalDAVPassword         string // app password; basic or digest auth
+	JiraBaseURL            string
+	JiraEmail              string
+	JiraToken              string
+	JiraJQL                string
+	QuietHours             string
+	SyncConcurrency        int
+	CalendarNames          map[string]string
+	ReadwiseMaxHighlights  int
+	ReadwiseMode           string
+	ReadwiseStyle          string // quote (default) or numbered
+	ReadwiseSeparator      string // blank (default) or rule
+	AccessLog              bool
+	GitHubReactionPriority int
+	GitHubScope            []string // mentioned, subscribed, assigned (empty = github_repos)
+	GitHubProjects         []string // orgs/{org}/projects/{n} or users/{user}/projects/{n}
+	GitHubInitialWindow    string   // first sync of a repo only queues issues updated within this (e.g. 30d)
+	GitHubPRFiles          bool     // add a Changed Files table to PRs that changed
+	GitHubPRFilesMax       int      // rows in that table (default githubPRFilesMax)
+	GitHubStatusIcons      bool     // prefix titles with a state emoji (open, merged, closed, draft)
+	GitHubLinkPRs          bool     // render a PR that closes an issue in the issue's record
+	GitHubBackfill         bool     // import each repo's full history in resumable batches
+	CacheRetention         string   // prune ended events, closed issues and idle documents older than this (e.g. 180d)
+	ThymerAppURL           string
+	StravaClientID         string
+	StravaClientSecret     string
+	RedditClientID         string
+	RedditClientSecret     string
+	SpotifyClientID        string
+	SpotifyClientSecret    string
+	SpotifyMode            string // recent (default), saved, both
+	LastfmAPIKey           string
+	LastfmUser             string
+	WeatherLocation        string // lat,lon[,place] for the daily weather lifelog
+	GitHubCollection       string // overrides the target collection per source
+	CalendarCollection     string
+	ReadwiseCollection     string
+	Footer                 bool     // end synced records with a link back to the source
+	FooterTemplate         string   // {label} and {url} placeholders (default defaultFooterTemplate)
+	ResyncMaxAge           string   // after a cache clear, only queue items newer than this (e.g. 90d)
+	AckLease               string   // time an ?ack=true consumer has to ack an item before it's redelivered (e.g. 2m)
+	AckMaxExpiries         int      // drop an item whose lease ran out this many times (0 = redeliver until acked)
+	HTMLToMarkdown         bool     // convert HTML in GitHub/Readwise bodies to markdown
+	MaxBodyChars           int      // truncate GitHub bodies and Readwise summaries past this, with a read-more link (0 = never)
+	RequireSyncLabel       bool     // only sync GitHub issues, Readwise documents and events carrying SyncLabel
+	SyncLabel              string   // label/tag/#hashtag for require_sync_label (default defaultSyncLabel)
+	ForwardURL             string   // tm serve POSTs a copy of every queued item here
+	ForwardSecret          string   // HMAC key for the X-TM-Signature-256 header
+	ErrorNotify            []string // thymer and/or webhook: where to report failing syncs
+	ErrorNotifyAfter       int      // consecutive failures before notifying (default 3)
+	ErrorWebhookURL        string   // e.g. a Slack incoming webhook
+	DefaultCollection      string   // collection for manual pushes without --collection (not lifelog/--section)
+	LifelogFormat          string   // shape lifelog content in the CLI, e.g. "- {time} {content}" ("" = plugin formats it)
+	StartupGrace           string   // how long tm serve waits for a plugin before the initial syncs (0 = don't wait)
+	Timezone               string   // IANA zone for displayed times and "today" (default: system zone)
+	ProxyURL               string   // proxy for every outbound request (default: HTTP_PROXY/HTTPS_PROXY)
+	MissingCollection      string   // default, create or error: when an item's collection doesn't exist
+	FrontmatterAllow       []string // only emit these frontmatter keys in synced records (empty = all)
+	FrontmatterDeny        []string // never emit these frontmatter keys
+
+	DedupKeys     dedupKeys         // dedup_key: source -> key template, e.g. calendar:{external_id}:{start}
+	SyncIntervals map[string]string // sync_intervals: source -> how often it syncs, e.g. reddit:1h
+	ContentType   string            // auto, text or markdown: how CLI pushes are tagged (default auto)
+
+	CalendarColorCategories map[string]string // calendar_color_categories: event color name or id -> category
+}
+
+// Target is one Thymer queue endpoint that pushed items are delivered to
+type Target struct {
+	URL   string
+	Token string
 }
 
 type QueueItem struct {
-	ID         string `json:"id"`
-	Content    string `json:"content"`
-	Action     string `json:"action,omitempty"`
-	Collection string `json:"collection,omitempty"`
-	Title      string `json:"title,omitempty"`
-	CreatedAt  string `json:"createdAt"`
+	ID               string    `json:"id"`
+	Content          string    `json:"content"`
+	Action           string    `json:"action,omitempty"`
+	Collection       string    `json:"collection,omitempty"`
+	Title            string    `json:"title,omitempty"`
+	Section          string    `json:"section,omitempty"`          // append under this heading of today's page (find-or-create)
+	RecordID         string    `json:"recordId,omitempty"`         // append-to-record: guid of the existing record to append to
+	CreateCollection bool      `json:"createCollection,omitempty"` // plugin may create a missing target collection
+	Raw              bool      `json:"raw,omitempty"`              // deliver content verbatim: no timestamps, trimming or layout heuristics (lifelog: shaped by lifelog_format)
+	CreatedAt        string    `json:"createdAt"`
+	Priority         int       `json:"priority,omitempty"`    // higher drains first; 0 = normal
+	ExternalID       string    `json:"external_id,omitempty"` // stable ID from the source (also in the frontmatter)
+	Upsert           bool      `json:"upsert,omitempty"`      // plugin finds-or-creates by ExternalID instead of appending
+	Part             int       `json:"part,omitempty"`        // --stream-large or a split Readwise document: 1-based part number; parts share ExternalID
+	Source           string    `json:"-"`                     // transient: github, calendar, readwise, jira (set by syncers)
+	SourceTime       time.Time `json:"-"`                     // transient: when the item happened or last changed (resync_max_age)
+	Verb             string    `json:"-"`                     // transient: for audit/logging
+	RequestID        string    `json:"requestId,omitempty"`   // X-Request-ID: correlates one push across tm, server and plugin logs
+	Seq              uint64    `json:"-"`                     // transient: server-side queue order, the last tie-breaker in drainsBefore
+
+	// OnMissingCollection tells the plugin what to do when Collection doesn't
+	// exist: default (today's journal), create, or error (nothing written)
+	OnMissingCollection string `json:"on_missing_collection,omitempty"`
+
+	// ContentType is text or markdown (tm detects it unless told). The plugin
+	// inserts text literally; markdown, or no type at all, is parsed.
+	ContentType string `json:"contentType,omitempty"`
+
+	// DeliverAfter (RFC 3339) snoozes the item: the server keeps it queued
+	// and only hands it to the plugin once this time has passed
+	DeliverAfter string `json:"deliverAfter,omitempty"`
+
+	// LeaseDeadline (RFC 3339) is set only on items sent to ?ack=true or
+	// ?lease= consumers: POST /ack before then, or the item is sent again
+	LeaseDeadline string `json:"leaseDeadline,omitempty"`
+}
+
+// validMissingCollection reports whether v is an on_missing_collection value ("" = unset)
+func validMissingCollection(v string) bool {
+	switch v {
+	case "", "default", "create", "error":
+		return true
+	}
+	return false
+}
+
+// actionAppendToRecord appends to the existing record named by RecordID
+const actionAppendToRecord = "append-to-record"
+
+// Queue priorities used by syncers. Anything else (e.g. --priority 5) is fine too.
+const (
+	priorityLow  = -1 // bulk backfills (Readwise) yield to everything else
+	priorityHigh = 1  // time-sensitive items (calendar reminders)
+)
+
+// sourceOrder breaks ties between items of equal priority queued in the same
+// second: tm's own notices and CLI pushes first, then time-sensitive sources,
+// bulk backfills last. Unlisted sources follow, by name.
+var sourceOrder = []string{"tm", "manual", "calendar", "caldav", "github", "github-projects", "jira", "reddit", "strava", "spotify", "lastfm", "weather", "readwise"}
+
+// sourceRank is the item's position in sourceOrder (len(sourceOrder) if unlisted)
+func sourceRank(item QueueItem) int {
+	if i := slices.Index(sourceOrder, statsSource(item)); i >= 0 {
+		return i
+	}
+	return len(sourceOrder)
+}
+
+// drainsBefore orders the queue deterministically: highest priority first,
+// then oldest (CreatedAt, to the second), then by sourceOrder, then in the
+// order the server queued them (Seq), which keeps a sync's batch in order
+func drainsBefore(a, b QueueItem) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	ta, errA := time.Parse(time.RFC3339, a.CreatedAt)
+	tb, errB := time.Parse(time.RFC3339, b.CreatedAt)
+	if errA == nil && errB == nil && !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	if ra, rb := sourceRank(a), sourceRank(b); ra != rb {
+		return ra < rb
+	}
+	if sa, sb := statsSource(a), statsSource(b); sa != sb {
+		return sa < sb
+	}
+	if a.Seq != b.Seq {
+		return a.Seq < b.Seq
+	}
+	return a.ID < b.ID
 }
 
 func main() {
+	initOutputMode()
 	args := os.Args[1:]
+	startup := loadConfig()
+	setDisplayLocation(startup)
+	setFrontmatterFilter(startup)
+	setProxy(startup)
 
 	// Handle special commands first (before config check)
 	if len(args) > 0 {
@@ -68,8 +241,14 @@ func main() {
 		case "auth":
 			if len(args) > 1 && args[1] == "google" {
 				runGoogleAuth()
+			} else if len(args) > 1 && args[1] == "strava" {
+				runStravaAuth()
+			} else if len(args) > 1 && args[1] == "reddit" {
+				runRedditAuth()
+			} else if len(args) > 1 && args[1] == "spotify" {
+				runSpotifyAuth()
 			} else {
-				fmt.Println("Usage: tm auth google")
+				fmt.Println("Usage: tm auth [google|strava|reddit|spotify]")
 			}
 			return
 		case "calendar":
@@ -79,6 +258,13 @@ func main() {
 			}
 			fmt.Println("Usage: tm calendar test")
 			return
+		case "github":
+			if len(args) > 1 && args[1] == "list" {
+				runGitHubList(args[2:])
+				return
+			}
+			fmt.Println("Usage: tm github list [--open|--closed] [--repo owner/name] [--json]")
+			return
 		case "calendars":
 			if len(args) > 1 {
 				switch args[1] {
@@ -94,6 +280,8 @@ func main() {
 					} else {
 						fmt.Println("Usage: tm calendars disable <calendar-id>")
 					}
+				case "refresh":
+					runCalendarsRefresh()
 				default:
 					runListCalendars()
 				}
@@ -102,45 +290,87 @@ func main() {
 			}
 			return
 		case "sync":
+			// --once: sync in-process and push straight to Thymer (no server);
+			// --watch does the same, printing each item as it's pushed
+			if len(args) > 2 && (args[2] == "--once" || args[2] == "--watch") {
+				runSyncOnce(args[1], args[2] == "--watch")
+				return
+			}
 			// Trigger sync via HTTP endpoint (no cache clear)
 			if len(args) > 1 {
 				switch args[1] {
 				case "github":
 					triggerHTTPSync("github", false)
+				case "github-projects":
+					triggerHTTPSync("github-projects", false)
 				case "calendar":
 					triggerHTTPSync("calendar", false)
+				case "caldav":
+					triggerHTTPSync("caldav", false)
 				case "readwise":
 					triggerHTTPSync("readwise", false)
+				case "jira":
+					triggerHTTPSync("jira", false)
+				case "strava":
+					triggerHTTPSync("strava", false)
+				case "reddit":
+					triggerHTTPSync("reddit", false)
+				case "spotify":
+					triggerHTTPSync("spotify", false)
+				case "lastfm":
+					triggerHTTPSync("lastfm", false)
+				case "weather":
+					triggerHTTPSync("weather", false)
 				default:
-					fmt.Println("Usage: tm sync [github|calendar|readwise]")
+					fmt.Println("Usage: tm sync [github|github-projects|calendar|caldav|readwise|jira|strava|reddit|spotify|lastfm|weather]")
 				}
 			} else {
-				fmt.Println("Usage: tm sync [github|calendar|readwise]")
+				fmt.Println("Usage: tm sync [github|github-projects|calendar|caldav|readwise|jira|strava|reddit|spotify|lastfm|weather]")
 			}
 			return
 		case "resync":
-			// Trigger sync via HTTP endpoint WITH cache clear
-			if len(args) > 1 {
-				switch args[1] {
-				case "github":
-					triggerHTTPSync("github", true)
-				case "calendar":
-					triggerHTTPSync("calendar", true)
-				case "readwise":
-					triggerHTTPSync("readwise", true)
-				default:
-					fmt.Println("Usage: tm resync [github|calendar|readwise]")
-				}
-			} else {
-				// Resync all
-				triggerHTTPSync("github", true)
-				triggerHTTPSync("calendar", true)
-				triggerHTTPSync("readwise", true)
-			}
+			// Trigger sync via HTTP endpoint WITH cache clear (after confirmation)
+			runResync(args[1:])
+			return
+		case "replay":
+			runReplay(args[1:])
 			return
 		case "readwise-sync":
 			triggerReadwiseSync()
 			return
+		case "log":
+			runLog(args[1:])
+			return
+		case "queue":
+			runQueue(args[1:])
+			return
+		case "status":
+			runStatus(args[1:])
+			return
+		case "flush":
+			runFlush(len(args) > 1 && args[1] == "--stdout")
+			return
+		case "open":
+			runOpen()
+			return
+		case "config":
+			runConfig(args[1:])
+			return
+		case "token":
+			runToken(args[1:])
+			return
+		case "import":
+			runImport(args[1:])
+			return
+		case "export":
+			runExport(args[1:])
+			return
+		case "import-state":
+			runImportState(args[1:])
+			return
+		case "whoami":
+			runWhoami()
+			return
 		case "--help", "-h", "help":
 			printUsage()
 			return
@@ -158,7 +388,9 @@ func main() {
 	}
 
 	// Parse arguments
-	req := QueueItem{Action: "append"}
+	req := QueueItem{Action: "append", RequestID: newRequestID()}
+	streamLarge := false
+	snooze := ""
 
 	// Parse flags
 	i := 0
@@ -182,6 +414,59 @@ func main() {
 				i += 2
 				continue
 			}
+		case "--create-collection":
+			req.CreateCollection = true
+			i++
+			continue
+		case "--on-missing-collection":
+			if i+1 < len(args) {
+				req.OnMissingCollection = args[i+1]
+				i += 2
+				continue
+			}
+		case "--raw":
+			req.Raw = true
+			i++
+			continue
+		case "--snooze":
+			if i+1 < len(args) {
+				snooze = args[i+1]
+				i += 2
+				continue
+			}
+		case "--content-type":
+			if i+1 < len(args) {
+				req.ContentType = args[i+1]
+				i += 2
+				continue
+			}
+		case "--stream-large":
+			streamLarge = true
+			i++
+			continue
+		case "--section":
+			if i+1 < len(args) {
+				req.Section = args[i+1]
+				i += 2
+				continue
+			}
+		case "--record":
+			if i+1 < len(args) {
+				req.RecordID = args[i+1]
+				i += 2
+				continue
+			}
+		case "--priority", "-p":
+			if i+1 < len(args) {
+				p, err := strconv.Atoi(args[i+1])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: --priority must be a number, got %q\n", args[i+1])
+					os.Exit(1)
+				}
+				req.Priority = p
+				i += 2
+				continue
+			}
 		case "lifelog":
 			req.Action = "lifelog"
 			// Rest of args become the content
@@ -201,6 +486,11 @@ func main() {
 		i++
 	}
 
+	if streamLarge {
+		runStreamLarge(config, req)
+		return
+	}
+
 	// If no content from args, read from stdin
 	if req.Content == "" {
 		stat, _ := os.Stdin.Stat()
@@ -219,8 +509,74 @@ func main() {
 		os.Exit(1)
 	}
 
+	if req.Section != "" && req.Action != "append" {
+		fmt.Fprintf(os.Stderr, "Error: --section only works with the append action, not %q\n", req.Action)
+		os.Exit(1)
+	}
+
+	if req.Raw && req.Action == "lifelog" {
+		fmt.Fprintln(os.Stderr, "Error: --raw can't be combined with lifelog (lifelog adds a timestamp)")
+		os.Exit(1)
+	}
+
+	if req.RecordID != "" && req.Action != actionAppendToRecord {
+		fmt.Fprintf(os.Stderr, "Error: --record only works with --action %s, not %q\n", actionAppendToRecord, req.Action)
+		os.Exit(1)
+	}
+	if req.Action == actionAppendToRecord && req.RecordID == "" {
+		fmt.Fprintf(os.Stderr, "Error: --action %s needs --record <recordId>\n", actionAppendToRecord)
+		os.Exit(1)
+	}
+	if req.Action == actionAppendToRecord && req.Collection != "" {
+		fmt.Fprintln(os.Stderr, "Error: --record can't be combined with --collection (the record already has one)")
+		os.Exit(1)
+	}
+
+	// default_collection routes quick captures; lifelog, --section and --record have their own target
+	if req.Collection == "" && req.Action != "lifelog" && req.Section == "" && req.RecordID == "" {
+		req.Collection = config.DefaultCollection
+	}
+
+	if req.OnMissingCollection == "" {
+		req.OnMissingCollection = config.MissingCollection
+	}
+	if !validMissingCollection(req.OnMissingCollection) {
+		fmt.Fprintf(os.Stderr, "Error: --on-missing-collection must be default, create or error, got %q\n", req.OnMissingCollection)
+		os.Exit(1)
+	}
+
+	if req.ContentType == "" {
+		req.ContentType = config.ContentType
+	}
+	if !validContentType(req.ContentType) {
+		fmt.Fprintf(os.Stderr, "Error: --content-type must be auto, text or markdown, got %q\n", req.ContentType)
+		os.Exit(1)
+	}
+
 	// Add timestamp from CLI (includes timezone)
-	req.CreatedAt = time.Now().Format(time.RFC3339)
+	now := time.Now()
+	req.CreatedAt = now.Format(time.RFC3339)
+
+	if snooze != "" {
+		until, err := parseSnooze(snooze, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		req.DeliverAfter = until.Format(time.RFC3339)
+	}
+
+	// lifelog_format: deliver the entry already shaped, so it reads the same in
+	// forwards and sinks; raw keeps the plugin from timestamping it again
+	if req.Action == "lifelog" && config.LifelogFormat != "" {
+		req.Content = formatLifelog(config.LifelogFormat, req.Content, now)
+		req.Raw = true
+	}
+
+	// auto: detect after lifelog_format, which may have turned the entry into markdown
+	if req.ContentType == "" || req.ContentType == contentTypeAuto {
+		req.ContentType = detectContentType(req.Content)
+	}
 
 	// Send to queue
 	if err := sendToQueue(config, req); err != nil {
@@ -228,22 +584,96 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Printf("✓ Queued %d bytes (%s)\n", len(req.Content), req.Action)
+	if len(config.Targets) > 1 {
+		fmt.Printf(em("✓ Queued %d bytes (%s) to %d targets, request_id=%s\n"), len(req.Content), req.Action, len(config.Targets), req.RequestID)
+		return
+	}
+	fmt.Printf(em("✓ Queued %d bytes (%s), request_id=%s\n"), len(req.Content), req.Action, req.RequestID)
+	if req.DeliverAfter != "" {
+		until, _ := time.Parse(time.RFC3339, req.DeliverAfter)
+		fmt.Printf("  Snoozed until %s\n", until.In(displayLocation).Format("Mon Jan 2 15:04"))
+	}
+}
+
+// formatLifelog fills lifelog_format's {time} (15:04), {date} (2006-01-02) and
+// {content} placeholders, with times in the display zone
+func formatLifelog(format, content string, t time.Time) string {
+	t = t.In(displayLocation)
+	return strings.NewReplacer(
+		"{time}", t.Format("15:04"),
+		"{date}", t.Format("2006-01-02"),
+		"{content}", strings.TrimSpace(content),
+	).Replace(format)
+}
+
+// runStreamLarge is the --stream-large path: stdin is sent in parts as it's read
+func runStreamLarge(config Config, req QueueItem) {
+	if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintln(os.Stderr, "Error: --stream-large reads from a pipe, e.g. cat export.md | tm --stream-large")
+		os.Exit(1)
+	}
+	if req.Action != "append" && req.Action != "create" {
+		fmt.Fprintf(os.Stderr, "Error: --stream-large only works with append or create, not %q\n", req.Action)
+		os.Exit(1)
+	}
+	if req.Section != "" {
+		fmt.Fprintln(os.Stderr, "Error: --stream-large can't be combined with --section")
+		os.Exit(1)
+	}
+	if req.Collection == "" {
+		req.Collection = config.DefaultCollection
+	}
+
+	parts, total, err := streamStdin(config, req, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (%d parts sent)\n", err, max(parts-1, 0))
+		os.Exit(1)
+	}
+	if parts == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+	fmt.Printf(em("✓ Queued %d bytes in %d parts (%s), request_id=%s\n"), total, parts, req.Action, req.RequestID)
 }
 
+// sendToQueue delivers req to every configured target. A failing target doesn't
+// stop the others; the returned error names each target that failed.
 func sendToQueue(config Config, req QueueItem) error {
+	targets := config.Targets
+	if len(targets) == 0 {
+		targets = []Target{{URL: config.URL, Token: config.Token}}
+	}
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	}
+	logger.Debug("sending", "request_id", req.RequestID, "action", req.Action, "bytes", len(req.Content), "targets", len(targets))
+
+	var errs []error
+	for _, t := range targets {
+		if err := sendToTarget(t, req); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.URL, err))
+		}
+	}
+	if len(errs) > 0 && len(targets) > 1 {
+		errs = append([]error{fmt.Errorf("delivered to %d of %d targets", len(targets)-len(errs), len(targets))}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
+func sendToTarget(t Target, req QueueItem) error {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
 
-	httpReq, err := http.NewRequest("POST", config.URL+"/queue", bytes.NewReader(body))
+	httpReq, err := http.NewRequest("POST", t.URL+"/queue", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+config.Token)
+	httpReq.Header.Set("Authorization", "Bearer "+t.Token)
+	httpReq.Header.Set(requestIDHeader, req.RequestID)
 
 	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
@@ -253,7 +683,7 @@ func sendToQueue(config Config, req QueueItem) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, apiErrorMessage(body))
 	}
 
 	return nil
@@ -264,174 +694,243 @@ func sendToQueue(config Config, req QueueItem) error {
 // ============================================================================
 
 type Server struct {
-	queue      map[string]QueueItem
-	mu         sync.RWMutex
-	token      string
-	ghSyncer   *GitHubSyncer
-	rwSyncer   *ReadwiseSyncer
-	calSyncer  *CalendarSyncer
+	queue       map[string]QueueItem
+	mu          sync.RWMutex
+	token       string
+	scheduler   *Scheduler
+	quiet       *QuietHours
+	audit       *AuditLog
+	forward     *Forwarder    // forward_url: copy of every queued item (nil = off)
+	flushed     chan struct{} // closed (and replaced) by POST /flush to wake SSE streams
+	streams     int           // connected SSE clients
+	firstClient chan struct{} // closed when the first plugin connects (/stream or /pending)
+	firstOnce   sync.Once
+	observers   observerHub // /observe subscribers
+	events      eventHub    // /events subscribers
+
+	missingCollection string            // missing_collection: default hint for synced items ("" = plugin's choice)
+	skipped           map[string]string // configured sources disabled at startup, with the reason (ready as far as /ready cares)
+	stats             *queueStats       // rolling queued/delivered counts for /queue/stats
+	dedup             dedupKeys         // dedup_key: a newly queued item replaces waiting ones with the same key
+	seq               uint64            // last Seq handed out by put
+	store             *queueStore       // queue.db: the queue as of the last change (nil = memory only)
+
+	inflight    map[string]leasedItem // handed to ?ack=true consumers, waiting for POST /ack
+	expiries    map[string]int        // leases that ran out per item, for maxExpiries
+	ackLease    time.Duration         // ack_lease: time to ack before an item is redelivered
+	maxExpiries int                   // ack_max_expiries: drop an item after this many expired leases (0 = never)
+
+	authMu    sync.RWMutex // guards token and tokenNext, which POST /token/reload replaces
+	tokenNext string       // token_next: also accepted while clients move to it ("" = none)
+
+	reloadMu sync.Mutex // one config reload at a time (SIGHUP, POST /reload)
+	config   Config     // as last loaded, so a reload can tell what changed
+	noSync   bool       // --no-sync and --only, which reloads keep honoring
+	only     []string
+	started  time.Time // calendar_since_startup watermark
 }
 
-func resyncRepo(repo string) {
-	home, _ := os.UserHomeDir()
-	dbPath := filepath.Join(home, ".config", "tm", "github.db")
+func triggerReadwiseSync() {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
 
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	req, err := http.NewRequest("POST", url+"/readwise-sync?token="+token, nil)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer db.Close()
-
-	var deleted int
-	err = db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("github_issues"))
-		if b == nil {
-			return nil
-		}
-
-		var keysToDelete [][]byte
-		b.ForEach(func(k, v []byte) error {
-			key := string(k)
-			// If repo specified, only delete matching keys
-			// Key format: github_owner_repo_123
-			if repo == "" {
-				keysToDelete = append(keysToDelete, k)
-			} else {
-				repoSlug := strings.ReplaceAll(repo, "/", "_")
-				if strings.Contains(key, repoSlug) {
-					keysToDelete = append(keysToDelete, k)
-				}
-			}
-			return nil
-		})
-
-		for _, k := range keysToDelete {
-			if err := b.Delete(k); err != nil {
-				return err
-			}
-			deleted++
-		}
-		return nil
-	})
 
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
 		os.Exit(1)
 	}
+	defer resp.Body.Close()
 
-	if repo == "" {
-		fmt.Printf("✓ Cleared all %d GitHub issues from cache\n", deleted)
-	} else {
-		fmt.Printf("✓ Cleared %d issues for %s from cache\n", deleted, repo)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+		os.Exit(1)
 	}
-	fmt.Println("  Restart 'tm serve' to resync")
-}
 
-func resyncReadwise() {
-	home, _ := os.UserHomeDir()
-	dbPath := filepath.Join(home, ".config", "tm", "readwise.db")
+	fmt.Println(em("✓ Readwise sync triggered"))
+}
 
-	// Check if file exists
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		fmt.Println("✓ No Readwise cache to clear")
-		return
+// runResync clears caches for the named source (default: github, calendar,
+// readwise) after confirming how many items will be re-queued. --yes/-y skips
+// the prompt for scripts; --repo limits a GitHub resync to one repo.
+func runResync(args []string) {
+	var sources []string
+	var repo string
+	yes := false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--yes", "-y":
+			yes = true
+		case "--repo":
+			if i+1 < len(args) {
+				repo = args[i+1]
+				i++
+				continue
+			}
+			fmt.Println("Usage: tm resync github --repo owner/name [--yes]")
+			return
+		case "github", "github-projects", "calendar", "caldav", "readwise", "jira", "strava", "reddit", "spotify", "lastfm", "weather":
+			sources = append(sources, arg)
+		default:
+			fmt.Println("Usage: tm resync [github|github-projects|calendar|caldav|readwise|jira|strava|reddit|spotify|lastfm|weather] [--repo owner/name] [--yes]")
+			return
+		}
+	}
+	if repo != "" {
+		if len(sources) > 1 || (len(sources) == 1 && sources[0] != "github") {
+			fmt.Fprintln(os.Stderr, "Error: --repo only applies to github")
+			os.Exit(1)
+		}
+		sources = []string{"github"}
+	}
+	if len(sources) == 0 {
+		// Resync all
+		sources = []string{"github", "calendar", "readwise"}
 	}
 
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+	// Prefer the running server (it holds the cache locks); without one, open the caches directly
+	countCache, clearCache := fetchCachedCount, clearCacheHTTP
+	online := serverRunning()
+	if !online {
+		fmt.Println("tm serve isn't running — clearing the caches directly")
+		countCache, clearCache = cachedCountLocal, clearCacheLocal
 	}
-	defer db.Close()
 
-	var deleted int
-	err = db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("documents"))
-		if b == nil {
-			return nil
+	if !yes && repo != "" {
+		fmt.Printf("This will re-queue every cached item from %s on next sync — continue? [y/N] ", repo)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Aborted")
+			return
 		}
-
-		var keysToDelete [][]byte
-		b.ForEach(func(k, v []byte) error {
-			keysToDelete = append(keysToDelete, k)
-			return nil
-		})
-
-		for _, k := range keysToDelete {
-			if err := b.Delete(k); err != nil {
-				return err
+	} else if !yes {
+		var total int
+		for _, name := range sources {
+			n, err := countCache(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
-			deleted++
+			fmt.Printf("  %s: %d cached items\n", strings.Title(name), n)
+			total += n
 		}
-
-		// Also clear sync metadata
-		if meta := tx.Bucket([]byte("sync_meta")); meta != nil {
-			meta.Delete([]byte("last_sync"))
+		fmt.Printf("This will re-queue %d items on next sync — continue? [y/N] ", total)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Aborted")
+			return
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
 	}
 
-	fmt.Printf("✓ Cleared %d Readwise documents from cache\n", deleted)
-	fmt.Println("  Restart 'tm serve' to resync")
+	for _, name := range sources {
+		cleared, err := clearCache(name, repo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if repo != "" {
+			fmt.Printf(em("✓ Cleared %d cached items for %s\n"), cleared, repo)
+		} else {
+			fmt.Printf(em("✓ Cleared %d cached %s items\n"), cleared, strings.Title(name))
+		}
+		if online {
+			triggerHTTPSync(name, false)
+		}
+	}
+	if !online {
+		fmt.Println("  Start 'tm serve' to resync")
+	}
 }
 
-func resyncCalendar() {
-	home, _ := os.UserHomeDir()
-	dbPath := filepath.Join(home, ".config", "tm", "calendar.db")
+// serverRunning reports whether a tm serve answers /health at the configured URL
+func serverRunning() bool {
+	config := loadConfig()
 
-	// Check if file exists
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		fmt.Println("✓ No Calendar cache to clear")
-		return
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
 	}
 
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(url + "/health")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		return false
 	}
-	defer db.Close()
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
 
-	var deleted int
-	err = db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(calendarBucket))
-		if b == nil {
-			return nil
+// openLocalSyncer opens a source's cache in this process. Only use it when no
+// tm serve is running: the server holds the lock on every cache it syncs.
+func openLocalSyncer(name string) (Syncer, func(), error) {
+	syncer, err := buildSyncer(name, loadConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFn := func() {
+		if c, ok := syncer.(io.Closer); ok {
+			c.Close()
 		}
+	}
+	return syncer, closeFn, nil
+}
+
+// cachedCountLocal is fetchCachedCount without a server. Unconfigured sources count as 0.
+func cachedCountLocal(name string) (int, error) {
+	syncer, closeFn, err := openLocalSyncer(name)
+	if err == errNotConfigured {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer closeFn()
+	return syncer.CachedCount()
+}
 
-		var keysToDelete [][]byte
-		b.ForEach(func(k, v []byte) error {
-			keysToDelete = append(keysToDelete, k)
-			return nil
-		})
+// clearCacheLocal is clearCacheHTTP without a server
+func clearCacheLocal(name, repo string) (int, error) {
+	syncer, closeFn, err := openLocalSyncer(name)
+	if err == errNotConfigured {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer closeFn()
 
-		for _, k := range keysToDelete {
-			if err := b.Delete(k); err != nil {
-				return err
-			}
-			deleted++
+	if repo != "" {
+		pc, ok := syncer.(partialClearer)
+		if !ok {
+			return 0, fmt.Errorf("%s can't clear part of its cache", name)
 		}
+		return pc.ClearCacheFor(repo)
+	}
 
-		return nil
-	})
-
+	n, err := syncer.CachedCount()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return 0, err
 	}
-
-	fmt.Printf("✓ Cleared %d Calendar events from cache\n", deleted)
-	fmt.Println("  Restart 'tm serve' to resync")
+	return n, syncer.ClearCache()
 }
 
-func triggerReadwiseSync() {
+// clearCacheHTTP clears a source's cache (or one GitHub repo's part of it)
+// through the running server with DELETE /cache/{source}
+func clearCacheHTTP(syncType, repo string) (int, error) {
 	config := loadConfig()
 
 	url := config.URL
@@ -443,29 +942,39 @@ func triggerReadwiseSync() {
 		token = "local-dev-token"
 	}
 
-	req, err := http.NewRequest("POST", url+"/readwise-sync?token="+token, nil)
+	endpoint := fmt.Sprintf("%s/cache/%s?token=%s", url, syncType, token)
+	if repo != "" {
+		endpoint += "&repo=" + neturl.QueryEscape(repo)
+	}
+
+	req, err := http.NewRequest("DELETE", endpoint, nil)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return 0, err
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
-		os.Exit(1)
+		return 0, fmt.Errorf("%v (is 'tm serve' running?)", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		fmt.Fprintf(os.Stderr, "Error: %s\n", string(body))
-		os.Exit(1)
+		return 0, fmt.Errorf("%s", apiErrorMessage(body))
 	}
 
-	fmt.Println("✓ Readwise sync triggered")
+	var result struct {
+		Cleared int `json:"cleared"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	return result.Cleared, nil
 }
 
-func triggerHTTPSync(syncType string, resync bool) {
+// fetchCachedCount asks the running server how many items a source has cached.
+// Sources the server hasn't enabled count as 0.
+func fetchCachedCount(syncType string) (int, error) {
 	config := loadConfig()
 
 	url := config.URL
@@ -477,9 +986,44 @@ func triggerHTTPSync(syncType string, resync bool) {
 		token = "local-dev-token"
 	}
 
-	endpoint := fmt.Sprintf("%s/sync/%s?token=%s", url, syncType, token)
-	if resync {
-		endpoint += "&resync=true"
+	resp, err := http.Get(fmt.Sprintf("%s/sync/%s?token=%s", url, syncType, token))
+	if err != nil {
+		return 0, fmt.Errorf("%v (is 'tm serve' running?)", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("%s", apiErrorMessage(body))
+	}
+
+	var result struct {
+		Cached int `json:"cached"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	return result.Cached, nil
+}
+
+func triggerHTTPSync(syncType string, resync bool) {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+
+	endpoint := fmt.Sprintf("%s/sync/%s?token=%s", url, syncType, token)
+	if resync {
+		endpoint += "&resync=true"
 	}
 
 	req, err := http.NewRequest("POST", endpoint, nil)
@@ -497,7 +1041,7 @@ func triggerHTTPSync(syncType string, resync bool) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		fmt.Fprintf(os.Stderr, "Error: %s\n", string(body))
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
 		os.Exit(1)
 	}
 
@@ -505,16 +1049,53 @@ func triggerHTTPSync(syncType string, resync bool) {
 	if resync {
 		action = "resync"
 	}
-	fmt.Printf("✓ %s %s triggered\n", strings.Title(syncType), action)
+	fmt.Printf(em("✓ %s %s triggered\n"), strings.Title(syncType), action)
 }
 
 func runServer() {
-	// Check for verbose flag
+	// Check for flags
 	verbose := false
-	for _, arg := range os.Args[2:] {
-		if arg == "-v" || arg == "--verbose" {
+	drain := false
+	drainTimeout := defaultDrainTimeout
+	noSync := false
+	var only []string // --only: run just these sources ("" = every configured one)
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-v", "--verbose":
 			verbose = true
-			break
+		case "--no-sync":
+			noSync = true
+		case "--only":
+			if i+1 < len(args) {
+				only = parseRepoList(args[i+1])
+				i++
+			}
+			for _, name := range only {
+				if _, ok := findSyncSource(name); !ok {
+					fmt.Fprintf(os.Stderr, "Error: --only: unknown source %q\n", name)
+					os.Exit(1)
+				}
+			}
+			if len(only) == 0 {
+				fmt.Fprintln(os.Stderr, "Error: --only wants a source, e.g. --only github")
+				os.Exit(1)
+			}
+		case "--reload":
+			runReload()
+			return
+		case "--drain", "--once-then-exit":
+			drain = true
+		case "--timeout":
+			if i+1 < len(args) {
+				d, err := parseDuration(args[i+1])
+				if err != nil || d <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: --timeout wants a duration like 5m, got %q\n", args[i+1])
+					os.Exit(1)
+				}
+				drainTimeout = d
+				i++
+			}
 		}
 	}
 
@@ -529,276 +1110,456 @@ func runServer() {
 
 	config := loadConfig()
 
-	token := config.Token
-	if token == "" {
-		token = "local-dev-token"
+	token, tokenNext := serverTokens(config)
+	if config.Token == "" {
 		logger.Warn("no THYMER_TOKEN set, using default", "token", token)
 	}
+	if tokenNext != "" {
+		logger.Info("token rotation in progress: accepting token and token_next")
+	}
 
 	srv := &Server{
-		queue: make(map[string]QueueItem),
-		token: token,
+		queue:       make(map[string]QueueItem),
+		inflight:    make(map[string]leasedItem),
+		expiries:    make(map[string]int),
+		ackLease:    defaultAckLease,
+		token:       token,
+		tokenNext:   tokenNext,
+		flushed:     make(chan struct{}),
+		firstClient: make(chan struct{}),
+		skipped:     make(map[string]string),
+		stats:       newQueueStats(),
+		dedup:       config.DedupKeys,
+	}
+
+	// Rolling audit log of everything queued
+	home, _ := os.UserHomeDir()
+	auditDir := filepath.Join(home, ".config", "tm")
+	os.MkdirAll(auditDir, 0755)
+	if audit, err := NewAuditLog(auditDir, auditMaxEntries); err != nil {
+		logger.Warn("audit log disabled", "error", err)
+	} else {
+		srv.audit = audit
 	}
 
-	// Start GitHub sync if configured
-	if config.GitHubToken != "" && len(config.GitHubRepos) > 0 {
-		home, _ := os.UserHomeDir()
-		dataDir := filepath.Join(home, ".config", "tm")
-		os.MkdirAll(dataDir, 0755)
+	// Persisted queue: pick up whatever the last run didn't deliver
+	if store, err := openQueueStore(auditDir); err != nil {
+		logger.Warn("queue persistence disabled, items are lost on restart", "error", err)
+	} else {
+		srv.store = store
+		if n, err := srv.restoreQueue(); err != nil {
+			logger.Warn("failed to restore queue", "error", err)
+		} else if n > 0 {
+			logger.Info("restored queue from last run", "items", n)
+		}
+	}
 
-		syncer, err := NewGitHubSyncer(config.GitHubToken, config.GitHubRepos, dataDir)
-		if err != nil {
-			logger.Warn("GitHub sync disabled", "error", err)
+	if config.ForwardURL != "" {
+		srv.forward = NewForwarder(config.ForwardURL, config.ForwardSecret)
+		if config.ForwardSecret == "" {
+			logger.Warn("forwarding unsigned items: set forward_secret", "url", config.ForwardURL)
 		} else {
-			srv.ghSyncer = syncer
-			ctx := context.Background()
-			syncer.StartPeriodicSync(ctx, 1*time.Minute, func(issues []GitHubIssue) {
-				srv.queueGitHubChanges(issues)
-			})
-			logger.Info("GitHub sync enabled", "repos", strings.Join(config.GitHubRepos, ", "))
+			logger.Info("forwarding enabled", "url", config.ForwardURL)
 		}
 	}
 
-	// Start Readwise sync if configured
-	if config.ReadwiseToken != "" {
-		home, _ := os.UserHomeDir()
-		dataDir := filepath.Join(home, ".config", "tm")
-		os.MkdirAll(dataDir, 0755)
+	if validMissingCollection(config.MissingCollection) {
+		srv.missingCollection = config.MissingCollection
+	} else {
+		logger.Warn("ignoring missing_collection: want default, create or error", "value", config.MissingCollection)
+	}
 
-		syncer, err := NewReadwiseSyncer(config.ReadwiseToken, dataDir)
-		if err != nil {
-			logger.Warn("Readwise sync disabled", "error", err)
+	if config.AckLease != "" {
+		if d, err := parseDuration(config.AckLease); err != nil || d <= 0 {
+			logger.Warn("ignoring ack_lease", "value", config.AckLease)
 		} else {
-			srv.rwSyncer = syncer
-			go srv.startReadwiseSync(1 * time.Hour)
-			logger.Info("Readwise sync enabled", "interval", "1h")
+			srv.ackLease = d
 		}
 	}
+	if config.AckMaxExpiries < 0 {
+		logger.Warn("ignoring ack_max_expiries", "value", config.AckMaxExpiries)
+	} else {
+		srv.maxExpiries = config.AckMaxExpiries
+	}
 
-	// Start Google Calendar sync if configured
-	if len(config.GoogleCalendars) > 0 {
-		tokens, err := loadGoogleTokens()
+	// Hold GitHub/Readwise items during quiet hours (calendar is exempt)
+	if config.QuietHours != "" {
+		quiet, err := parseQuietHours(config.QuietHours)
 		if err != nil {
-			logger.Warn("Calendar sync disabled", "error", "not authenticated - run 'tm auth google'")
+			logger.Warn("quiet hours disabled", "error", err)
 		} else {
-			home, _ := os.UserHomeDir()
-			dataDir := filepath.Join(home, ".config", "tm")
+			srv.quiet = quiet
+			logger.Info("quiet hours enabled", "window", config.QuietHours)
+		}
+	}
 
-			calTokens := &CalendarTokens{
-				AccessToken:  tokens.AccessToken,
-				RefreshToken: tokens.RefreshToken,
-				TokenType:    tokens.TokenType,
-				Expiry:       tokens.Expiry,
-			}
+	srv.scheduler = NewScheduler(srv.queueChanges)
+	srv.scheduler.onEvent = srv.events.publish
+	if notifier := NewNotifier(config, srv.enqueue); notifier != nil {
+		srv.scheduler.onFailure = notifier.SyncFailed
+	}
+	if config.ResyncMaxAge != "" {
+		if d, err := parseDuration(config.ResyncMaxAge); err != nil {
+			logger.Warn("ignoring resync_max_age", "error", err)
+		} else {
+			srv.scheduler.maxAge = d
+		}
+	}
 
-			syncer, err := NewCalendarSyncer(calTokens, config.GoogleCalendars, dataDir)
-			if err != nil {
-				logger.Warn("Calendar sync disabled", "error", err)
-			} else {
-				srv.calSyncer = syncer
-				ctx := context.Background()
-				syncer.StartPeriodicSync(ctx, 5*time.Minute, func(events []CalendarEvent) {
-					srv.queueCalendarChanges(events)
-				})
-				logger.Info("Calendar sync enabled", "calendars", strings.Join(config.GoogleCalendars, ", "), "interval", "5m")
-			}
+	// Start every configured sync source (--no-sync: none, --only: just those)
+	if noSync {
+		logger.Info("syncs disabled for this run (--no-sync): serving the queue only")
+	}
+	srv.config, srv.noSync, srv.only, srv.started = config, noSync, only, time.Now()
+	for _, src := range syncSources {
+		if noSync || (len(only) > 0 && !slices.Contains(only, src.name)) {
+			continue
+		}
+		srv.startSource(src, config)
+	}
+
+	// Hold the initial syncs until a plugin is there to receive them (or the grace
+	// period runs out), so the first batch doesn't sit in the queue unseen
+	grace := defaultStartupGrace
+	if config.StartupGrace != "" {
+		if d, err := parseDuration(config.StartupGrace); err != nil {
+			logger.Warn("ignoring startup_grace", "error", err)
+		} else {
+			grace = d
+		}
+	}
+	if drain {
+		// CI/testing: one pass of every sync, then exit once it's all delivered
+		go srv.drain(drainTimeout)
+	} else {
+		go func() {
+			srv.awaitFirstClient(grace)
+			srv.scheduler.Start(context.Background())
+		}()
+	}
+
+	if srv.quiet != nil {
+		go srv.startQuietHoursFlush(1 * time.Minute)
+	}
+
+	// SIGHUP (or POST /reload, tm serve --reload) re-reads the config
+	go srv.reloadOnSignal()
+
+	if config.CacheRetention != "" {
+		if d, err := parseDuration(config.CacheRetention); err != nil || d <= 0 {
+			logger.Warn("ignoring cache_retention: want a positive duration like 180d", "value", config.CacheRetention)
+		} else {
+			go srv.startCacheCompaction(d, compactInterval)
 		}
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", srv.handleHealth)
-	mux.HandleFunc("/readwise-sync", srv.handleReadwiseSync)
-	mux.HandleFunc("/sync/github", srv.handleGitHubSync)
-	mux.HandleFunc("/sync/calendar", srv.handleCalendarSync)
-	mux.HandleFunc("/sync/readwise", srv.handleReadwiseSync)
+	mux.HandleFunc("/ready", srv.handleReady)
+	mux.HandleFunc("/status", srv.handleStatus)
+	mux.HandleFunc("/token/reload", srv.handleTokenReload)
+	mux.HandleFunc("/readwise-sync", srv.handleSync)
+	mux.HandleFunc("/sync/", srv.handleSync)
+	mux.HandleFunc("/cache/", srv.handleCache)
+	mux.HandleFunc("/calendars/refresh", srv.handleCalendarsRefresh)
+	mux.HandleFunc("/replay", srv.handleReplay)
+	mux.HandleFunc("/github/issues", srv.handleGitHubIssues)
 	mux.HandleFunc("/queue", srv.handleQueue)
+	mux.HandleFunc("/queue/stats", srv.handleQueueStats)
+	mux.HandleFunc("/reload", srv.handleReload)
 	mux.HandleFunc("/stream", srv.handleStream)
 	mux.HandleFunc("/pending", srv.handlePending)
+	mux.HandleFunc("/ack", srv.handleAck)
 	mux.HandleFunc("/peek", srv.handlePeek)
+	mux.HandleFunc("/audit", srv.handleAudit)
+	mux.HandleFunc("/flush", srv.handleFlush)
+	mux.HandleFunc("/observe", srv.handleObserve)
+	mux.HandleFunc("/events", srv.handleEvents)
+
+	handler := srv.corsMiddleware(mux)
+	if verbose || config.AccessLog {
+		handler = accessLogMiddleware(handler)
+	}
 
 	logger.Info("server starting", "port", LocalServerPort, "token", token)
 
-	if err := http.ListenAndServe(":"+LocalServerPort, srv.corsMiddleware(mux)); err != nil {
+	if err := http.ListenAndServe(":"+LocalServerPort, handler); err != nil {
 		logger.Error("server failed", "error", err)
 		os.Exit(1)
 	}
 }
 
-func (s *Server) queueGitHubChanges(issues []GitHubIssue) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// defaultStartupGrace is how long runServer waits for a plugin before the first syncs
+const defaultStartupGrace = 30 * time.Second
 
-	for _, issue := range issues {
-		item := QueueItem{
-			ID:        fmt.Sprintf("gh-%d", time.Now().UnixNano()),
-			Action:    "append",
-			Title:     issue.Title,
-			Content:   issue.ToMarkdown(),
-			CreatedAt: time.Now().Format(time.RFC3339),
-		}
-		s.queue[item.ID] = item
-		logger.Debug("queued GitHub issue", "repo", issue.Repo, "number", issue.Number, "state", issue.State)
+// awaitFirstClient blocks until a plugin connects or grace elapses
+func (s *Server) awaitFirstClient(grace time.Duration) {
+	if grace <= 0 {
+		return
+	}
+	logger.Info("waiting for a client before initial sync", "grace", grace)
+	select {
+	case <-s.firstClient:
+		logger.Info("client connected, starting sync")
+	case <-time.After(grace):
+		logger.Info("no client yet, starting sync anyway")
 	}
 }
 
-func (s *Server) queueCalendarChanges(events []CalendarEvent) {
+// clientConnected releases awaitFirstClient
+func (s *Server) clientConnected() {
+	s.firstOnce.Do(func() { close(s.firstClient) })
+}
+
+// queueChanges is the Scheduler's fan-in: it queues (or holds) a syncer's items
+func (s *Server) queueChanges(syncer Syncer, items []QueueItem) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, event := range events {
-		item := QueueItem{
-			ID:        fmt.Sprintf("cal-%d", time.Now().UnixNano()),
-			Action:    "append",
-			Title:     event.Title,
-			Content:   event.ToMarkdown(),
-			CreatedAt: time.Now().Format(time.RFC3339),
+	var queued []QueueItem
+	for _, item := range items {
+		item.Upsert = item.ExternalID != ""
+		if item.OnMissingCollection == "" {
+			item.OnMissingCollection = s.missingCollection
+		}
+		if item.RequestID == "" {
+			item.RequestID = newRequestID()
 		}
-		s.queue[item.ID] = item
-		logger.Debug("queued calendar event", "title", event.Title, "start", event.Start.Format("2006-01-02 15:04"), "verb", event.Verb)
+		if s.holdIfQuiet(syncer, item) {
+			logger.Debug("held for quiet hours", "source", item.Source, "external_id", item.ExternalID, "request_id", item.RequestID)
+			continue
+		}
+		queued = append(queued, s.put(item))
+		s.stats.recordQueued(item)
+		s.forward.Send(item)
+		s.audit.Record(AuditEntry{Source: item.Source, ExternalID: item.ExternalID, Verb: item.Verb, Title: item.Title})
+		logger.Debug("queued", "source", item.Source, "external_id", item.ExternalID, "verb", item.Verb, "request_id", item.RequestID)
 	}
+	s.store.save(queued...)
+}
+
+// put adds item to the queue, stamped with the next sequence number and
+// replacing dedup_key duplicates and snoozed older versions. It returns the
+// stamped item for the caller to persist with s.store.save, once per batch.
+// Call with s.mu held.
+func (s *Server) put(item QueueItem) QueueItem {
+	s.replaceDuplicate(item)
+	s.replaceSnoozed(item)
+	s.seq++
+	item.Seq = s.seq
+	s.queue[item.ID] = item
+	return item
 }
 
-func (s *Server) startReadwiseSync(interval time.Duration) {
-	// Initial sync after short delay (let server start)
-	time.Sleep(5 * time.Second)
-	s.doReadwiseSync()
+// enqueue adds one item generated by tm itself (e.g. a sync failure notice)
+func (s *Server) enqueue(item QueueItem) {
+	item.ID = fmt.Sprintf("tm-%d", time.Now().UnixNano())
+	item.CreatedAt = time.Now().Format(time.RFC3339)
+	item.RequestID = newRequestID()
 
-	// Periodic sync
-	ticker := time.NewTicker(interval)
-	for range ticker.C {
-		s.doReadwiseSync()
+	s.mu.Lock()
+	s.store.save(s.put(item))
+	s.mu.Unlock()
+	s.stats.recordQueued(item)
+	s.forward.Send(item)
+
+	s.audit.Record(AuditEntry{Source: item.Source, Verb: item.Verb, Title: item.Title})
+}
+
+// holdIfQuiet parks an item in its syncer's meta bucket during quiet hours.
+// Returns false (caller should queue normally) outside the window, for exempt
+// syncers, or on error.
+func (s *Server) holdIfQuiet(syncer Syncer, item QueueItem) bool {
+	store, ok := syncer.(heldStore)
+	if !ok || !s.quiet.Active(time.Now()) {
+		return false
+	}
+	db, bucket := store.metaStore()
+	if err := holdItem(db, bucket, item); err != nil {
+		logger.Error("failed to hold item, queueing now", "id", item.ID, "error", err)
+		return false
 	}
+	return true
 }
 
-func (s *Server) handleReadwiseSync(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
-		return
+// startQuietHoursFlush moves held items into the queue once quiet hours end
+func (s *Server) startQuietHoursFlush(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		if s.quiet.Active(time.Now()) {
+			continue
+		}
+		s.flushHeld()
 	}
+}
 
-	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
-		return
+func (s *Server) flushHeld() {
+	var held []QueueItem
+	for _, syncer := range s.scheduler.Syncers() {
+		store, ok := syncer.(heldStore)
+		if !ok {
+			continue
+		}
+		db, bucket := store.metaStore()
+		items, err := takeHeld(db, bucket)
+		if err != nil {
+			logger.Error("failed to flush held items", "source", syncer.Name(), "error", err)
+		}
+		for i := range items {
+			if items[i].Source == "" {
+				items[i].Source = syncer.Name() // held by an older tm, which didn't store it
+			}
+		}
+		held = append(held, items...)
 	}
 
-	if s.rwSyncer == nil {
-		http.Error(w, `{"error":"Readwise sync not configured"}`, http.StatusBadRequest)
+	if len(held) == 0 {
 		return
 	}
 
-	go s.doReadwiseSync()
+	s.mu.Lock()
+	queued := make([]QueueItem, 0, len(held))
+	for _, item := range held {
+		queued = append(queued, s.put(item))
+		s.stats.recordQueued(item)
+		s.forward.Send(item)
+		s.audit.Record(AuditEntry{Source: item.Source, ExternalID: item.ExternalID, Verb: item.Verb, Title: item.Title})
+	}
+	s.store.save(queued...)
+	s.mu.Unlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "sync started"})
+	logger.Info("quiet hours over, flushed held items", "count", len(held))
 }
 
-func (s *Server) doReadwiseSync() {
-	if s.rwSyncer == nil {
+// handleSync triggers a sync for /sync/{source} (and the legacy /readwise-sync).
+// ?resync=true clears the source's cache first. GET reports how many items
+// are cached, i.e. how many a resync would re-queue.
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" && r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "GET or POST only")
 		return
 	}
 
-	docs, err := s.rwSyncer.Sync()
-	if err != nil {
-		logger.Error("Readwise sync failed", "error", err)
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	if len(docs) == 0 {
-		logger.Debug("Readwise sync complete", "changes", 0)
-		return
+	name := strings.TrimPrefix(r.URL.Path, "/sync/")
+	if r.URL.Path == "/readwise-sync" {
+		name = "readwise"
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	syncer := s.scheduler.Get(name)
+	if syncer == nil {
+		writeError(w, http.StatusBadRequest, errCodeNotConfigured, name+" sync not configured")
+		return
+	}
 
-	for _, doc := range docs {
-		item := QueueItem{
-			ID:        fmt.Sprintf("rw-%d", time.Now().UnixNano()),
-			Action:    "append",
-			Title:     doc.Document.Title,
-			Content:   doc.ToMarkdown(),
-			CreatedAt: time.Now().Format(time.RFC3339),
+	if r.Method == "GET" {
+		cached, err := syncer.CachedCount()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+			return
 		}
-		s.queue[item.ID] = item
-		status := "updated"
-		if doc.IsNew {
-			status = "new"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"source": name, "cached": cached})
+		return
+	}
+
+	// Check for resync flag to clear cache first
+	if r.URL.Query().Get("resync") == "true" {
+		if err := s.scheduler.ClearCache(name); err != nil {
+			logger.Error("failed to clear cache", "source", name, "error", err)
+		} else {
+			logger.Info("cache cleared for resync", "source", name)
 		}
-		logger.Debug("queued Readwise", "title", doc.Document.Title, "status", status, "highlights", len(doc.Highlights))
 	}
-	logger.Info("Readwise sync complete", "documents", len(docs))
+
+	s.scheduler.Trigger(name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "sync started"})
 }
 
-func (s *Server) handleGitHubSync(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+// handleCache serves DELETE /cache/{source}[?repo=owner/name]: clears the cache
+// through the server's open syncer, so resync needs no restart. The next
+// sync (POST /sync/{source}) re-queues whatever was cleared.
+func (s *Server) handleCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "DELETE only")
 		return
 	}
 
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	if s.ghSyncer == nil {
-		http.Error(w, `{"error":"GitHub sync not configured"}`, http.StatusBadRequest)
+	name := strings.TrimPrefix(r.URL.Path, "/cache/")
+	syncer := s.scheduler.Get(name)
+	if syncer == nil {
+		writeError(w, http.StatusBadRequest, errCodeNotConfigured, name+" sync not configured")
 		return
 	}
 
-	// Check for resync flag to clear cache first
-	if r.URL.Query().Get("resync") == "true" {
-		if err := s.ghSyncer.ClearCache(); err != nil {
-			logger.Error("failed to clear GitHub cache", "error", err)
-		} else {
-			logger.Info("GitHub cache cleared for resync")
-		}
+	var cleared int
+	var err error
+	if repo := r.URL.Query().Get("repo"); repo != "" {
+		cleared, err = s.scheduler.ClearCacheFor(name, repo)
+	} else if cleared, err = syncer.CachedCount(); err == nil {
+		err = s.scheduler.ClearCache(name)
+	}
+	if err != nil {
+		logger.Error("failed to clear cache", "source", name, "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+		return
 	}
 
-	go s.ghSyncer.doSync(func(issues []GitHubIssue) {
-		s.queueGitHubChanges(issues)
-	})
+	logger.Info("cache cleared", "source", name, "cleared", cleared, "repo", r.URL.Query().Get("repo"))
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "sync started"})
+	json.NewEncoder(w).Encode(map[string]any{"source": name, "cleared": cleared})
 }
 
-func (s *Server) handleCalendarSync(w http.ResponseWriter, r *http.Request) {
+// handleCalendarsRefresh re-fetches the calendar list and returns what changed
+// since the cached names (POST /calendars/refresh)
+func (s *Server) handleCalendarsRefresh(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
 		return
 	}
 
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	if s.calSyncer == nil {
-		http.Error(w, `{"error":"Calendar sync not configured"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Check for resync flag to clear cache first
-	if r.URL.Query().Get("resync") == "true" {
-		if err := s.calSyncer.ClearCache(); err != nil {
-			logger.Error("failed to clear calendar cache", "error", err)
-		} else {
-			logger.Info("Calendar cache cleared for resync")
+	var change *CalendarNamesChange
+	err := s.scheduler.Do("calendar", func(syncer Syncer) error {
+		cal, ok := syncer.(*CalendarSyncer)
+		if !ok {
+			return fmt.Errorf("calendar sync not configured")
 		}
+		var err error
+		change, err = cal.RefreshCalendarNames(r.Context())
+		return err
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
 	}
 
-	go s.calSyncer.doSync(func(events []CalendarEvent) {
-		s.queueCalendarChanges(events)
-	})
+	logger.Info("calendar names refreshed", "added", len(change.Added), "removed", len(change.Removed), "renamed", len(change.Renamed))
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "sync started"})
+	json.NewEncoder(w).Encode(change)
 }
 
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		// Allow requests from public websites to localhost (Private Network Access)
 		w.Header().Set("Access-Control-Allow-Private-Network", "true")
@@ -819,7 +1580,10 @@ func (s *Server) checkAuth(r *http.Request) bool {
 	if token == "" {
 		token = r.URL.Query().Get("token")
 	}
-	return token == s.token
+
+	s.authMu.RLock()
+	defer s.authMu.RUnlock()
+	return token == s.token || (s.tokenNext != "" && token == s.tokenNext)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -827,25 +1591,80 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleReady is the readiness probe: 200 once every running syncer has
+// completed a successful sync, 503 until then. Sources disabled at startup
+// (bad token, locked cache) count as ready and are listed as skipped.
+// /health stays the cheap liveness probe.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	sources := make(map[string]string)
+	ready := true
+	for name, synced := range s.scheduler.Readiness() {
+		if synced {
+			sources[name] = "ok"
+		} else {
+			sources[name] = "pending"
+			ready = false
+		}
+	}
+	s.mu.RLock()
+	for name, reason := range s.skipped {
+		sources[name] = "skipped: " + reason
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	status := "ready"
+	if !ready {
+		status = "not_ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"status": status, "sources": sources})
+}
+
 func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
 		return
 	}
 
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req QueueItem
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid JSON")
 		return
 	}
 
 	if req.Content == "" {
-		http.Error(w, `{"error":"content required"}`, http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "content required")
+		return
+	}
+
+	if req.Section != "" && req.Action != "" && req.Action != "append" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "section requires the append action")
+		return
+	}
+
+	if (req.RecordID != "") != (req.Action == actionAppendToRecord) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "recordId and the append-to-record action go together")
+		return
+	}
+
+	if !validMissingCollection(req.OnMissingCollection) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "on_missing_collection must be default, create or error")
+		return
+	}
+
+	if req.ContentType != "" && req.ContentType != contentTypeText && req.ContentType != contentTypeMarkdown {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "contentType must be text or markdown")
+		return
+	}
+
+	if _, err := time.Parse(time.RFC3339, req.DeliverAfter); req.DeliverAfter != "" && err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "deliverAfter must be an RFC 3339 time")
 		return
 	}
 
@@ -853,19 +1672,39 @@ func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
 	req.ID = fmt.Sprintf("%d-%d", time.Now().UnixNano(), time.Now().UnixNano()%1000)
 	req.CreatedAt = time.Now().Format(time.RFC3339)
 
+	// Keep the sender's correlation id (header first, then body) so its logs line up with ours
+	if id := r.Header.Get(requestIDHeader); id != "" {
+		req.RequestID = id
+	}
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	}
+
 	s.mu.Lock()
-	s.queue[req.ID] = req
+	s.store.save(s.put(req))
 	s.mu.Unlock()
+	s.stats.recordQueued(req)
+	s.forward.Send(req)
+
+	s.audit.Record(AuditEntry{Source: "manual", ExternalID: req.ExternalID, Verb: req.Action, Title: req.Title})
 
-	logger.Debug("queued", "action", req.Action, "bytes", len(req.Content))
+	logger.Debug("queued", "action", req.Action, "bytes", len(req.Content), "id", req.ID, "request_id", req.RequestID)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": req.ID})
+	w.Header().Set(requestIDHeader, req.RequestID)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": req.ID, "request_id": req.RequestID})
 }
 
 func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// ?ack=true or ?lease=: items are leased until POST /ack instead of dropped when sent
+	lease, err := s.leaseFor(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -875,7 +1714,7 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeUnsupported, "SSE not supported")
 		return
 	}
 
@@ -883,7 +1722,18 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "event: connected\ndata: {}\n\n")
 	flusher.Flush()
 
-	logger.Info("SSE client connected")
+	logger.Info("SSE client connected", "lease", lease)
+	s.clientConnected()
+
+	s.mu.Lock()
+	s.streams++
+	flush := s.flushed
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.streams--
+		s.mu.Unlock()
+	}()
 
 	// Check queue every 2 seconds for 25 seconds
 	ticker := time.NewTicker(2 * time.Second)
@@ -893,12 +1743,28 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 
 	for {
 		select {
+		case <-flush:
+			// POST /flush: send everything now instead of one item per tick
+			var sent int
+			for item := s.pop(lease); item != nil; item = s.pop(lease) {
+				data, _ := json.Marshal(item)
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				logger.Debug("sent", "action", item.Action, "bytes", len(item.Content), "request_id", item.RequestID)
+				sent++
+			}
+			flusher.Flush()
+			logger.Debug("flushed", "items", sent)
+
+			s.mu.RLock()
+			flush = s.flushed
+			s.mu.RUnlock()
+
 		case <-ticker.C:
-			item := s.popOldest()
+			item := s.pop(lease)
 			if item != nil {
 				data, _ := json.Marshal(item)
 				fmt.Fprintf(w, "data: %s\n\n", data)
-				logger.Debug("sent", "action", item.Action, "bytes", len(item.Content))
+				logger.Debug("sent", "action", item.Action, "bytes", len(item.Content), "request_id", item.RequestID)
 			} else {
 				fmt.Fprintf(w, ": heartbeat\n\n")
 			}
@@ -915,27 +1781,60 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleFlush wakes every connected SSE stream to deliver the whole queue now
+func (s *Server) handleFlush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.mu.Lock()
+	queued, streams := len(s.queue)-s.snoozedCount(time.Now()), s.streams // snoozed items stay
+	close(s.flushed)
+	s.flushed = make(chan struct{})
+	s.mu.Unlock()
+
+	logger.Info("flush requested", "queued", queued, "streams", streams)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "queued": queued, "streams": streams})
+}
+
 func (s *Server) handlePending(w http.ResponseWriter, r *http.Request) {
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	lease, err := s.leaseFor(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
-	item := s.popOldest()
+	s.clientConnected()
+
+	item := s.pop(lease)
 	if item == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	logger.Debug("sent (poll)", "action", item.Action, "bytes", len(item.Content))
+	logger.Debug("sent (poll)", "action", item.Action, "bytes", len(item.Content), "request_id", item.RequestID)
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(requestIDHeader, item.RequestID)
 	json.NewEncoder(w).Encode(item)
 }
 
 func (s *Server) handlePeek(w http.ResponseWriter, r *http.Request) {
 	if !s.checkAuth(r) {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -946,9 +1845,9 @@ func (s *Server) handlePeek(w http.ResponseWriter, r *http.Request) {
 	}
 	s.mu.RUnlock()
 
-	// Sort by ID (timestamp-based)
+	// Sort in drain order (priority, then timestamp)
 	sort.Slice(items, func(i, j int) bool {
-		return items[i].ID < items[j].ID
+		return drainsBefore(items[i], items[j])
 	})
 
 	w.Header().Set("Content-Type", "application/json")
@@ -959,23 +1858,48 @@ func (s *Server) handlePeek(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) popOldest() *QueueItem {
+	return s.pop(0)
+}
+
+// pop takes the next item to deliver. With a lease (?ack=true and ?lease=
+// consumers) the item is only in flight until POST /ack, and comes back if
+// that doesn't arrive within the lease.
+func (s *Server) pop(lease time.Duration) *QueueItem {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	now := time.Now()
+	s.expireLeases(now)
 	if len(s.queue) == 0 {
 		return nil
 	}
 
-	// Find oldest by ID
+	// Find highest priority, oldest first within a priority; snoozed items wait
 	var oldestID string
-	for id := range s.queue {
-		if oldestID == "" || id < oldestID {
+	for id, item := range s.queue {
+		if !item.due(now) {
+			continue
+		}
+		if oldestID == "" || drainsBefore(item, s.queue[oldestID]) {
 			oldestID = id
 		}
 	}
+	if oldestID == "" {
+		return nil
+	}
 
 	item := s.queue[oldestID]
-	delete(s.queue, oldestID)
+	if lease > 0 {
+		delete(s.queue, oldestID)
+		s.lease(item, now.Add(lease))
+	} else {
+		s.unqueue(oldestID)
+		s.stats.recordDelivered(item)
+	}
+	s.observers.publish(item)
+	if lease > 0 {
+		item.LeaseDeadline = now.Add(lease).Format(time.RFC3339)
+	}
 	return &item
 }
 
@@ -989,6 +1913,7 @@ func loadConfig() Config {
 		Token:         os.Getenv("THYMER_TOKEN"),
 		GitHubToken:   os.Getenv("GITHUB_TOKEN"),
 		ReadwiseToken: os.Getenv("READWISE_TOKEN"),
+		JiraToken:     os.Getenv("JIRA_TOKEN"),
 	}
 
 	if repos := os.Getenv("GITHUB_REPOS"); repos != "" {
@@ -999,24 +1924,40 @@ func loadConfig() Config {
 	home, _ := os.UserHomeDir()
 	configPath := filepath.Join(home, ".config", "tm", "config")
 	data, err := os.ReadFile(configPath)
+	values := make(map[string]string) // first value of each key, for *_file resolution
 	if err == nil {
 		for _, line := range strings.Split(string(data), "\n") {
 			line = strings.TrimSpace(line)
 			if line == "" || strings.HasPrefix(line, "#") {
 				continue
 			}
+			line = expandConfigLine(line)
+			if key, value, ok := strings.Cut(line, "="); ok {
+				if _, seen := values[key]; !seen {
+					values[key] = value
+				}
+			}
 			if strings.HasPrefix(line, "url=") && config.URL == "" {
 				config.URL = strings.TrimPrefix(line, "url=")
 			}
 			if strings.HasPrefix(line, "token=") && config.Token == "" {
 				config.Token = strings.TrimPrefix(line, "token=")
 			}
+			if strings.HasPrefix(line, "token_next=") && config.TokenNext == "" {
+				config.TokenNext = strings.TrimPrefix(line, "token_next=")
+			}
 			if strings.HasPrefix(line, "github_token=") && config.GitHubToken == "" {
 				config.GitHubToken = strings.TrimPrefix(line, "github_token=")
 			}
 			if strings.HasPrefix(line, "github_repos=") && len(config.GitHubRepos) == 0 {
 				config.GitHubRepos = parseRepoList(strings.TrimPrefix(line, "github_repos="))
 			}
+			if strings.HasPrefix(line, "github_project=") && len(config.GitHubProjects) == 0 {
+				config.GitHubProjects = parseRepoList(strings.TrimPrefix(line, "github_project="))
+			}
+			if strings.HasPrefix(line, "github_scope=") && len(config.GitHubScope) == 0 {
+				config.GitHubScope = parseRepoList(strings.TrimPrefix(line, "github_scope="))
+			}
 			if strings.HasPrefix(line, "readwise_token=") && config.ReadwiseToken == "" {
 				config.ReadwiseToken = strings.TrimPrefix(line, "readwise_token=")
 			}
@@ -1029,12 +1970,279 @@ func loadConfig() Config {
 			if strings.HasPrefix(line, "google_calendars=") && len(config.GoogleCalendars) == 0 {
 				config.GoogleCalendars = parseRepoList(strings.TrimPrefix(line, "google_calendars="))
 			}
+			if strings.HasPrefix(line, "calendar_deliver_before=") && config.CalendarDeliverBefore == "" {
+				config.CalendarDeliverBefore = strings.TrimPrefix(line, "calendar_deliver_before=")
+			}
+			if strings.HasPrefix(line, "calendar_min_duration=") && config.CalendarMinDuration == "" {
+				config.CalendarMinDuration = strings.TrimPrefix(line, "calendar_min_duration=")
+			}
+			if strings.HasPrefix(line, "calendar_since_startup=") {
+				config.CalendarIncludePast = strings.TrimPrefix(line, "calendar_since_startup=") == "false"
+			}
+			if strings.HasPrefix(line, "caldav_url=") && config.CalDAVURL == "" {
+				config.CalDAVURL = strings.TrimPrefix(line, "caldav_url=")
+			}
+			if strings.HasPrefix(line, "caldav_user=") && config.CalDAVUser == "" {
+				config.CalDAVUser = strings.TrimPrefix(line, "caldav_user=")
+			}
+			if strings.HasPrefix(line, "caldav_password=") && config.CalDAVPassword == "" {
+				config.CalDAVPassword = strings.TrimPrefix(line, "caldav_password=")
+			}
+			if strings.HasPrefix(line, "jira_base_url=") && config.JiraBaseURL == "" {
+				config.JiraBaseURL = strings.TrimPrefix(line, "jira_base_url=")
+			}
+			if strings.HasPrefix(line, "jira_email=") && config.JiraEmail == "" {
+				config.JiraEmail = strings.TrimPrefix(line, "jira_email=")
+			}
+			if strings.HasPrefix(line, "jira_token=") && config.JiraToken == "" {
+				config.JiraToken = strings.TrimPrefix(line, "jira_token=")
+			}
+			if strings.HasPrefix(line, "jira_jql=") && config.JiraJQL == "" {
+				config.JiraJQL = strings.TrimPrefix(line, "jira_jql=")
+			}
+			if strings.HasPrefix(line, "calendar_names=") && len(config.CalendarNames) == 0 {
+				config.CalendarNames = parseCalendarNames(strings.TrimPrefix(line, "calendar_names="))
+			}
+			if strings.HasPrefix(line, "readwise_max_highlights_per_item=") && config.ReadwiseMaxHighlights == 0 {
+				config.ReadwiseMaxHighlights, _ = strconv.Atoi(strings.TrimPrefix(line, "readwise_max_highlights_per_item="))
+			}
+			if strings.HasPrefix(line, "readwise_highlight_style=") && config.ReadwiseStyle == "" {
+				config.ReadwiseStyle = strings.TrimPrefix(line, "readwise_highlight_style=")
+			}
+			if strings.HasPrefix(line, "readwise_highlight_separator=") && config.ReadwiseSeparator == "" {
+				config.ReadwiseSeparator = strings.TrimPrefix(line, "readwise_highlight_separator=")
+			}
+			if strings.HasPrefix(line, "readwise_mode=") && config.ReadwiseMode == "" {
+				config.ReadwiseMode = strings.TrimPrefix(line, "readwise_mode=")
+			}
+			if strings.HasPrefix(line, "access_log=") {
+				config.AccessLog = strings.TrimPrefix(line, "access_log=") == "true"
+			}
+			if strings.HasPrefix(line, "github_collection=") && config.GitHubCollection == "" {
+				config.GitHubCollection = strings.TrimPrefix(line, "github_collection=")
+			}
+			if strings.HasPrefix(line, "calendar_collection=") && config.CalendarCollection == "" {
+				config.CalendarCollection = strings.TrimPrefix(line, "calendar_collection=")
+			}
+			if strings.HasPrefix(line, "readwise_collection=") && config.ReadwiseCollection == "" {
+				config.ReadwiseCollection = strings.TrimPrefix(line, "readwise_collection=")
+			}
+			if strings.HasPrefix(line, "max_body_chars=") && config.MaxBodyChars == 0 {
+				config.MaxBodyChars, _ = strconv.Atoi(strings.TrimPrefix(line, "max_body_chars="))
+			}
+			if strings.HasPrefix(line, "html_to_markdown=") {
+				config.HTMLToMarkdown = strings.TrimPrefix(line, "html_to_markdown=") == "true"
+			}
+			if strings.HasPrefix(line, "footer=") {
+				config.Footer = strings.TrimPrefix(line, "footer=") == "true"
+			}
+			if strings.HasPrefix(line, "require_sync_label=") {
+				config.RequireSyncLabel = strings.TrimPrefix(line, "require_sync_label=") == "true"
+			}
+			if strings.HasPrefix(line, "sync_label=") && config.SyncLabel == "" {
+				config.SyncLabel = strings.TrimPrefix(line, "sync_label=")
+			}
+			if strings.HasPrefix(line, "footer_template=") && config.FooterTemplate == "" {
+				config.FooterTemplate = strings.TrimPrefix(line, "footer_template=")
+			}
+			if strings.HasPrefix(line, "cache_retention=") && config.CacheRetention == "" {
+				config.CacheRetention = strings.TrimPrefix(line, "cache_retention=")
+			}
+			if strings.HasPrefix(line, "github_pr_files=") {
+				config.GitHubPRFiles = strings.TrimPrefix(line, "github_pr_files=") == "true"
+			}
+			if strings.HasPrefix(line, "github_link_prs=") {
+				config.GitHubLinkPRs = strings.TrimPrefix(line, "github_link_prs=") == "true"
+			}
+			if strings.HasPrefix(line, "github_backfill=") {
+				config.GitHubBackfill = strings.TrimPrefix(line, "github_backfill=") == "true"
+			}
+			if strings.HasPrefix(line, "github_status_icons=") {
+				config.GitHubStatusIcons = strings.TrimPrefix(line, "github_status_icons=") == "true"
+			}
+			if strings.HasPrefix(line, "github_pr_files_max=") && config.GitHubPRFilesMax == 0 {
+				config.GitHubPRFilesMax, _ = strconv.Atoi(strings.TrimPrefix(line, "github_pr_files_max="))
+			}
+			if strings.HasPrefix(line, "github_initial_window=") && config.GitHubInitialWindow == "" {
+				config.GitHubInitialWindow = strings.TrimPrefix(line, "github_initial_window=")
+			}
+			if strings.HasPrefix(line, "github_reaction_priority=") && config.GitHubReactionPriority == 0 {
+				config.GitHubReactionPriority, _ = strconv.Atoi(strings.TrimPrefix(line, "github_reaction_priority="))
+			}
+			if strings.HasPrefix(line, "thymer_app_url=") && config.ThymerAppURL == "" {
+				config.ThymerAppURL = strings.TrimPrefix(line, "thymer_app_url=")
+			}
+			if strings.HasPrefix(line, "strava_client_id=") && config.StravaClientID == "" {
+				config.StravaClientID = strings.TrimPrefix(line, "strava_client_id=")
+			}
+			if strings.HasPrefix(line, "strava_client_secret=") && config.StravaClientSecret == "" {
+				config.StravaClientSecret = strings.TrimPrefix(line, "strava_client_secret=")
+			}
+			if strings.HasPrefix(line, "reddit_client_id=") && config.RedditClientID == "" {
+				config.RedditClientID = strings.TrimPrefix(line, "reddit_client_id=")
+			}
+			if strings.HasPrefix(line, "reddit_client_secret=") && config.RedditClientSecret == "" {
+				config.RedditClientSecret = strings.TrimPrefix(line, "reddit_client_secret=")
+			}
+			if strings.HasPrefix(line, "spotify_client_id=") && config.SpotifyClientID == "" {
+				config.SpotifyClientID = strings.TrimPrefix(line, "spotify_client_id=")
+			}
+			if strings.HasPrefix(line, "spotify_client_secret=") && config.SpotifyClientSecret == "" {
+				config.SpotifyClientSecret = strings.TrimPrefix(line, "spotify_client_secret=")
+			}
+			if strings.HasPrefix(line, "spotify_mode=") && config.SpotifyMode == "" {
+				config.SpotifyMode = strings.TrimPrefix(line, "spotify_mode=")
+			}
+			if strings.HasPrefix(line, "lastfm_api_key=") && config.LastfmAPIKey == "" {
+				config.LastfmAPIKey = strings.TrimPrefix(line, "lastfm_api_key=")
+			}
+			if strings.HasPrefix(line, "lastfm_user=") && config.LastfmUser == "" {
+				config.LastfmUser = strings.TrimPrefix(line, "lastfm_user=")
+			}
+			if strings.HasPrefix(line, "weather_location=") && config.WeatherLocation == "" {
+				config.WeatherLocation = strings.TrimPrefix(line, "weather_location=")
+			}
+			if strings.HasPrefix(line, "sync_intervals=") && len(config.SyncIntervals) == 0 {
+				config.SyncIntervals = parseCalendarNames(strings.TrimPrefix(line, "sync_intervals="))
+			}
+			if strings.HasPrefix(line, "sync_concurrency=") && config.SyncConcurrency == 0 {
+				config.SyncConcurrency, _ = strconv.Atoi(strings.TrimPrefix(line, "sync_concurrency="))
+			}
+			if strings.HasPrefix(line, "error_notify=") && len(config.ErrorNotify) == 0 {
+				config.ErrorNotify = parseRepoList(strings.TrimPrefix(line, "error_notify="))
+			}
+			if strings.HasPrefix(line, "error_notify_after=") && config.ErrorNotifyAfter == 0 {
+				config.ErrorNotifyAfter, _ = strconv.Atoi(strings.TrimPrefix(line, "error_notify_after="))
+			}
+			if strings.HasPrefix(line, "error_webhook_url=") && config.ErrorWebhookURL == "" {
+				config.ErrorWebhookURL = strings.TrimPrefix(line, "error_webhook_url=")
+			}
+			if strings.HasPrefix(line, "forward_url=") && config.ForwardURL == "" {
+				config.ForwardURL = strings.TrimPrefix(line, "forward_url=")
+			}
+			if strings.HasPrefix(line, "forward_secret=") && config.ForwardSecret == "" {
+				config.ForwardSecret = strings.TrimPrefix(line, "forward_secret=")
+			}
+			if strings.HasPrefix(line, "timezone=") && config.Timezone == "" {
+				config.Timezone = strings.TrimPrefix(line, "timezone=")
+			}
+			if strings.HasPrefix(line, "proxy_url=") && config.ProxyURL == "" {
+				config.ProxyURL = strings.TrimPrefix(line, "proxy_url=")
+			}
+			if strings.HasPrefix(line, "missing_collection=") && config.MissingCollection == "" {
+				config.MissingCollection = strings.TrimPrefix(line, "missing_collection=")
+			}
+			if strings.HasPrefix(line, "frontmatter_allow=") && len(config.FrontmatterAllow) == 0 {
+				config.FrontmatterAllow = parseRepoList(strings.TrimPrefix(line, "frontmatter_allow="))
+			}
+			if strings.HasPrefix(line, "frontmatter_deny=") && len(config.FrontmatterDeny) == 0 {
+				config.FrontmatterDeny = parseRepoList(strings.TrimPrefix(line, "frontmatter_deny="))
+			}
+			if strings.HasPrefix(line, "calendar_color_categories=") && len(config.CalendarColorCategories) == 0 {
+				config.CalendarColorCategories = parseCalendarNames(strings.TrimPrefix(line, "calendar_color_categories="))
+			}
+			if strings.HasPrefix(line, "content_type=") && config.ContentType == "" {
+				config.ContentType = strings.TrimPrefix(line, "content_type=")
+			}
+			if strings.HasPrefix(line, "dedup_key=") && len(config.DedupKeys) == 0 {
+				config.DedupKeys = parseDedupKeys(strings.TrimPrefix(line, "dedup_key="))
+			}
+			if strings.HasPrefix(line, "default_collection=") && config.DefaultCollection == "" {
+				config.DefaultCollection = strings.TrimPrefix(line, "default_collection=")
+			}
+			if strings.HasPrefix(line, "lifelog_format=") && config.LifelogFormat == "" {
+				config.LifelogFormat = strings.TrimPrefix(line, "lifelog_format=")
+			}
+			if strings.HasPrefix(line, "startup_grace=") && config.StartupGrace == "" {
+				config.StartupGrace = strings.TrimPrefix(line, "startup_grace=")
+			}
+			if strings.HasPrefix(line, "resync_max_age=") && config.ResyncMaxAge == "" {
+				config.ResyncMaxAge = strings.TrimPrefix(line, "resync_max_age=")
+			}
+			if strings.HasPrefix(line, "quiet_hours=") && config.QuietHours == "" {
+				config.QuietHours = strings.TrimPrefix(line, "quiet_hours=")
+			}
+			if strings.HasPrefix(line, "ack_lease=") && config.AckLease == "" {
+				config.AckLease = strings.TrimPrefix(line, "ack_lease=")
+			}
+			if strings.HasPrefix(line, "ack_max_expiries=") && config.AckMaxExpiries == 0 {
+				config.AckMaxExpiries, _ = strconv.Atoi(strings.TrimPrefix(line, "ack_max_expiries="))
+			}
 		}
 	}
 
+	resolveSecretFiles(&config, values)
+
+	// url=a,b fans out to several Thymers; URL/Token stay the first for
+	// commands that talk to a single server (sync, flush, log)
+	config.Targets = parseTargets(config.URL, config.Token)
+	if len(config.Targets) > 0 {
+		config.URL = config.Targets[0].URL
+		config.Token = config.Targets[0].Token
+	}
+
 	return config
 }
 
+// resolveSecretFiles reads secrets named by token_file=, github_token_file=, etc.
+// (systemd credentials, Docker secrets, `op read` output). When a key has a
+// _file variant configured, precedence is key= > key_file= > environment.
+func resolveSecretFiles(config *Config, values map[string]string) {
+	secrets := []struct {
+		key string
+		dst *string
+	}{
+		{"token", &config.Token},
+		{"token_next", &config.TokenNext},
+		{"github_token", &config.GitHubToken},
+		{"readwise_token", &config.ReadwiseToken},
+		{"jira_token", &config.JiraToken},
+		{"caldav_password", &config.CalDAVPassword},
+		{"google_client_secret", &config.GoogleClientSecret},
+		{"strava_client_secret", &config.StravaClientSecret},
+		{"reddit_client_secret", &config.RedditClientSecret},
+		{"spotify_client_secret", &config.SpotifyClientSecret},
+		{"lastfm_api_key", &config.LastfmAPIKey},
+		{"forward_secret", &config.ForwardSecret},
+	}
+
+	for _, s := range secrets {
+		path, ok := values[s.key+"_file"]
+		if !ok {
+			continue
+		}
+		if v, ok := values[s.key]; ok {
+			*s.dst = v
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warn("can't read secret file", "key", s.key+"_file", "error", err)
+			continue
+		}
+		*s.dst = strings.TrimSpace(string(data))
+	}
+}
+
+// parseTargets pairs a comma-separated url list with a token list by position.
+// A single token is shared by every URL.
+func parseTargets(urls, tokens string) []Target {
+	tokenList := parseRepoList(tokens)
+
+	var targets []Target
+	for i, u := range parseRepoList(urls) {
+		t := Target{URL: u}
+		switch {
+		case i < len(tokenList):
+			t.Token = tokenList[i]
+		case len(tokenList) == 1:
+			t.Token = tokenList[0]
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
 func parseRepoList(s string) []string {
 	var repos []string
 	for _, r := range strings.Split(s, ",") {
@@ -1046,6 +2254,147 @@ func parseRepoList(s string) []string {
 	return repos
 }
 
+// configVarPattern matches ${VAR} references in config values
+var configVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandConfigLine resolves ${VAR} in a key=value line from the environment.
+// Only the braced form is expanded, so values containing a bare $ stay literal.
+func expandConfigLine(line string) string {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok || !strings.Contains(value, "${") {
+		return line
+	}
+
+	value = configVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, set := os.LookupEnv(name)
+		if !set {
+			logger.Warn("config references unset environment variable", "key", key, "var", name)
+		}
+		return v
+	})
+	return key + "=" + value
+}
+
+// parseCalendarNames parses "primary:Personal,work@company.com:Work" into
+// lowercased calendar ID -> label
+func parseCalendarNames(s string) map[string]string {
+	names := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		id, label, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || strings.TrimSpace(id) == "" || strings.TrimSpace(label) == "" {
+			continue
+		}
+		names[strings.ToLower(strings.TrimSpace(id))] = strings.TrimSpace(label)
+	}
+	return names
+}
+
+// parseDuration extends time.ParseDuration with a "d" (days) suffix, e.g. "90d"
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// runFlush drains the running server's queue. By default it asks the server
+// to push everything to the connected plugin now (POST /flush); with --stdout
+// it pulls items via /pending and prints them as JSON lines instead, so they
+// never reach Thymer.
+func runFlush(toStdout bool) {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+
+	if !toStdout {
+		resp, err := http.Post(fmt.Sprintf("%s/flush?token=%s", url, token), "application/json", nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+			os.Exit(1)
+		}
+
+		var result struct {
+			Queued  int `json:"queued"`
+			Streams int `json:"streams"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if result.Streams == 0 && result.Queued > 0 {
+			fmt.Printf(em("⚠ %d items queued but no plugin is connected; they'll go out when Thymer reconnects\n"), result.Queued)
+			return
+		}
+		fmt.Printf(em("✓ Flushing %d items to Thymer\n"), result.Queued)
+		return
+	}
+
+	var count int
+	for {
+		resp, err := http.Get(fmt.Sprintf("%s/pending?token=%s", url, token))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
+			os.Exit(1)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNoContent {
+			break
+		}
+		if resp.StatusCode != http.StatusOK {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(bytes.TrimSpace(body))
+		fmt.Println()
+		count++
+	}
+	fmt.Fprintf(os.Stderr, em("✓ Drained %d items\n"), count)
+}
+
+// runOpen opens Thymer in the browser (thymer_app_url, falling back to url)
+func runOpen() {
+	config := loadConfig()
+
+	target := config.ThymerAppURL
+	if target == "" {
+		target = config.URL
+	}
+	if target == "" {
+		fmt.Fprintln(os.Stderr, "Error: no URL configured")
+		fmt.Fprintln(os.Stderr, "Set thymer_app_url=https://... in ~/.config/tm/config")
+		os.Exit(1)
+	}
+
+	if err := openBrowser(target); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
+		fmt.Println(target)
+		os.Exit(1)
+	}
+
+	fmt.Printf(em("✓ Opened %s\n"), target)
+}
+
 func printUsage() {
 	fmt.Println("tm - Thymer queue CLI")
 	fmt.Println()
@@ -1055,35 +2404,182 @@ func printUsage() {
 	fmt.Println("  tm lifelog Had coffee with Alex     Push lifelog entry")
 	fmt.Println("  tm --collection 'Tasks' < todo.md   Push to specific collection")
 	fmt.Println("  tm create --title 'New Note'        Create new record")
+	fmt.Println("  tm --priority 5 < urgent.md         Deliver ahead of normal items")
+	fmt.Println("  tm -c Books --create-collection < b.md  Create the collection if it doesn't exist")
+	fmt.Println("  tm -c Books --on-missing-collection default < b.md  Use today's journal if it doesn't exist")
+	fmt.Println("  echo 'Call Bob' | tm --section Tasks  Append under today's ## Tasks heading")
+	fmt.Println("  tm --record <id> -a append-to-record < x.md  Append to an existing record")
+	fmt.Println("  mytool | tm --raw                    Deliver byte-for-byte (no timestamp or reformatting)")
+	fmt.Println("  make 2>&1 | tm --content-type text   Insert literally, no markdown parsing (default: auto)")
+	fmt.Println("  echo 'Call Bob' | tm --snooze 1d     Deliver tomorrow (or 4h, 2026-10-20, '2026-10-20 08:30')")
+	fmt.Println("  cat huge.md | tm --stream-large      Send in parts while reading (no full buffering)")
 	fmt.Println("  tm serve                            Run local queue server")
-	fmt.Println("  tm resync [repo|readwise|calendar]  Clear sync cache (resync on next serve)")
+	fmt.Println("  tm serve --drain [--timeout 10m]    Sync everything once, exit when delivered (CI)")
+	fmt.Println("  tm serve --no-sync                  Queue and plugin connection only, no syncs")
+	fmt.Println("  tm serve --only github[,calendar]   Run just these syncs this time")
+	fmt.Println("  tm serve --reload                   Re-read the config in the running server (or send it SIGHUP)")
+	fmt.Println("  tm resync [source] [--yes]          Clear sync cache and resync (asks first)")
+	fmt.Println("  tm resync github --repo owner/repo  Resync a single repo")
+	fmt.Println("  tm replay <external_id>             Re-queue one cached item (e.g. a deleted record)")
+	fmt.Println("  tm replay --list [--source github]  List the ids tm replay accepts")
 	fmt.Println("  tm readwise-sync                    Trigger Readwise sync now")
+	fmt.Println("  tm github list [--open] [--repo owner/name]  List cached GitHub issues (offline)")
+	fmt.Println("  tm sync <source> --once             Sync once and push directly (no server)")
+	fmt.Println("  tm sync <source> --watch            Same, printing each item as it's pushed")
+	fmt.Println("  tm log [--source github] [--since 24h]  Show sync history")
+	fmt.Println("  tm export --out backup.json         Dump every sync cache and the config (secrets redacted)")
+	fmt.Println("  tm import-state backup.json         Restore an export on a new machine (--force to overwrite)")
+	fmt.Println("  tm open                             Open Thymer in the browser")
+	fmt.Println("  tm flush [--stdout]                 Deliver the whole queue now (or dump it)")
+	fmt.Println("  tm queue stats [--json]             Queued/delivered counts per source, time in queue")
+	fmt.Println("  tm status [--json]                  Recent runs per sync source, backfill progress")
+	fmt.Println("  tm config get|set|unset <key> [value]  Read or edit ~/.config/tm/config")
+	fmt.Println("  tm token rotate [new-token]         Add token_next; run again to promote it (no downtime)")
+	fmt.Println("  tm import <dir> [-c Archive] [--dry-run]  Create a record per .md file (skips imported)")
+	fmt.Println("  tm whoami                           Show the Google/GitHub/Readwise accounts in use")
 	fmt.Println()
 	fmt.Println("Google Calendar:")
 	fmt.Println("  tm auth google                      Authenticate with Google")
 	fmt.Println("  tm calendars                        List available calendars")
 	fmt.Println("  tm calendars enable <id>            Enable calendar for sync")
 	fmt.Println("  tm calendars disable <id>           Disable calendar from sync")
+	fmt.Println("  tm calendars refresh                Re-fetch calendar names, report changes")
+	fmt.Println()
+	fmt.Println("Strava:")
+	fmt.Println("  tm auth strava                      Authenticate with Strava")
+	fmt.Println("  tm sync strava                      Sync new activities now")
+	fmt.Println()
+	fmt.Println("Reddit:")
+	fmt.Println("  tm auth reddit                      Authenticate with Reddit")
+	fmt.Println("  tm sync reddit                      Sync saved posts and comments now")
+	fmt.Println()
+	fmt.Println("Spotify:")
+	fmt.Println("  tm auth spotify                     Authenticate with Spotify")
+	fmt.Println("  tm sync spotify                     Sync recent plays / liked songs now")
 	fmt.Println()
 	fmt.Println("Actions:")
 	fmt.Println("  append (default)  Append to daily page")
 	fmt.Println("  lifelog           Add timestamped lifelog entry")
 	fmt.Println("  create            Create new record in collection")
+	fmt.Println("  append-to-record  Append to the record given by --record <recordId>")
 	fmt.Println()
 	fmt.Println("Server mode:")
 	fmt.Printf("  tm serve                            Start server on port %s\n", LocalServerPort)
-	fmt.Println("  tm serve -v                         Verbose logging (debug level)")
+	fmt.Println("  tm serve -v                         Verbose logging (debug level + access log)")
+	fmt.Println()
+	fmt.Println("Output:")
+	fmt.Println("  --no-emoji (or NO_COLOR=1)          Plain ASCII markers ([ok], [!]) instead of emoji")
 	fmt.Println()
 	fmt.Println("Config:")
 	fmt.Println("  Set THYMER_URL and THYMER_TOKEN environment variables")
 	fmt.Println("  Or create ~/.config/tm/config with:")
 	fmt.Println("    url=https://thymer.lifelog.my")
 	fmt.Println("    token=your-secret-token")
+	fmt.Println("    url=https://a.example,https://b.example  token=tok-a,tok-b  (deliver to both)")
+	fmt.Println("    thymer_app_url=https://myteam.thymer.com  (for tm open)")
+	fmt.Println("    default_collection=Inbox           (when --collection is omitted)")
+	fmt.Println("    lifelog_format=- {time} {content}  (shape lifelog entries in the CLI)")
 	fmt.Println()
 	fmt.Println("  For Google Calendar:")
 	fmt.Println("    google_client_id=YOUR_ID.apps.googleusercontent.com")
 	fmt.Println("    google_client_secret=YOUR_SECRET")
 	fmt.Println("    google_calendars=primary,work@company.com")
+	fmt.Println("    calendar_min_duration=15m          Skip shorter timed events")
+	fmt.Println("    calendar_deliver_before=5m         Deliver each event's record 5m before it starts, not on sync")
+	fmt.Println("    calendar_names=primary:Personal,work@company.com:Work")
+	fmt.Println("    calendar_color_categories=tomato:external,basil:focus  Set category: from event colors")
+	fmt.Println("    calendar_since_startup=false       Also queue events that ended before tm serve started")
+	fmt.Println()
+	fmt.Println("  For CalDAV (Fastmail, iCloud, Nextcloud):")
+	fmt.Println("    caldav_url=https://caldav.fastmail.com/dav/calendars/user/you@fastmail.com/")
+	fmt.Println("    caldav_user=you@fastmail.com")
+	fmt.Println("    caldav_password=APP_PASSWORD")
+	fmt.Println()
+	fmt.Println("  For Jira:")
+	fmt.Println("    jira_base_url=https://yourteam.atlassian.net")
+	fmt.Println("    jira_email=you@company.com")
+	fmt.Println("    jira_token=YOUR_API_TOKEN")
+	fmt.Println("    jira_jql=assignee=currentUser() AND statusCategory!=Done")
+	fmt.Println()
+	fmt.Println("  For Strava:")
+	fmt.Println("    strava_client_id=12345")
+	fmt.Println("    strava_client_secret=YOUR_SECRET")
+	fmt.Println()
+	fmt.Println("  For Reddit:")
+	fmt.Println("    reddit_client_id=YOUR_CLIENT_ID")
+	fmt.Println("    reddit_client_secret=YOUR_SECRET  (omit for installed apps)")
+	fmt.Println()
+	fmt.Println("  For Spotify:")
+	fmt.Println("    spotify_client_id=YOUR_CLIENT_ID")
+	fmt.Println("    spotify_client_secret=YOUR_SECRET")
+	fmt.Println("    spotify_mode=recent                recent, saved, or both")
+	fmt.Println()
+	fmt.Println("  For Last.fm (scrobbles as lifelog entries, API key only):")
+	fmt.Println("    lastfm_api_key=YOUR_API_KEY")
+	fmt.Println("    lastfm_user=your-username")
+	fmt.Println()
+	fmt.Println("  Log the weather once a day (Open-Meteo, no key needed):")
+	fmt.Println("    weather_location=38.72,-9.14,Lisbon")
+	fmt.Println()
+	fmt.Println("  Sync GitHub Projects (v2) items with their status and custom fields:")
+	fmt.Println("    github_project=orgs/acme/projects/7")
+	fmt.Println()
+	fmt.Println("  Sync only GitHub issues that involve you (instead of github_repos):")
+	fmt.Println("    github_scope=mentioned,assigned     mentioned, subscribed, and/or assigned")
+	fmt.Println()
+	fmt.Println("  Prune caches daily (past events, long-closed issues, idle documents):")
+	fmt.Println("    cache_retention=180d")
+	fmt.Println()
+	fmt.Println("  Only import recent GitHub activity the first time a repo syncs:")
+	fmt.Println("    github_initial_window=30d")
+	fmt.Println()
+	fmt.Println("  Add a Changed Files table (file, +/-) to PRs that changed:")
+	fmt.Println("    github_pr_files=true  github_pr_files_max=20")
+	fmt.Println()
+	fmt.Println("  Prefix GitHub titles with 🟢 open, 🟣 merged, 🔴 closed, 📝 draft:")
+	fmt.Println("    github_status_icons=true")
+	fmt.Println()
+	fmt.Println("  Show a PR that closes an issue (\"Fixes #12\") in the issue's record:")
+	fmt.Println("    github_link_prs=true")
+	fmt.Println()
+	fmt.Println("  Import each repo's full history, 500 issues and PRs per sync (resumes after restarts):")
+	fmt.Println("    github_backfill=true")
+	fmt.Println()
+	fmt.Println("  Hold GitHub/Readwise items overnight (flushed when the window ends):")
+	fmt.Println("    quiet_hours=22:00-07:00")
+	fmt.Println()
+	fmt.Println("  Time a consumer of /stream?ack=true or /pending?ack=true has to POST /ack an item:")
+	fmt.Println("    ack_lease=60s")
+	fmt.Println("  Drop an item instead of redelivering it once its lease has run out this often:")
+	fmt.Println("    ack_max_expiries=3")
+	fmt.Println()
+	fmt.Println("  Report syncs that keep failing (journal note and/or webhook):")
+	fmt.Println("    error_notify=thymer,webhook  error_notify_after=3  error_webhook_url=https://hooks.slack.com/...")
+	fmt.Println()
+	fmt.Println("  Forward a signed copy of every queued item (tm serve):")
+	fmt.Println("    forward_url=https://hooks.example.com/tm  forward_secret=<random string>")
+	fmt.Println()
+	fmt.Println("  Reach every API through a proxy (default: HTTP_PROXY/HTTPS_PROXY, NO_PROXY honored):")
+	fmt.Println("    proxy_url=http://proxy.corp.example:3128")
+	fmt.Println()
+	fmt.Println("  Wait for the plugin before the first syncs after tm serve starts (default 30s):")
+	fmt.Println("    startup_grace=30s")
+	fmt.Println()
+	fmt.Println("  Only re-queue recent items after tm resync (older ones are cached only):")
+	fmt.Println("    resync_max_age=90d")
+	fmt.Println()
+	fmt.Println("  Send sources to your own collections:")
+	fmt.Println("    github_collection=Issues  calendar_collection=Meetings  readwise_collection=Reading")
+	fmt.Println()
+	fmt.Println("  Convert HTML in GitHub/Readwise bodies to markdown:")
+	fmt.Println("    html_to_markdown=true")
+	fmt.Println()
+	fmt.Println("  Cut long GitHub bodies and Readwise summaries, linking to the full text:")
+	fmt.Println("    max_body_chars=2000")
+	fmt.Println()
+	fmt.Println("  End synced GitHub/Calendar/Readwise records with a link back:")
+	fmt.Println("    footer=true")
+	fmt.Println("    footer_template=[{label}]({url})")
 	fmt.Println()
 	fmt.Println("  For local development:")
 	fmt.Printf("    url=%s\n", LocalServerURL)
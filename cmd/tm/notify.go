@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// defaultNotifyAfter is how many consecutive failures trigger a notification
+const defaultNotifyAfter = 3
+
+// Notifier tells you when a source keeps failing, so a revoked token doesn't
+// quietly stop the inbox for days. Channels (error_notify=):
+//   - thymer: a note in today's journal, delivered through the queue
+//   - webhook: POST {"text": ...} to error_webhook_url (Slack incoming webhooks work as-is)
+//
+// It fires once per failure streak: after `after` consecutive failures, or on
+// the first failure when the source rejected its credentials.
+type Notifier struct {
+	after   int
+	thymer  bool
+	webhook string
+	enqueue func(QueueItem) // Server.enqueue, for the thymer channel
+	client  *http.Client
+}
+
+// NewNotifier builds a notifier from the config, or returns nil when no channel is enabled
+func NewNotifier(config Config, enqueue func(QueueItem)) *Notifier {
+	n := &Notifier{
+		after:   defaultNotifyAfter,
+		webhook: config.ErrorWebhookURL,
+		enqueue: enqueue,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if config.ErrorNotifyAfter > 0 {
+		n.after = config.ErrorNotifyAfter
+	}
+
+	channels := config.ErrorNotify
+	if len(channels) == 0 && n.webhook != "" {
+		channels = []string{"webhook"}
+	}
+	webhook := false
+	for _, ch := range channels {
+		switch ch {
+		case "thymer":
+			n.thymer = true
+		case "webhook":
+			webhook = true
+		default:
+			logger.Warn("ignoring unknown error_notify channel", "channel", ch)
+		}
+	}
+	if webhook && n.webhook == "" {
+		logger.Warn("error_notify=webhook needs error_webhook_url")
+	}
+	if !webhook {
+		n.webhook = ""
+	}
+
+	if !n.thymer && n.webhook == "" {
+		return nil
+	}
+	return n
+}
+
+// SyncFailed is the Scheduler's onFailure hook
+func (n *Notifier) SyncFailed(syncer Syncer, err error, failures int) {
+	auth := isAuthError(err)
+	if failures != n.after && !(auth && failures == 1) {
+		return
+	}
+
+	name := syncer.Name()
+	msg := fmt.Sprintf("⚠️ %s sync has failed %d times in a row: %v", name, failures, err)
+	if auth {
+		msg = fmt.Sprintf("⚠️ %s sync: credentials rejected, fix the token or re-auth: %v", name, err)
+	}
+	logger.Warn("notifying sync failure", "source", name, "failures", failures, "auth", auth)
+
+	if n.thymer {
+		n.enqueue(QueueItem{
+			Action:   "append",
+			Content:  msg,
+			Priority: priorityHigh,
+			Source:   "tm",
+			Verb:     "error",
+		})
+	}
+	if n.webhook != "" {
+		go n.post(name, msg, err, failures)
+	}
+}
+
+func (n *Notifier) post(name, msg string, syncErr error, failures int) {
+	body, _ := json.Marshal(map[string]any{
+		"text":     msg,
+		"source":   name,
+		"error":    syncErr.Error(),
+		"failures": failures,
+	})
+
+	resp, err := n.client.Post(n.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Warn("error webhook failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logger.Warn("error webhook failed", "status", resp.StatusCode)
+	}
+}
+
+// isAuthError reports whether a sync error means the source rejected its credentials
+func isAuthError(err error) bool {
+	if errors.Is(err, errInvalidToken) {
+		return true
+	}
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized {
+		return true
+	}
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// observerHub fans a copy of each dispatched item out to /observe subscribers
+type observerHub struct {
+	mu   sync.Mutex
+	subs map[chan QueueItem]struct{}
+}
+
+func (h *observerHub) subscribe() chan QueueItem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs == nil {
+		h.subs = make(map[chan QueueItem]struct{})
+	}
+	ch := make(chan QueueItem, 32)
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *observerHub) unsubscribe(ch chan QueueItem) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+}
+
+// publish never blocks delivery: slow observers miss items instead
+func (h *observerHub) publish(item QueueItem) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- item:
+		default:
+		}
+	}
+}
+
+// handleObserve is a read-only SSE feed: a "dispatched" event for every item
+// handed to a consumer, and a "depth" event with the queue size every 5s.
+// Unlike /stream it never pops items, so it can run alongside the plugin.
+func (s *Server) handleObserve(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errCodeUnsupported, "SSE not supported")
+		return
+	}
+
+	items := s.observers.subscribe()
+	defer s.observers.unsubscribe(items)
+
+	fmt.Fprintf(w, "event: connected\ndata: {}\n\n")
+	s.writeDepth(w)
+	flusher.Flush()
+
+	logger.Info("observer connected")
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item := <-items:
+			data, _ := json.Marshal(item)
+			fmt.Fprintf(w, "event: dispatched\ndata: %s\n\n", data)
+			flusher.Flush()
+
+		case <-ticker.C:
+			s.writeDepth(w)
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			logger.Info("observer disconnected")
+			return
+		}
+	}
+}
+
+func (s *Server) writeDepth(w http.ResponseWriter) {
+	s.mu.RLock()
+	queued, streams := len(s.queue), s.streams
+	s.mu.RUnlock()
+
+	data, _ := json.Marshal(map[string]int{"queued": queued, "streams": streams})
+	fmt.Fprintf(w, "event: depth\ndata: %s\n\n", data)
+}
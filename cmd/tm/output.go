@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// plainOutput swaps the CLI's emoji and symbols for ASCII markers, for logs,
+// CI, and terminals that mangle them. Set by --no-emoji or NO_COLOR.
+var plainOutput bool
+
+// plainMarkers maps each glyph the CLI prints to its ASCII stand-in. Wide
+// emoji are followed by two spaces in the source, so those pairs go first.
+var plainMarkers = strings.NewReplacer(
+	"⚠️  ", "[!] ",
+	"⚠️", "[!]",
+	"⚠", "[!]",
+	"✅", "[ok]",
+	"✓", "[ok]",
+	"✗", "[x]",
+	"🔐", "[auth]",
+	"→", "->",
+)
+
+// initOutputMode reads NO_COLOR (https://no-color.org) and strips --no-emoji
+// from os.Args, so the flag works before or after any command
+func initOutputMode() {
+	if os.Getenv("NO_COLOR") != "" {
+		plainOutput = true
+	}
+
+	args := os.Args[:1]
+	for _, arg := range os.Args[1:] {
+		if arg == "--no-emoji" {
+			plainOutput = true
+			continue
+		}
+		args = append(args, arg)
+	}
+	os.Args = args
+}
+
+// em returns s with its glyphs replaced by ASCII markers in plain output mode.
+// Wrap format strings, not user data, so titles keep their own characters.
+func em(s string) string {
+	if !plainOutput {
+		return s
+	}
+	return plainMarkers.Replace(s)
+}
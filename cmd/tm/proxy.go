@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// setProxy routes every outbound client through proxy_url when it's set.
+// Without it, HTTP_PROXY/HTTPS_PROXY/NO_PROXY apply as usual. Either way
+// loopback addresses go direct, so the CLI still reaches tm serve.
+//
+// Every client in tm uses http.DefaultTransport, directly or as the base
+// the Google and oauth2 clients copy, so changing its Proxy covers them all.
+// Run it before any client is built.
+func setProxy(config Config) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return
+	}
+	if config.ProxyURL == "" {
+		return // already http.ProxyFromEnvironment
+	}
+	if _, err := url.Parse(config.ProxyURL); err != nil {
+		logger.Warn("ignoring proxy_url", "error", err)
+		return
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  config.ProxyURL,
+		HTTPSProxy: config.ProxyURL,
+		NoProxy:    noProxy,
+	}).ProxyFunc()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
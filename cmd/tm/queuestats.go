@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// queueStatsWindow is how far back queue stats look. Older events are dropped.
+const queueStatsWindow = 24 * time.Hour
+
+// queueEvent is one item entering or leaving the queue
+type queueEvent struct {
+	source string
+	at     time.Time
+	wait   time.Duration // time in queue (deliveries only)
+}
+
+// queueStats keeps rolling in-memory counts of queued and delivered items for
+// GET /queue/stats. They start over when the server restarts.
+type queueStats struct {
+	mu        sync.Mutex
+	started   time.Time
+	pending   map[string]queueEvent // queue id -> when it was queued
+	queued    []queueEvent
+	delivered []queueEvent
+}
+
+func newQueueStats() *queueStats {
+	return &queueStats{started: time.Now(), pending: make(map[string]queueEvent)}
+}
+
+// statsSource names the source an item is counted under
+func statsSource(item QueueItem) string {
+	if item.Source == "" {
+		return "manual"
+	}
+	return item.Source
+}
+
+// recordQueued counts an item entering the queue
+func (q *queueStats) recordQueued(item QueueItem) {
+	now := time.Now()
+	event := queueEvent{source: statsSource(item), at: now}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[item.ID] = event
+	q.queued = append(trimEvents(q.queued, now), event)
+}
+
+// recordDelivered counts an item handed to the plugin, with its time in queue
+func (q *queueStats) recordDelivered(item QueueItem) {
+	now := time.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	event := queueEvent{source: statsSource(item), at: now}
+	if queued, ok := q.pending[item.ID]; ok {
+		event.wait = now.Sub(queued.at)
+		delete(q.pending, item.ID)
+	}
+	q.delivered = append(trimEvents(q.delivered, now), event)
+}
+
+// forget stops tracking an item that left the queue undelivered (dedup_key
+// replaced it). It still counts as queued.
+func (q *queueStats) forget(item QueueItem) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, item.ID)
+}
+
+// trimEvents drops events older than queueStatsWindow (events are in time order)
+func trimEvents(events []queueEvent, now time.Time) []queueEvent {
+	cutoff := now.Add(-queueStatsWindow)
+	i := sort.Search(len(events), func(i int) bool { return !events[i].at.Before(cutoff) })
+	return events[i:]
+}
+
+// queueStatsRow is one line of the stats table
+type queueStatsRow struct {
+	Source        string `json:"source"`
+	QueuedHour    int    `json:"queued_1h"`
+	QueuedDay     int    `json:"queued_24h"`
+	DeliveredHour int    `json:"delivered_1h"`
+	DeliveredDay  int    `json:"delivered_24h"`
+	AvgWait       string `json:"avg_wait,omitempty"` // mean time in queue of the last 24h's deliveries
+
+	waitTotal time.Duration
+	waited    int
+}
+
+func (r *queueStatsRow) add(e queueEvent, delivered bool, hourAgo time.Time) {
+	recent := !e.at.Before(hourAgo)
+	if !delivered {
+		r.QueuedDay++
+		if recent {
+			r.QueuedHour++
+		}
+		return
+	}
+	r.DeliveredDay++
+	if recent {
+		r.DeliveredHour++
+	}
+	r.waitTotal += e.wait
+	r.waited++
+}
+
+func (r *queueStatsRow) finish() {
+	if r.waited > 0 {
+		r.AvgWait = (r.waitTotal / time.Duration(r.waited)).Round(100 * time.Millisecond).String()
+	}
+}
+
+// queueStatsReport is the response to GET /queue/stats
+type queueStatsReport struct {
+	Since   time.Time       `json:"since"` // server start: counts cover at most this long
+	Pending int             `json:"pending"`
+	Sources []queueStatsRow `json:"sources"` // busiest first
+	Total   queueStatsRow   `json:"total"`
+}
+
+// report summarizes the last hour and day, per source and in total
+func (q *queueStats) report(pending int) queueStatsReport {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour)
+
+	q.mu.Lock()
+	q.queued = trimEvents(q.queued, now)
+	q.delivered = trimEvents(q.delivered, now)
+	rows := make(map[string]*queueStatsRow)
+	total := queueStatsRow{Source: "total"}
+	count := func(events []queueEvent, delivered bool) {
+		for _, e := range events {
+			row, ok := rows[e.source]
+			if !ok {
+				row = &queueStatsRow{Source: e.source}
+				rows[e.source] = row
+			}
+			row.add(e, delivered, hourAgo)
+			total.add(e, delivered, hourAgo)
+		}
+	}
+	count(q.queued, false)
+	count(q.delivered, true)
+	since := q.started
+	q.mu.Unlock()
+
+	report := queueStatsReport{Since: since, Pending: pending, Sources: make([]queueStatsRow, 0, len(rows))}
+	for _, row := range rows {
+		row.finish()
+		report.Sources = append(report.Sources, *row)
+	}
+	sort.Slice(report.Sources, func(i, j int) bool {
+		a, b := report.Sources[i], report.Sources[j]
+		if a.QueuedDay != b.QueuedDay {
+			return a.QueuedDay > b.QueuedDay
+		}
+		return a.Source < b.Source
+	})
+	total.finish()
+	report.Total = total
+	return report
+}
+
+// handleQueueStats reports rolling queue throughput (GET /queue/stats)
+func (s *Server) handleQueueStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "GET only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.mu.RLock()
+	pending := len(s.queue)
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.stats.report(pending))
+}
+
+// runQueue implements `tm queue stats [--json]`
+func runQueue(args []string) {
+	if len(args) == 0 || args[0] != "stats" {
+		fmt.Println("Usage: tm queue stats [--json]")
+		return
+	}
+	asJSON := len(args) > 1 && args[1] == "--json"
+
+	config := loadConfig()
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/queue/stats?token=%s", url, token))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+		os.Exit(1)
+	}
+	if asJSON {
+		os.Stdout.Write(body)
+		return
+	}
+
+	var report queueStatsReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-16s %9s %10s %12s %13s %9s\n", "SOURCE", "QUEUED 1H", "QUEUED 24H", "DELIVERED 1H", "DELIVERED 24H", "AVG WAIT")
+	for _, row := range append(report.Sources, report.Total) {
+		wait := row.AvgWait
+		if wait == "" {
+			wait = "-"
+		}
+		fmt.Printf("%-16s %9d %10d %12d %13d %9s\n", row.Source, row.QueuedHour, row.QueuedDay, row.DeliveredHour, row.DeliveredDay, wait)
+	}
+	fmt.Printf("\n%d waiting in the queue; counting since %s\n", report.Pending, report.Since.In(displayLocation).Format("2006-01-02 15:04"))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// queueBucket holds every undelivered item in queue.db, keyed by its ID
+const queueBucket = "queue"
+
+// persistedItem is a queue.db value. The server-side fields aren't part of
+// QueueItem's JSON (the plugin has no use for them), so they're stored
+// alongside: without Seq and Source a restart would change the drain order
+// and dedup_key matches, without Verb the audit log.
+type persistedItem struct {
+	Seq        uint64    `json:"seq"`
+	Source     string    `json:"source,omitempty"`
+	SourceTime time.Time `json:"sourceTime"`
+	Verb       string    `json:"verb,omitempty"`
+	Item       QueueItem `json:"item"`
+}
+
+func newPersistedItem(item QueueItem) persistedItem {
+	return persistedItem{Seq: item.Seq, Source: item.Source, SourceTime: item.SourceTime, Verb: item.Verb, Item: item}
+}
+
+// queueItem is the item as it was queued, server-side fields included
+func (p persistedItem) queueItem() QueueItem {
+	item := p.Item
+	item.Seq = p.Seq
+	item.Source = p.Source
+	item.SourceTime = p.SourceTime
+	item.Verb = p.Verb
+	return item
+}
+
+// queueStore writes the queue through to bolt, so items waiting for the
+// plugin (snoozed ones included) survive a crash or restart of tm serve. The
+// in-memory map stays the source of truth while the server runs; a nil
+// store persists nothing.
+type queueStore struct {
+	db *bolt.DB
+}
+
+// openQueueStore opens (or creates) queue.db in dataDir
+func openQueueStore(dataDir string) (*queueStore, error) {
+	db, err := openBolt(filepath.Join(dataDir, "queue.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(queueBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create queue bucket: %w", err)
+	}
+	return &queueStore{db: db}, nil
+}
+
+// load returns the items persisted by an earlier run
+func (q *queueStore) load() ([]QueueItem, error) {
+	var items []QueueItem
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(queueBucket)).ForEach(func(k, v []byte) error {
+			var stored persistedItem
+			if err := json.Unmarshal(v, &stored); err != nil {
+				logger.Warn("dropping unreadable queued item", "id", string(k), "error", err)
+				return nil
+			}
+			items = append(items, stored.queueItem())
+			return nil
+		})
+	})
+	return items, err
+}
+
+// save persists queued items in one transaction, so a sync's batch costs a
+// single fsync. A failed write only costs the items' durability, so it's
+// logged rather than refusing them.
+func (q *queueStore) save(items ...QueueItem) {
+	if q == nil || len(items) == 0 {
+		return
+	}
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(queueBucket))
+		for _, item := range items {
+			data, err := json.Marshal(newPersistedItem(item))
+			if err != nil {
+				return fmt.Errorf("item %s: %w", item.ID, err)
+			}
+			if err := b.Put([]byte(item.ID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warn("failed to persist queued items", "count", len(items), "error", err)
+	}
+}
+
+// remove forgets an item that was delivered or replaced
+func (q *queueStore) remove(id string) {
+	if q == nil {
+		return
+	}
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(queueBucket)).Delete([]byte(id))
+	})
+	if err != nil {
+		logger.Warn("failed to remove persisted item", "id", id, "error", err)
+	}
+}
+
+// restoreQueue loads the items an earlier run left undelivered. Their Seq
+// is kept, and new items continue after the highest, so the drain order is
+// the same as before the restart. They count as queued in this run's stats.
+func (s *Server) restoreQueue() (int, error) {
+	items, err := s.store.load()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range items {
+		s.queue[item.ID] = item
+		s.stats.recordQueued(item)
+		if item.Seq > s.seq {
+			s.seq = item.Seq
+		}
+	}
+	return len(items), nil
+}
+
+// unqueue removes an item from the queue and its store for good (delivered,
+// acked or replaced). Call with s.mu held.
+func (s *Server) unqueue(id string) {
+	delete(s.queue, id)
+	delete(s.expiries, id)
+	s.store.remove(id)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestServer is a Server with just the queue state, as runServer sets it up
+func newTestServer(store *queueStore) *Server {
+	return &Server{
+		queue:    make(map[string]QueueItem),
+		inflight: make(map[string]leasedItem),
+		expiries: make(map[string]int),
+		stats:    newQueueStats(),
+		store:    store,
+	}
+}
+
+func openTestStore(t *testing.T, dir string) *queueStore {
+	t.Helper()
+	store, err := openQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.db.Close() })
+	return store
+}
+
+// restartedServer closes store and restores its queue into a new server,
+// as the next tm serve would
+func restartedServer(t *testing.T, store *queueStore, dir string) *Server {
+	t.Helper()
+	if err := store.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(openTestStore(t, dir))
+	if _, err := s.restoreQueue(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func drainIDs(s *Server) []string {
+	var ids []string
+	for item := s.popOldest(); item != nil; item = s.popOldest() {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// testItems are queued in the same second, so only the source order and Seq
+// tell them apart
+func testItems() []QueueItem {
+	created := time.Now().Format(time.RFC3339)
+	return []QueueItem{
+		{ID: "r1", Content: "a", CreatedAt: created, Source: "readwise", Verb: "created", ExternalID: "doc-1"},
+		{ID: "g1", Content: "b", CreatedAt: created, Source: "github", Verb: "updated", ExternalID: "1", SourceTime: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{ID: "c1", Content: "c", CreatedAt: created, Source: "calendar", Verb: "created", ExternalID: "ev-1"},
+		{ID: "g2", Content: "d", CreatedAt: created, Source: "github", Verb: "created", ExternalID: "2"},
+		{ID: "p1", Content: "e", CreatedAt: created, Priority: 1, Source: "github", ExternalID: "3"},
+	}
+}
+
+func TestQueueStoreRestart(t *testing.T) {
+	want := newTestServer(nil)
+	want.queueChanges(nil, testItems())
+	wantOrder := drainIDs(want)
+
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	before := newTestServer(store)
+	before.queueChanges(nil, testItems())
+
+	s := restartedServer(t, store, dir)
+	if len(s.queue) != len(wantOrder) {
+		t.Fatalf("restored %d items, want %d", len(s.queue), len(wantOrder))
+	}
+	for id, item := range before.queue {
+		got := s.queue[id]
+		if got.Seq != item.Seq || got.Source != item.Source || got.Verb != item.Verb || !got.SourceTime.Equal(item.SourceTime) {
+			t.Errorf("%s restored as seq=%d source=%q verb=%q source_time=%v, want seq=%d source=%q verb=%q source_time=%v",
+				id, got.Seq, got.Source, got.Verb, got.SourceTime, item.Seq, item.Source, item.Verb, item.SourceTime)
+		}
+	}
+
+	if got := drainIDs(s); !slices.Equal(got, wantOrder) {
+		t.Errorf("drained %v after restart, want %v", got, wantOrder)
+	}
+	report := s.stats.report(0)
+	if report.Total.QueuedDay != len(wantOrder) || report.Total.DeliveredDay != len(wantOrder) {
+		t.Errorf("stats after restart: queued %d, delivered %d, want %d each", report.Total.QueuedDay, report.Total.DeliveredDay, len(wantOrder))
+	}
+
+	// Delivered items are gone from queue.db too
+	if s = restartedServer(t, s.store, dir); len(s.queue) != 0 {
+		t.Errorf("%d items restored after draining, want 0", len(s.queue))
+	}
+}
+
+func TestQueueStoreRestartKeepsSeqOrder(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	before := newTestServer(store)
+	before.queueChanges(nil, testItems())
+
+	s := restartedServer(t, store, dir)
+	created := time.Now().Format(time.RFC3339)
+	s.queueChanges(nil, []QueueItem{{ID: "g3", Content: "f", CreatedAt: created, Source: "github", ExternalID: "4"}})
+	if s.queue["g3"].Seq <= before.seq {
+		t.Errorf("new item got seq %d, want after the restored %d", s.queue["g3"].Seq, before.seq)
+	}
+	if got := drainIDs(s); !slices.Equal(got, []string{"p1", "c1", "g1", "g2", "g3", "r1"}) {
+		t.Errorf("drained %v", got)
+	}
+}
+
+func TestQueueStoreRestartDedup(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	before := newTestServer(store)
+	before.queueChanges(nil, testItems())
+
+	s := restartedServer(t, store, dir)
+	s.dedup = parseDedupKeys("{source}:{external_id}")
+	created := time.Now().Format(time.RFC3339)
+	s.queueChanges(nil, []QueueItem{
+		{ID: "g1b", Content: "b2", CreatedAt: created, Source: "github", ExternalID: "1"},
+		{ID: "c2", Content: "c2", CreatedAt: created, Source: "calendar", ExternalID: "1"},
+	})
+
+	if _, ok := s.queue["g1"]; ok {
+		t.Error("restored github item 1 wasn't replaced by its new version")
+	}
+	if _, ok := s.queue["c2"]; !ok {
+		t.Error("calendar item 1 was merged with github item 1")
+	}
+	if len(s.queue) != 6 {
+		t.Errorf("%d items queued, want 6", len(s.queue))
+	}
+
+	// The replacement is persisted too
+	s = restartedServer(t, s.store, dir)
+	if _, ok := s.queue["g1"]; ok {
+		t.Error("replaced item came back after a restart")
+	}
+	if _, ok := s.queue["g1b"]; !ok {
+		t.Error("replacement wasn't persisted")
+	}
+}
+
+func TestQueueStoreRestartReplacesSnoozed(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir)
+	before := newTestServer(store)
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+	before.queueChanges(nil, []QueueItem{
+		{ID: "c1", Content: "old time", Source: "calendar", ExternalID: "ev-1", DeliverAfter: later},
+		{ID: "g1", Content: "same id", Source: "github", ExternalID: "ev-1", DeliverAfter: later},
+	})
+
+	s := restartedServer(t, store, dir)
+	s.queueChanges(nil, []QueueItem{{ID: "c2", Content: "new time", Source: "calendar", ExternalID: "ev-1", DeliverAfter: later}})
+	if _, ok := s.queue["c1"]; ok {
+		t.Error("snoozed calendar event wasn't replaced by its new version")
+	}
+	if _, ok := s.queue["g1"]; !ok {
+		t.Error("snoozed item of another source was replaced")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// heldPrefix marks queue items held back during quiet hours in a syncer's meta bucket
+const heldPrefix = "held_"
+
+// QuietHours is a daily local-time window during which non-urgent items are held
+type QuietHours struct {
+	Start int // minutes since midnight
+	End   int // minutes since midnight
+}
+
+// parseQuietHours parses "22:00-07:00" into a QuietHours window
+func parseQuietHours(s string) (*QuietHours, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid quiet_hours %q (want HH:MM-HH:MM)", s)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid quiet_hours %q (start equals end)", s)
+	}
+
+	return &QuietHours{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	hm := strings.Split(strings.TrimSpace(s), ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	h, err := strconv.Atoi(hm[0])
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	m, err := strconv.Atoi(hm[1])
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// Active reports whether t (in displayLocation) falls inside the window.
+// Windows that cross midnight (22:00-07:00) are handled.
+func (q *QuietHours) Active(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	t = t.In(displayLocation)
+	now := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return now >= q.Start && now < q.End
+	}
+	return now >= q.Start || now < q.End
+}
+
+// holdItem stores a queue item in the given meta bucket until quiet hours end.
+// It's wrapped like a queue.db entry, so the flushed item keeps its source
+// and verb.
+func holdItem(db *bolt.DB, bucket string, item QueueItem) error {
+	data, err := json.Marshal(newPersistedItem(item))
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		return b.Put([]byte(heldPrefix+item.ID), data)
+	})
+}
+
+// takeHeld removes and returns all held items from the given meta bucket
+func takeHeld(db *bolt.DB, bucket string) ([]QueueItem, error) {
+	var items []QueueItem
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		c := b.Cursor()
+
+		var keys [][]byte
+		prefix := []byte(heldPrefix)
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), heldPrefix); k, v = c.Next() {
+			var stored persistedItem
+			err := json.Unmarshal(v, &stored)
+			if err == nil && stored.Item.ID == "" {
+				err = json.Unmarshal(v, &stored.Item) // held by an older tm: a bare QueueItem
+			}
+			if err == nil {
+				items = append(items, stored.queueItem())
+			}
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return items, err
+}
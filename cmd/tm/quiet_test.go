@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestHeldItemsKeepSourceAndVerb(t *testing.T) {
+	db, err := openBolt(filepath.Join(t.TempDir(), "held.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	held := QueueItem{ID: "a", Content: "x", Source: "github", Verb: "closed", SourceTime: when}
+	if err := holdItem(db, metaBucket, held); err != nil {
+		t.Fatal(err)
+	}
+	// An item held before they were wrapped still comes back
+	legacy, _ := json.Marshal(QueueItem{ID: "b", Content: "y"})
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte(heldPrefix+"b"), legacy)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := takeHeld(db, metaBucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("took %d held items, want 2", len(items))
+	}
+	got := items[0]
+	if got.ID != "a" || got.Source != "github" || got.Verb != "closed" || !got.SourceTime.Equal(when) {
+		t.Errorf("held item came back as id=%s source=%q verb=%q source_time=%v", got.ID, got.Source, got.Verb, got.SourceTime)
+	}
+	if items[1].ID != "b" || items[1].Content != "y" {
+		t.Errorf("legacy held item came back as %+v", items[1])
+	}
+
+	if items, _ := takeHeld(db, metaBucket); len(items) != 0 {
+		t.Errorf("%d items still held after takeHeld", len(items))
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -31,6 +32,27 @@ type ReadwiseDocument struct {
 	Note            string    `json:"note"`      // User's note on highlight
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+
+	Tags readwiseTags `json:"tags"` // Reader tags, for require_sync_label
+}
+
+// readwiseTags is Reader's tags field: an object keyed by tag name, which
+// comes back as [] or null for untagged documents
+type readwiseTags []string
+
+func (t *readwiseTags) UnmarshalJSON(data []byte) error {
+	var byName map[string]json.RawMessage
+	if err := json.Unmarshal(data, &byName); err != nil {
+		// A list: untagged ([] or null from the API), or our own stored backfill pages
+		var names []string
+		json.Unmarshal(data, &names)
+		*t = names
+		return nil
+	}
+	for name := range byName {
+		*t = append(*t, name)
+	}
+	return nil
 }
 
 // ReadwiseAPIResponse represents the paginated API response
@@ -45,12 +67,24 @@ type ReadwiseSyncer struct {
 	token   string
 	db      *bolt.DB
 	client  *http.Client
+
+	maxHighlightsPerItem int  // split documents with more highlights (0 = never)
+	nested               bool   // readwise_mode=nested: book record + one child record per highlight
+	collection           string // readwise_collection override ("" = Readwise)
+	footer               string // link-back footer template ("" = off)
+	htmlToMarkdown       bool   // html_to_markdown: convert HTML in summaries and highlights
+	highlightStyle       string // readwise_highlight_style: quote or numbered ("" = quote)
+	highlightSeparator   string // readwise_highlight_separator: blank or rule ("" = blank)
+	syncLabel            string // require_sync_label: only documents with this tag ("" = all)
+	maxBody              int    // max_body_chars: truncate long summaries (0 = never)
+
+	backfillState // first sync of a library, for /status and tm status
 }
 
 // NewReadwiseSyncer creates a new Readwise syncer
 func NewReadwiseSyncer(token string, dataDir string) (*ReadwiseSyncer, error) {
 	dbPath := dataDir + "/readwise.db"
-	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	db, err := openBolt(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("open readwise db: %w", err)
 	}
@@ -61,6 +95,10 @@ func NewReadwiseSyncer(token string, dataDir string) (*ReadwiseSyncer, error) {
 		if err != nil {
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists([]byte(readwiseBackfillBucket))
+		if err != nil {
+			return err
+		}
 		_, err = tx.CreateBucketIfNotExists([]byte("sync_meta"))
 		return err
 	})
@@ -81,8 +119,236 @@ func (s *ReadwiseSyncer) Close() error {
 	return s.db.Close()
 }
 
-// Sync fetches documents and highlights, returns documents with new highlights
-func (s *ReadwiseSyncer) Sync() ([]HighlightedDocument, error) {
+// Name implements Syncer
+func (s *ReadwiseSyncer) Name() string {
+	return "readwise"
+}
+
+// Verify implements verifier: lists a single document
+func (s *ReadwiseSyncer) Verify(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", readwiseBaseURL+"?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+s.token)
+
+	// Still inside a rate-limit window from before a restart: don't spend a request on it
+	if until := s.rateLimitedUntil(); time.Now().Before(until) {
+		logger.Info("Readwise verify skipped: rate limited", "until", until.Format(time.RFC3339))
+		return nil
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusTooManyRequests:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: readwise API returned %d", errInvalidToken, resp.StatusCode)
+	}
+	return fmt.Errorf("readwise API returned %d", resp.StatusCode)
+}
+
+// metaStore implements heldStore
+func (s *ReadwiseSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, "sync_meta"
+}
+
+// CachedCount implements Syncer: the number of cached documents
+func (s *ReadwiseSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, "documents")
+}
+
+// Prune implements pruner: forgets documents whose highlights haven't changed
+// since cutoff. Syncs are incremental, so they only return if touched again.
+func (s *ReadwiseSyncer) Prune(cutoff time.Time) (int, error) {
+	return pruneBucket(s.db, "documents", func(v []byte) bool {
+		var stored storedDoc
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return false
+		}
+		return stored.UpdatedAt.Before(cutoff)
+	})
+}
+
+// ClearCache clears all cached documents and the last-sync watermark
+func (s *ReadwiseSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("documents"))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		if meta := tx.Bucket([]byte("sync_meta")); meta != nil {
+			meta.Delete([]byte("last_sync"))
+		}
+		// A backfill in progress starts over too
+		return s.finishBackfill(tx)
+	})
+}
+
+// Sync implements Syncer: fetches new highlights and renders them as queue items
+func (s *ReadwiseSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	docs, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		logger.Debug("Readwise sync complete", "changes", 0)
+		return nil, nil
+	}
+
+	items := make([]QueueItem, 0, len(docs))
+	for _, doc := range docs {
+		doc.Collection = s.collection
+		doc.Footer = s.footer
+		doc.Style = s.highlightStyle
+		doc.Separator = s.highlightSeparator
+		doc.MaxBody = s.maxBody
+		if s.htmlToMarkdown {
+			doc = doc.withMarkdown()
+		}
+		verb := "updated"
+		if doc.IsNew {
+			verb = "highlighted"
+		}
+		if s.nested {
+			items = append(items, s.nestedItems(doc, verb)...)
+			logger.Debug("Readwise document changed", "title", doc.Document.Title, "verb", verb, "new_highlights", len(doc.NewHighlights))
+			continue
+		}
+
+		parts := s.partItems(doc, verb)
+		items = append(items, parts...)
+		logger.Debug("Readwise document changed", "title", doc.Document.Title, "verb", verb, "highlights", len(doc.Highlights), "parts", len(parts))
+	}
+	logger.Info("Readwise sync complete", "documents", len(docs))
+	return items, nil
+}
+
+// partItems renders the document as one item, or one per part past
+// readwise_max_highlights_per_item. Parts share the external_id and are
+// numbered in Part, so dedup_key never merges them.
+func (s *ReadwiseSyncer) partItems(doc HighlightedDocument, verb string) []QueueItem {
+	parts := doc.ToMarkdownParts(s.maxHighlightsPerItem)
+	items := make([]QueueItem, 0, len(parts))
+	for i, content := range parts {
+		item := QueueItem{
+			ID:         fmt.Sprintf("rw-%d-%03d", time.Now().UnixNano(), i),
+			Action:     "append",
+			Title:      doc.Document.Title,
+			Content:    content,
+			CreatedAt:  time.Now().Format(time.RFC3339),
+			Priority:   priorityLow,
+			Source:     "readwise",
+			ExternalID: "readwise_" + doc.Document.ID,
+			SourceTime: doc.Document.UpdatedAt,
+			Verb:       verb,
+		}
+		if len(parts) > 1 {
+			item.Part = i + 1
+		}
+		if i > 0 {
+			item.Verb = "" // continuation parts don't get their own journal entry
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// nestedItems renders the book record followed by a child record per new highlight
+func (s *ReadwiseSyncer) nestedItems(doc HighlightedDocument, verb string) []QueueItem {
+	book := doc
+	book.Highlights = nil
+
+	items := []QueueItem{{
+		ID:         fmt.Sprintf("rw-%d-%03d", time.Now().UnixNano(), 0),
+		Action:     "append",
+		Title:      doc.Document.Title,
+		Content:    book.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Priority:   priorityLow,
+		Source:     "readwise",
+		ExternalID: "readwise_" + doc.Document.ID,
+		SourceTime: doc.Document.UpdatedAt,
+		Verb:       verb,
+	}}
+
+	for i, h := range doc.NewHighlights {
+		items = append(items, QueueItem{
+			ID:         fmt.Sprintf("rw-%d-%03d", time.Now().UnixNano(), i+1),
+			Action:     "append",
+			Title:      highlightTitle(h),
+			Content:    h.HighlightMarkdown(doc.Document.ID, doc.collection()),
+			CreatedAt:  time.Now().Format(time.RFC3339),
+			Priority:   priorityLow,
+			Source:     "readwise",
+			ExternalID: "readwise_" + h.ID,
+			SourceTime: h.UpdatedAt,
+		})
+	}
+	return items
+}
+
+// HighlightMarkdown renders a single highlight as a child of its document
+func (h ReadwiseDocument) HighlightMarkdown(parentID, collection string) string {
+	var b strings.Builder
+
+	// Frontmatter
+	b.WriteString("---\n")
+	b.WriteString(fmt.Sprintf("collection: %s\n", collection))
+	b.WriteString(fmt.Sprintf("external_id: readwise_%s\n", h.ID))
+	b.WriteString(fmt.Sprintf("parent_external_id: readwise_%s\n", parentID))
+	b.WriteString(fmt.Sprintf("title: %s\n", highlightTitle(h)))
+	b.WriteString("category: highlight\n")
+	if h.URL != "" {
+		b.WriteString(fmt.Sprintf("url: %s\n", h.URL))
+	}
+	b.WriteString("---\n\n")
+
+	// Blockquote the highlight
+	b.WriteString("> ")
+	b.WriteString(strings.ReplaceAll(h.Content, "\n", "\n> "))
+	b.WriteString("\n")
+
+	// Add note if present
+	if h.Note != "" {
+		b.WriteString("\n**Note:** ")
+		b.WriteString(h.Note)
+		b.WriteString("\n")
+	}
+
+	return filterFrontmatter(b.String())
+}
+
+// highlightTitle is the first ~60 characters of the highlight text
+func highlightTitle(h ReadwiseDocument) string {
+	title := cleanTitle(h.Content)
+	if r := []rune(title); len(r) > 60 {
+		title = strings.TrimSpace(string(r[:60])) + "…"
+	}
+	return title
+}
+
+// SyncChanges fetches documents and highlights, returns documents with new highlights
+func (s *ReadwiseSyncer) SyncChanges(ctx context.Context) ([]HighlightedDocument, error) {
 	// Get last sync time
 	var lastSync time.Time
 	s.db.View(func(tx *bolt.Tx) error {
@@ -93,8 +359,15 @@ func (s *ReadwiseSyncer) Sync() ([]HighlightedDocument, error) {
 		return nil
 	})
 
-	// Fetch all documents and highlights
-	docs, highlights, err := s.fetchAll(lastSync)
+	// Fetch all documents and highlights; the first sync of a library backfills resumably
+	watermark := time.Now()
+	var docs, highlights []ReadwiseDocument
+	var err error
+	if lastSync.IsZero() {
+		docs, highlights, watermark, err = s.fetchBackfill(ctx)
+	} else {
+		docs, highlights, err = s.fetchAll(ctx, lastSync)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -114,15 +387,19 @@ func (s *ReadwiseSyncer) Sync() ([]HighlightedDocument, error) {
 		if !hasHighlights {
 			continue
 		}
+		if s.syncLabel != "" && !hasLabel(doc.Tags, s.syncLabel) {
+			continue
+		}
 
 		// Check if this is new or has new highlights
-		isNew, hasNewHighlights := s.checkIfNew(doc.ID, docHighlights)
+		isNew, newHighlights := s.checkIfNew(doc.ID, docHighlights)
 
-		if isNew || hasNewHighlights {
+		if isNew || len(newHighlights) > 0 {
 			results = append(results, HighlightedDocument{
-				Document:   doc,
-				Highlights: docHighlights,
-				IsNew:      isNew,
+				Document:      doc,
+				Highlights:    docHighlights,
+				NewHighlights: newHighlights,
+				IsNew:         isNew,
 			})
 		}
 
@@ -133,7 +410,12 @@ func (s *ReadwiseSyncer) Sync() ([]HighlightedDocument, error) {
 	// Update last sync time
 	s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("sync_meta"))
-		return b.Put([]byte("last_sync"), []byte(time.Now().Format(time.RFC3339)))
+		if lastSync.IsZero() {
+			if err := s.finishBackfill(tx); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte("last_sync"), []byte(watermark.Format(time.RFC3339)))
 	})
 
 	return results, nil
@@ -141,20 +423,78 @@ func (s *ReadwiseSyncer) Sync() ([]HighlightedDocument, error) {
 
 // HighlightedDocument is a document with its highlights
 type HighlightedDocument struct {
-	Document   ReadwiseDocument
-	Highlights []ReadwiseDocument
-	IsNew      bool // First time seeing this document
+	Document      ReadwiseDocument
+	Highlights    []ReadwiseDocument
+	NewHighlights []ReadwiseDocument // Highlights not seen in a previous sync
+	IsNew         bool               // First time seeing this document
+	Collection    string             // Target collection from readwise_collection ("" = Readwise)
+	Footer        string             // Link-back footer template ("" = off)
+	Style         string             // readwise_highlight_style: quote (default) or numbered
+	Separator     string             // readwise_highlight_separator: blank (default) or rule
+	MaxBody       int                // max_body_chars: truncate the summary past this (0 = never)
+}
+
+// withMarkdown returns a copy with HTML in the summary and highlights converted to markdown
+func (hd HighlightedDocument) withMarkdown() HighlightedDocument {
+	convert := func(docs []ReadwiseDocument) []ReadwiseDocument {
+		out := make([]ReadwiseDocument, len(docs))
+		for i, d := range docs {
+			d.Content = htmlToMarkdown(d.Content)
+			d.Note = htmlToMarkdown(d.Note)
+			out[i] = d
+		}
+		return out
+	}
+
+	hd.Document.Summary = htmlToMarkdown(hd.Document.Summary)
+	hd.Highlights = convert(hd.Highlights)
+	hd.NewHighlights = convert(hd.NewHighlights)
+	return hd
+}
+
+// collection returns the target collection, defaulting to Readwise
+func (hd *HighlightedDocument) collection() string {
+	if hd.Collection == "" {
+		return "Readwise"
+	}
+	return hd.Collection
 }
 
 // ToMarkdown converts to frontmatter + markdown body
 func (hd *HighlightedDocument) ToMarkdown() string {
+	return hd.markdownPart(hd.Highlights, 0, 1, 1)
+}
+
+// ToMarkdownParts splits the document into parts of at most max highlights,
+// each sharing the external_id and tagged "part: n/total". A single part
+// (identical to ToMarkdown) is returned when max <= 0 or isn't exceeded.
+func (hd *HighlightedDocument) ToMarkdownParts(max int) []string {
+	if max <= 0 || len(hd.Highlights) <= max {
+		return []string{hd.ToMarkdown()}
+	}
+
+	total := (len(hd.Highlights) + max - 1) / max
+	parts := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * max
+		if end > len(hd.Highlights) {
+			end = len(hd.Highlights)
+		}
+		parts = append(parts, hd.markdownPart(hd.Highlights[i*max:end], i*max, i+1, total))
+	}
+	return parts
+}
+
+// markdownPart renders one part; only part 1 carries the verb, summary, and heading.
+// first is the index of highlights[0] in the whole document, for numbering.
+func (hd *HighlightedDocument) markdownPart(highlights []ReadwiseDocument, first, part, total int) string {
 	var b strings.Builder
 
 	// Frontmatter
 	b.WriteString("---\n")
-	b.WriteString("collection: Readwise\n")
+	b.WriteString(fmt.Sprintf("collection: %s\n", hd.collection()))
 	b.WriteString(fmt.Sprintf("external_id: readwise_%s\n", hd.Document.ID))
-	if hd.IsNew {
+	if hd.IsNew && part == 1 {
 		b.WriteString("verb: highlighted\n")
 	}
 	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(hd.Document.Title)))
@@ -168,19 +508,46 @@ func (hd *HighlightedDocument) ToMarkdown() string {
 	if hd.Document.URL != "" {
 		b.WriteString(fmt.Sprintf("url: %s\n", hd.Document.URL))
 	}
+	if total > 1 {
+		b.WriteString(fmt.Sprintf("part: %d/%d\n", part, total))
+	}
 	b.WriteString("---\n\n")
 
 	// Summary section
-	if hd.Document.Summary != "" {
+	if hd.Document.Summary != "" && part == 1 {
 		b.WriteString("## Summary\n\n")
-		b.WriteString(hd.Document.Summary)
+		url := hd.Document.URL
+		if url == "" {
+			url = hd.Document.SourceURL
+		}
+		b.WriteString(truncateBody(hd.Document.Summary, hd.MaxBody, url))
 		b.WriteString("\n\n")
 	}
 
 	// Highlights section
-	if len(hd.Highlights) > 0 {
-		b.WriteString("## Highlights\n\n")
-		for _, h := range hd.Highlights {
+	if len(highlights) > 0 {
+		if part == 1 {
+			b.WriteString("## Highlights\n\n")
+		}
+		for i, h := range highlights {
+			if i > 0 && hd.Separator == "rule" {
+				b.WriteString("---\n\n")
+			}
+
+			if hd.Style == "numbered" {
+				// Numbered list item; continuation lines and the note stay inside it
+				fmt.Fprintf(&b, "%d. ", first+i+1)
+				b.WriteString(strings.ReplaceAll(h.Content, "\n", "\n   "))
+				b.WriteString("\n")
+				if h.Note != "" {
+					b.WriteString("\n   **Note:** ")
+					b.WriteString(strings.ReplaceAll(h.Note, "\n", "\n   "))
+					b.WriteString("\n")
+				}
+				b.WriteString("\n")
+				continue
+			}
+
 			// Blockquote the highlight
 			b.WriteString("> ")
 			b.WriteString(strings.ReplaceAll(h.Content, "\n", "\n> "))
@@ -196,13 +563,43 @@ func (hd *HighlightedDocument) ToMarkdown() string {
 		}
 	}
 
-	return b.String()
+	writeFooter(&b, hd.Footer, "Open in Reader", hd.Document.URL)
+
+	return filterFrontmatter(b.String())
+}
+
+func (s *ReadwiseSyncer) fetchAll(ctx context.Context, since time.Time) (docs []ReadwiseDocument, highlights []ReadwiseDocument, err error) {
+	err = s.fetchPages(ctx, since, "", func(page ReadwiseAPIResponse) error {
+		docs, highlights = splitHighlights(page.Results, docs, highlights)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return docs, highlights, nil
 }
 
-func (s *ReadwiseSyncer) fetchAll(since time.Time) (docs []ReadwiseDocument, highlights []ReadwiseDocument, err error) {
-	var pageCursor string
+// splitHighlights appends items to docs or highlights by whether they have a parent
+func splitHighlights(items, docs, highlights []ReadwiseDocument) ([]ReadwiseDocument, []ReadwiseDocument) {
+	for _, item := range items {
+		if item.ParentID != nil {
+			highlights = append(highlights, item)
+		} else {
+			docs = append(docs, item)
+		}
+	}
+	return docs, highlights
+}
 
+// fetchPages lists everything updated after since (zero = everything),
+// starting at pageCursor ("" = the first page), and hands each page to fn
+func (s *ReadwiseSyncer) fetchPages(ctx context.Context, since time.Time, pageCursor string, fn func(page ReadwiseAPIResponse) error) error {
 	for {
+		// Respect a Retry-After window, including one left over from before a restart
+		if err := s.waitRateLimit(ctx); err != nil {
+			return err
+		}
+
 		// Build request URL
 		reqUrl := readwiseBaseURL + "?"
 		if !since.IsZero() {
@@ -212,20 +609,20 @@ func (s *ReadwiseSyncer) fetchAll(since time.Time) (docs []ReadwiseDocument, hig
 			reqUrl += "pageCursor=" + pageCursor
 		}
 
-		req, err := http.NewRequest("GET", reqUrl, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", reqUrl, nil)
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 		req.Header.Set("Authorization", "Token "+s.token)
 
 		resp, err := s.client.Do(req)
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode == 429 {
-			// Rate limited - wait and retry
+			// Rate limited - remember until when, so a restart doesn't retry early; the loop waits
 			retryAfter := resp.Header.Get("Retry-After")
 			wait := 60 * time.Second
 			if retryAfter != "" {
@@ -234,39 +631,72 @@ func (s *ReadwiseSyncer) fetchAll(since time.Time) (docs []ReadwiseDocument, hig
 				}
 			}
 			logger.Warn("Readwise rate limited", "wait", wait)
-			time.Sleep(wait)
+			if err := s.setRateLimitedUntil(time.Now().Add(wait)); err != nil {
+				logger.Warn("couldn't store Readwise rate limit", "error", err)
+			}
 			continue
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			return nil, nil, fmt.Errorf("readwise API returned %d: %s", resp.StatusCode, string(body))
+			return fmt.Errorf("readwise API returned %d: %s", resp.StatusCode, string(body))
 		}
 
 		var apiResp ReadwiseAPIResponse
 		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-			return nil, nil, err
+			return err
 		}
 
-		// Separate documents from highlights
-		for _, item := range apiResp.Results {
-			if item.ParentID != nil {
-				highlights = append(highlights, item)
-			} else {
-				docs = append(docs, item)
-			}
+		if err := fn(apiResp); err != nil {
+			return err
 		}
 
 		if apiResp.NextPageCursor == "" {
-			break
+			return nil
 		}
 		pageCursor = apiResp.NextPageCursor
 	}
+}
 
-	return docs, highlights, nil
+// rateLimitKey holds the "don't call before" time from Readwise's last 429 in sync_meta
+const rateLimitKey = "rate_limited_until"
+
+// rateLimitedUntil returns when the last rate-limit window ends (zero if none)
+func (s *ReadwiseSyncer) rateLimitedUntil() time.Time {
+	var until time.Time
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte("sync_meta")).Get([]byte(rateLimitKey)); v != nil {
+			until, _ = time.Parse(time.RFC3339, string(v))
+		}
+		return nil
+	})
+	return until
 }
 
-func (s *ReadwiseSyncer) checkIfNew(docID string, highlights []ReadwiseDocument) (isNew bool, hasNewHighlights bool) {
+// setRateLimitedUntil persists the end of a rate-limit window
+func (s *ReadwiseSyncer) setRateLimitedUntil(until time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("sync_meta")).Put([]byte(rateLimitKey), []byte(until.Format(time.RFC3339)))
+	})
+}
+
+// waitRateLimit sleeps until the stored rate-limit window is over
+func (s *ReadwiseSyncer) waitRateLimit(ctx context.Context) error {
+	wait := time.Until(s.rateLimitedUntil())
+	if wait <= 0 {
+		return nil
+	}
+	logger.Info("waiting out Readwise rate limit", "wait", wait.Round(time.Second))
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkIfNew reports whether the document is unseen and which highlights are new
+func (s *ReadwiseSyncer) checkIfNew(docID string, highlights []ReadwiseDocument) (isNew bool, newHighlights []ReadwiseDocument) {
 	var stored storedDoc
 
 	err := s.db.View(func(tx *bolt.Tx) error {
@@ -279,19 +709,21 @@ func (s *ReadwiseSyncer) checkIfNew(docID string, highlights []ReadwiseDocument)
 		return json.Unmarshal(v, &stored)
 	})
 
-	if err != nil || isNew {
-		return isNew, false
+	if err != nil {
+		return isNew, nil
+	}
+	if isNew {
+		return true, highlights
 	}
 
-	// Check if we have new highlights
+	// Collect highlights we haven't stored yet
 	for _, h := range highlights {
 		if !stored.HighlightIDs[h.ID] {
-			hasNewHighlights = true
-			break
+			newHighlights = append(newHighlights, h)
 		}
 	}
 
-	return false, hasNewHighlights
+	return false, newHighlights
 }
 
 type storedDoc struct {
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// readwiseBackfillBucket holds what the first sync has fetched so far, by item ID
+	readwiseBackfillBucket = "backfill"
+
+	// readwiseBackfillKey is the first sync's cursor in sync_meta
+	readwiseBackfillKey = "backfill"
+)
+
+// readwiseBackfill is the cursor of a first sync in progress. Highlights are
+// grouped under their document only once everything is fetched, so pages are
+// kept in readwiseBackfillBucket until then.
+type readwiseBackfill struct {
+	PageCursor string    `json:"page_cursor"` // next page ("" = first)
+	StartedAt  time.Time `json:"started_at"`  // becomes last_sync, so changes made during the backfill are picked up next
+	Done       int       `json:"done"`        // documents and highlights fetched
+	Total      int       `json:"total"`       // Readwise's count when the backfill started
+}
+
+func (b readwiseBackfill) progress() backfillProgress {
+	return backfillProgress{Done: b.Done, Total: b.Total, Unit: "items"}
+}
+
+// fetchBackfill is fetchAll for the first sync of a library: every page is
+// stored together with the cursor after it, so a restart, a timeout or a
+// rate-limit wait that outlasts the sync resumes from the last page instead
+// of from scratch. It returns everything once the last page is in, with the
+// time to record as last_sync.
+func (s *ReadwiseSyncer) fetchBackfill(ctx context.Context) (docs, highlights []ReadwiseDocument, startedAt time.Time, err error) {
+	cursor := readwiseBackfill{StartedAt: time.Now()}
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte("sync_meta")).Get([]byte(readwiseBackfillKey)); v != nil {
+			json.Unmarshal(v, &cursor)
+		}
+		return nil
+	})
+	if cursor.Done > 0 {
+		logger.Info("Readwise backfill resuming", "done", cursor.Done, "total", cursor.Total)
+	}
+	s.setBackfill("", cursor.progress())
+
+	err = s.fetchPages(ctx, time.Time{}, cursor.PageCursor, func(page ReadwiseAPIResponse) error {
+		if cursor.Total == 0 {
+			cursor.Total = page.Count
+		}
+		cursor.PageCursor = page.NextPageCursor
+		cursor.Done += len(page.Results)
+
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(readwiseBackfillBucket))
+			for _, item := range page.Results {
+				data, err := json.Marshal(item)
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte(item.ID), data); err != nil {
+					return err
+				}
+			}
+			data, err := json.Marshal(cursor)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket([]byte("sync_meta")).Put([]byte(readwiseBackfillKey), data)
+		})
+		if err != nil {
+			return err
+		}
+		s.setBackfill("", cursor.progress())
+		logger.Debug("Readwise backfill page", "done", cursor.Done, "total", cursor.Total)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(readwiseBackfillBucket)).ForEach(func(k, v []byte) error {
+			var item ReadwiseDocument
+			if err := json.Unmarshal(v, &item); err != nil {
+				return nil
+			}
+			docs, highlights = splitHighlights([]ReadwiseDocument{item}, docs, highlights)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	logger.Info("Readwise backfill fetched", "documents", len(docs), "highlights", len(highlights))
+	return docs, highlights, cursor.StartedAt, nil
+}
+
+// finishBackfill drops the fetched pages and the cursor once SyncChanges has
+// stored every document's state. Call inside the Update that sets last_sync.
+func (s *ReadwiseSyncer) finishBackfill(tx *bolt.Tx) error {
+	s.forgetBackfill("")
+	if err := tx.DeleteBucket([]byte(readwiseBackfillBucket)); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+	if _, err := tx.CreateBucket([]byte(readwiseBackfillBucket)); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte("sync_meta")).Delete([]byte(readwiseBackfillKey))
+}
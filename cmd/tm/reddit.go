@@ -0,0 +1,569 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	redditBucket     = "reddit_saved"
+	redditMetaBucket = "reddit_meta"
+	redditAPIBase    = "https://oauth.reddit.com"
+	redditPageSize   = 100
+	redditMaxPages   = 10 // Reddit listings stop at ~1000 items anyway
+	redditUserAgent  = "tm:thymer-inbox:v1 (by /u/thymer-inbox)"
+)
+
+// RedditTokens holds OAuth tokens for the Reddit API
+type RedditTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	Username     string    `json:"username,omitempty"`
+}
+
+// RedditItem represents a stored saved post or comment
+type RedditItem struct {
+	ID        string    `json:"id"`       // reddit_{fullname}
+	Fullname  string    `json:"fullname"` // t3_abc (post) or t1_xyz (comment)
+	Kind      string    `json:"kind"`     // post, comment
+	Title     string    `json:"title"`    // post title, or the parent post's title for comments
+	Subreddit string    `json:"subreddit"`
+	Author    string    `json:"author"`
+	Permalink string    `json:"permalink"`
+	LinkURL   string    `json:"link_url,omitempty"` // external URL for link posts
+	Body      string    `json:"body,omitempty"`     // self-post text or comment body
+	CreatedAt time.Time `json:"created_at"`
+	Verb      string    `json:"-"` // transient: saved (not stored)
+}
+
+// ToMarkdown returns the item as markdown with YAML frontmatter
+func (i RedditItem) ToMarkdown() string {
+	var b strings.Builder
+
+	// YAML frontmatter
+	b.WriteString("---\n")
+	b.WriteString("collection: Reddit\n")
+	b.WriteString(fmt.Sprintf("external_id: %s\n", i.ID))
+	if i.Verb != "" {
+		b.WriteString(fmt.Sprintf("verb: %s\n", i.Verb))
+	}
+	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(i.Title)))
+	b.WriteString(fmt.Sprintf("type: %s\n", i.Kind))
+	b.WriteString(fmt.Sprintf("subreddit: r/%s\n", i.Subreddit))
+	b.WriteString(fmt.Sprintf("author: u/%s\n", i.Author))
+	b.WriteString(fmt.Sprintf("url: %s\n", i.Permalink))
+	if i.LinkURL != "" {
+		b.WriteString(fmt.Sprintf("source_url: %s\n", i.LinkURL))
+	}
+	b.WriteString(fmt.Sprintf("created: %s\n", i.CreatedAt.Format(time.RFC3339)))
+	b.WriteString("---\n\n")
+
+	// Body
+	if i.Kind == "comment" {
+		b.WriteString(fmt.Sprintf("Comment by u/%s on [%s](%s):\n\n", i.Author, i.Title, i.Permalink))
+		b.WriteString("> ")
+		b.WriteString(strings.ReplaceAll(i.Body, "\n", "\n> "))
+		b.WriteString("\n")
+	} else if i.Body != "" {
+		b.WriteString(i.Body)
+	}
+
+	return filterFrontmatter(b.String())
+}
+
+// RedditSyncer handles syncing a user's saved Reddit posts and comments
+type RedditSyncer struct {
+	client   *http.Client
+	db       *bolt.DB
+	username string
+
+	unchangedCount // for /status
+}
+
+// NewRedditSyncer creates a new syncer
+func NewRedditSyncer(tokens *RedditTokens, dataDir string) (*RedditSyncer, error) {
+	if tokens.Username == "" {
+		return nil, fmt.Errorf("reddit username unknown - run 'tm auth reddit'")
+	}
+
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "reddit.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(redditBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(redditMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+		Expiry:       tokens.Expiry,
+	}
+
+	ts := newSavingTokenSource(getRedditOAuthConfig().TokenSource(redditContext(), token), tokens.AccessToken, func(t *oauth2.Token) error {
+		return saveRedditTokens(RedditTokens{
+			AccessToken:  t.AccessToken,
+			RefreshToken: t.RefreshToken,
+			TokenType:    t.TokenType,
+			Expiry:       t.Expiry,
+			Username:     tokens.Username,
+		})
+	})
+
+	client := oauth2.NewClient(context.Background(), ts)
+	client.Timeout = 30 * time.Second
+
+	return &RedditSyncer{
+		client:   client,
+		db:       db,
+		username: tokens.Username,
+	}, nil
+}
+
+// Close closes the database
+func (s *RedditSyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of cached saved items
+func (s *RedditSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, redditBucket)
+}
+
+// ClearCache clears all cached saved items
+func (s *RedditSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(redditBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RedditSyncResult contains sync statistics
+type RedditSyncResult struct {
+	Created   []RedditItem
+	Unchanged int
+	Errors    []error
+}
+
+// SyncChanges fetches saved items newest-first and returns the ones not seen before
+func (s *RedditSyncer) SyncChanges(ctx context.Context) (*RedditSyncResult, error) {
+	result := &RedditSyncResult{
+		Created: make([]RedditItem, 0),
+		Errors:  make([]error, 0),
+	}
+
+	var after string
+	for page := 0; page < redditMaxPages; page++ {
+		items, next, err := s.fetchSaved(ctx, after)
+		if err != nil {
+			return nil, err
+		}
+
+		created := 0
+		for _, item := range items {
+			isNew, err := s.insert(item)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				continue
+			}
+			if !isNew {
+				result.Unchanged++
+				continue
+			}
+			item.Verb = "saved"
+			result.Created = append(result.Created, item)
+			created++
+		}
+
+		// Saved listings are newest-first: a page with nothing new means we've caught up
+		if next == "" || created == 0 {
+			return result, nil
+		}
+		after = next
+	}
+
+	logger.Warn("Reddit sync hit page limit", "pages", redditMaxPages, "created", len(result.Created))
+	return result, nil
+}
+
+// redditListing is the subset of a Reddit Listing response we use
+type redditListing struct {
+	Data struct {
+		After    string `json:"after"`
+		Children []struct {
+			Kind string `json:"kind"` // t3 = link/post, t1 = comment
+			Data struct {
+				Name       string  `json:"name"`
+				Title      string  `json:"title"`
+				LinkTitle  string  `json:"link_title"`
+				Subreddit  string  `json:"subreddit"`
+				Author     string  `json:"author"`
+				Permalink  string  `json:"permalink"`
+				URL        string  `json:"url"`
+				IsSelf     bool    `json:"is_self"`
+				Selftext   string  `json:"selftext"`
+				Body       string  `json:"body"`
+				CreatedUTC float64 `json:"created_utc"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// fetchSaved returns one page of saved items and the cursor for the next page
+func (s *RedditSyncer) fetchSaved(ctx context.Context, after string) ([]RedditItem, string, error) {
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", redditPageSize))
+	params.Set("raw_json", "1")
+	if after != "" {
+		params.Set("after", after)
+	}
+
+	endpoint := fmt.Sprintf("%s/user/%s/saved?%s", redditAPIBase, url.PathEscape(s.username), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", redditUserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list saved items: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("reddit API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var listing redditListing
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, "", err
+	}
+
+	items := make([]RedditItem, 0, len(listing.Data.Children))
+	for _, child := range listing.Data.Children {
+		d := child.Data
+		item := RedditItem{
+			ID:        "reddit_" + d.Name,
+			Fullname:  d.Name,
+			Subreddit: d.Subreddit,
+			Author:    d.Author,
+			Permalink: "https://www.reddit.com" + d.Permalink,
+			CreatedAt: time.Unix(int64(d.CreatedUTC), 0).UTC(),
+		}
+
+		switch child.Kind {
+		case "t3":
+			item.Kind = "post"
+			item.Title = d.Title
+			if d.IsSelf {
+				item.Body = d.Selftext
+			} else {
+				item.LinkURL = d.URL
+			}
+		case "t1":
+			item.Kind = "comment"
+			item.Title = d.LinkTitle
+			item.Body = d.Body
+		default:
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, listing.Data.After, nil
+}
+
+// insert stores the item if unseen. Saved items don't change, so there's no update path.
+func (s *RedditSyncer) insert(item RedditItem) (bool, error) {
+	var isNew bool
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(redditBucket))
+		if b.Get([]byte(item.ID)) != nil {
+			return nil
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		isNew = true
+		return b.Put([]byte(item.ID), data)
+	})
+
+	return isNew, err
+}
+
+// Name implements Syncer
+func (s *RedditSyncer) Name() string {
+	return "reddit"
+}
+
+// metaStore implements heldStore
+func (s *RedditSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, redditMetaBucket
+}
+
+// Sync implements Syncer: fetches newly saved items and renders them as queue items
+func (s *RedditSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Reddit sync complete", "created", len(result.Created), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	s.unchanged = result.Unchanged
+
+	items := make([]QueueItem, 0, len(result.Created))
+	for _, saved := range result.Created {
+		items = append(items, saved.queueItem())
+	}
+	return items, nil
+}
+
+func (i RedditItem) queueItem() QueueItem {
+	return QueueItem{
+		ID:         fmt.Sprintf("reddit-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      i.Title,
+		Content:    i.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Source:     "reddit",
+		ExternalID: i.ID,
+		SourceTime: i.CreatedAt,
+		Verb:       i.Verb,
+	}
+}
+
+// Replay implements replayer: the cached post or comment as saved
+func (s *RedditSyncer) Replay(id string) (QueueItem, error) {
+	saved, err := loadCached[RedditItem](s.db, redditBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	saved.Verb = "saved"
+	return saved.queueItem(), nil
+}
+
+// ReplayIDs implements replayer
+func (s *RedditSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, redditBucket)
+}
+
+// redditContext makes the oauth2 package send Reddit's required User-Agent on token requests
+func redditContext() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: userAgentTransport{agent: redditUserAgent, base: http.DefaultTransport},
+	})
+}
+
+type userAgentTransport struct {
+	agent string
+	base  http.RoundTripper
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.agent)
+	return t.base.RoundTrip(req)
+}
+
+// getRedditOAuthConfig returns the OAuth2 config for the Reddit API
+func getRedditOAuthConfig() *oauth2.Config {
+	cfg := loadConfig()
+
+	return &oauth2.Config{
+		ClientID:     cfg.RedditClientID,
+		ClientSecret: cfg.RedditClientSecret, // empty for "installed app" clients
+		Scopes:       []string{"identity", "history"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://www.reddit.com/api/v1/authorize",
+			TokenURL:  "https://www.reddit.com/api/v1/access_token",
+			AuthStyle: oauth2.AuthStyleInHeader,
+		},
+		RedirectURL: OAuthCallbackURL,
+	}
+}
+
+// runRedditAuth runs the OAuth browser flow for Reddit
+func runRedditAuth() {
+	fmt.Println(em("🔐 Reddit Authentication"))
+	fmt.Println()
+
+	config := getRedditOAuthConfig()
+
+	// Check if client ID is configured
+	if config.ClientID == "" {
+		fmt.Println(em("⚠️  Reddit OAuth not configured!"))
+		fmt.Println()
+		fmt.Println("To set up Reddit sync:")
+		fmt.Println()
+		fmt.Println("1. Go to https://www.reddit.com/prefs/apps and create an app")
+		fmt.Printf("2. Set the redirect URI to: %s\n", OAuthCallbackURL)
+		fmt.Println("3. Add your client ID (and secret, for \"web app\" types) to ~/.config/tm/config:")
+		fmt.Println()
+		fmt.Println("   reddit_client_id=YOUR_CLIENT_ID")
+		fmt.Println("   reddit_client_secret=YOUR_CLIENT_SECRET")
+		fmt.Println()
+		fmt.Println("4. Run 'tm auth reddit' again")
+		os.Exit(1)
+	}
+
+	// Generate state for CSRF protection
+	state, err := generateState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating state: %v\n", err)
+		os.Exit(1)
+	}
+
+	// duration=permanent gets a refresh token
+	authURL := config.AuthCodeURL(state, oauth2.SetAuthURLParam("duration", "permanent"))
+
+	fmt.Println("Opening browser for Reddit sign-in...")
+	code, err := waitForOAuthCode(authURL, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exchange code for tokens
+	ctx, cancel := context.WithTimeout(redditContext(), 30*time.Second)
+	defer cancel()
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exchanging code: %v\n", err)
+		os.Exit(1)
+	}
+
+	username, err := getRedditUsername(ctx, config, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting Reddit username: %v\n", err)
+		os.Exit(1)
+	}
+
+	tokens := RedditTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		Username:     username,
+	}
+
+	if err := saveRedditTokens(tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf(em("✅ Authenticated as u/%s\n"), username)
+	fmt.Println(em("✅ Token saved to ~/.config/tm/reddit.json"))
+	fmt.Println()
+	fmt.Println("Restart 'tm serve' to start syncing saved items")
+}
+
+func getRedditUsername(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", redditAPIBase+"/api/v1/me", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", redditUserAgent)
+
+	resp, err := config.Client(ctx, token).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("reddit API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var me struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return "", err
+	}
+	return me.Name, nil
+}
+
+func loadRedditTokens() (*RedditTokens, error) {
+	home, _ := os.UserHomeDir()
+	tokenPath := filepath.Join(home, ".config", "tm", "reddit.json")
+
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens RedditTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+func saveRedditTokens(tokens RedditTokens) error {
+	home, _ := os.UserHomeDir()
+	configDir := filepath.Join(home, ".config", "tm")
+	os.MkdirAll(configDir, 0700)
+
+	tokenPath := filepath.Join(configDir, "reddit.json")
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(tokenPath, data, 0600)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// startSource builds, verifies and schedules one source, recording why it was
+// skipped when it can't run. Returns whether it was scheduled.
+func (s *Server) startSource(src syncSource, config Config) bool {
+	s.mu.Lock()
+	delete(s.skipped, src.name)
+	s.mu.Unlock()
+	skip := func(reason string) {
+		s.mu.Lock()
+		s.skipped[src.name] = reason
+		s.mu.Unlock()
+	}
+
+	syncer, err := buildSyncer(src.name, config)
+	if err == errNotConfigured {
+		return false
+	}
+	if errors.Is(err, errDBLocked) {
+		logger.Error("sync disabled: cache in use by another process", "source", src.name, "error", err)
+		skip("cache in use by another process")
+		return false
+	}
+	if err != nil {
+		logger.Warn("sync disabled", "source", src.name, "error", err)
+		skip(err.Error())
+		return false
+	}
+
+	// Pre-flight: surface bad credentials now rather than on the first tick
+	verified, err := verifySyncer(syncer)
+	if errors.Is(err, errInvalidToken) {
+		logger.Error("sync disabled: invalid token", "source", src.name, "error", err)
+		skip("invalid token")
+		if c, ok := syncer.(io.Closer); ok {
+			c.Close()
+		}
+		return false
+	}
+	if err != nil {
+		logger.Warn("sync could not be verified", "source", src.name, "error", err)
+	}
+
+	// Events already over at startup are cached but not queued
+	if w, ok := syncer.(sinceStartup); ok && !config.CalendarIncludePast {
+		w.setSince(s.started)
+	}
+
+	interval := src.intervalFor(config)
+	s.scheduler.Add(syncer, interval, src.initialDelay, src.timeout)
+	attrs := append([]any{"source", src.name, "interval", interval}, describeSyncer(src.name, config)...)
+	if verified {
+		logger.Info("sync enabled and verified", attrs...)
+	} else {
+		logger.Info("sync enabled", attrs...)
+	}
+	return true
+}
+
+// reloadResult is what a reload changed (POST /reload)
+type reloadResult struct {
+	Started   []string `json:"started,omitempty"`   // newly configured
+	Stopped   []string `json:"stopped,omitempty"`   // no longer configured (or now failing verification)
+	Restarted []string `json:"restarted,omitempty"` // settings changed: rebuilt with the new config
+	Retimed   []string `json:"retimed,omitempty"`   // only sync_intervals changed
+}
+
+// syncSettings is config minus what a reload applies without touching the
+// syncers, so comparing two tells whether any source needs rebuilding
+func syncSettings(config Config) Config {
+	config.SyncIntervals = nil
+	config.Token, config.TokenNext = "", ""
+	config.DedupKeys = nil
+	return config
+}
+
+// reload re-reads the config and reconciles the running syncs with it
+// (SIGHUP, POST /reload). Sources that became configured start, ones that
+// aren't any more stop and close their caches. When only sync_intervals
+// changed, the affected sources are retimed in place; any other change
+// rebuilds every running source, since most settings feed several of them.
+// The queue and connected plugins are untouched. token, token_next and
+// dedup_key apply too; other server settings still need a restart.
+func (s *Server) reload() reloadResult {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	config := loadConfig()
+	old := s.config
+	s.config = config
+
+	token, next := serverTokens(config)
+	s.setTokens(token, next)
+	s.mu.Lock()
+	s.dedup = config.DedupKeys
+	s.mu.Unlock()
+
+	rebuild := !reflect.DeepEqual(syncSettings(old), syncSettings(config))
+	var result reloadResult
+	for _, src := range syncSources {
+		if s.noSync || (len(s.only) > 0 && !slices.Contains(s.only, src.name)) {
+			continue
+		}
+		running := s.scheduler.Get(src.name) != nil
+		if !rebuild {
+			if running && src.intervalFor(config) != src.intervalFor(old) {
+				s.scheduler.SetInterval(src.name, src.intervalFor(config))
+				result.Retimed = append(result.Retimed, src.name)
+			}
+			continue
+		}
+
+		if running {
+			s.scheduler.Remove(src.name)
+		}
+		switch started := s.startSource(src, config); {
+		case running && started:
+			result.Restarted = append(result.Restarted, src.name)
+		case running:
+			result.Stopped = append(result.Stopped, src.name)
+		case started:
+			result.Started = append(result.Started, src.name)
+		}
+	}
+
+	logger.Info("config reloaded",
+		"started", result.Started,
+		"stopped", result.Stopped,
+		"restarted", result.Restarted,
+		"retimed", result.Retimed)
+	return result
+}
+
+// reloadOnSignal reloads the config on every SIGHUP
+func (s *Server) reloadOnSignal() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		logger.Info("SIGHUP: reloading config")
+		s.reload()
+	}
+}
+
+// handleReload reloads the config (POST /reload)
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.reload())
+}
+
+// runReload implements `tm serve --reload`: ask the running server to re-read the config
+func runReload() {
+	config := loadConfig()
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token, _ := serverTokens(config)
+
+	req, err := http.NewRequest("POST", url+"/reload", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+		os.Exit(1)
+	}
+
+	var result reloadResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(em("✓ Config reloaded"))
+	for _, line := range []struct {
+		label   string
+		sources []string
+	}{
+		{"started", result.Started},
+		{"stopped", result.Stopped},
+		{"restarted", result.Restarted},
+		{"retimed", result.Retimed},
+	} {
+		if len(line.sources) > 0 {
+			fmt.Printf("  %-10s %s\n", line.label+":", strings.Join(line.sources, ", "))
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// replayer is implemented by syncers that can re-render a cached item, so
+// `tm replay` can bring back a deleted record without a full resync
+type replayer interface {
+	// Replay renders the cached item with this external_id, with the verb a
+	// first sync would give it. Returns errNotCached for unknown ids.
+	Replay(id string) (QueueItem, error)
+	// ReplayIDs lists the external_ids Replay accepts
+	ReplayIDs() ([]string, error)
+}
+
+// errNotCached means a replayer has no cached item with the requested id
+var errNotCached = errors.New("not in cache")
+
+// loadCached reads one JSON-encoded entry from bucket
+func loadCached[T any](db *bolt.DB, bucket, id string) (T, error) {
+	var v T
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return errNotCached
+		}
+		data := b.Get([]byte(id))
+		if data == nil {
+			return errNotCached
+		}
+		return json.Unmarshal(data, &v)
+	})
+	return v, err
+}
+
+// cachedIDs lists the keys of bucket in key order
+func cachedIDs(db *bolt.DB, bucket string) ([]string, error) {
+	var ids []string
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	return ids, err
+}
+
+// replayResult is the response to POST /replay
+type replayResult struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Verb   string `json:"verb"`
+}
+
+// handleReplay lists replayable ids (GET /replay?source=) or re-queues one
+// cached item (POST /replay?id=[&source=]) as if it had just synced
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source != "" && s.scheduler.Get(source) == nil {
+		writeError(w, http.StatusBadRequest, errCodeNotConfigured, source+" sync not configured")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		ids := make(map[string][]string)
+		for _, syncer := range s.scheduler.Syncers() {
+			if source != "" && syncer.Name() != source {
+				continue
+			}
+			err := s.scheduler.Do(syncer.Name(), func(syncer Syncer) error {
+				rp, ok := syncer.(replayer)
+				if !ok {
+					return nil
+				}
+				list, err := rp.ReplayIDs()
+				ids[syncer.Name()] = list
+				return err
+			})
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ids": ids})
+
+	case "POST":
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "id required")
+			return
+		}
+		for _, syncer := range s.scheduler.Syncers() {
+			if source != "" && syncer.Name() != source {
+				continue
+			}
+			var item QueueItem
+			err := s.scheduler.Do(syncer.Name(), func(syncer Syncer) error {
+				rp, ok := syncer.(replayer)
+				if !ok {
+					return errNotCached
+				}
+				var err error
+				item, err = rp.Replay(id)
+				return err
+			})
+			if errors.Is(err, errNotCached) {
+				continue
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+				return
+			}
+
+			s.queueChanges(syncer, []QueueItem{item})
+			logger.Info("replayed cached item", "source", syncer.Name(), "id", id, "verb", item.Verb)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(replayResult{Source: syncer.Name(), ID: id, Title: item.Title, Verb: item.Verb})
+			return
+		}
+		writeError(w, http.StatusNotFound, errCodeNotFound, id+" not found in any cache")
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "GET or POST only")
+	}
+}
+
+// runReplay implements `tm replay <id>` and `tm replay --list [--source name]`
+func runReplay(args []string) {
+	var id, source string
+	list := false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--list":
+			list = true
+		case "--source":
+			if i+1 < len(args) {
+				source = args[i+1]
+				i++
+				continue
+			}
+			fmt.Println("Usage: tm replay <id> [--source name] | tm replay --list [--source name]")
+			return
+		default:
+			if strings.HasPrefix(arg, "-") || id != "" {
+				fmt.Println("Usage: tm replay <id> [--source name] | tm replay --list [--source name]")
+				return
+			}
+			id = arg
+		}
+	}
+	if !list && id == "" {
+		fmt.Println("Usage: tm replay <id> [--source name] | tm replay --list [--source name]")
+		return
+	}
+	if source != "" {
+		if _, ok := findSyncSource(source); !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown source %q\n", source)
+			os.Exit(1)
+		}
+	}
+
+	// Prefer the running server (it holds the cache locks); without one, open the caches directly
+	online := serverRunning()
+
+	if list {
+		var ids map[string][]string
+		var err error
+		if online {
+			ids, err = replayIDsHTTP(source)
+		} else {
+			ids, err = replayIDsLocal(source)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, src := range syncSources {
+			if cached, ok := ids[src.name]; ok {
+				fmt.Printf("%s (%d):\n", src.name, len(cached))
+				for _, id := range cached {
+					fmt.Printf("  %s\n", id)
+				}
+			}
+		}
+		return
+	}
+
+	var result *replayResult
+	var err error
+	if online {
+		result, err = replayHTTP(id, source)
+	} else {
+		result, err = replayLocal(id, source)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf(em("✓ Replayed %s from %s (%s: %s)\n"), result.ID, result.Source, result.Verb, result.Title)
+}
+
+// replayIDsHTTP asks the running server for replayable ids
+func replayIDsHTTP(source string) (map[string][]string, error) {
+	resp, err := replayRequest("GET", neturl.Values{"source": {source}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDs map[string][]string `json:"ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.IDs, nil
+}
+
+// replayHTTP asks the running server to re-queue a cached item
+func replayHTTP(id, source string) (*replayResult, error) {
+	resp, err := replayRequest("POST", neturl.Values{"id": {id}, "source": {source}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result replayResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func replayRequest(method string, params neturl.Values) (*http.Response, error) {
+	config := loadConfig()
+
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+	params.Set("token", token)
+	if params.Get("source") == "" {
+		params.Del("source")
+	}
+
+	req, err := http.NewRequest(method, url+"/replay?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%v (is 'tm serve' running?)", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s", apiErrorMessage(body))
+	}
+	return resp, nil
+}
+
+// replaySources returns the sources to search: just source, or every one
+func replaySources(source string) []string {
+	if source != "" {
+		return []string{source}
+	}
+	var names []string
+	for _, src := range syncSources {
+		names = append(names, src.name)
+	}
+	return names
+}
+
+// replayIDsLocal is replayIDsHTTP without a server. Unconfigured sources are skipped.
+func replayIDsLocal(source string) (map[string][]string, error) {
+	ids := make(map[string][]string)
+	for _, name := range replaySources(source) {
+		syncer, closeFn, err := openLocalSyncer(name)
+		if err == errNotConfigured {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if rp, ok := syncer.(replayer); ok {
+			ids[name], err = rp.ReplayIDs()
+		}
+		closeFn()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return ids, nil
+}
+
+// replayLocal is replayHTTP without a server: the item is pushed straight to
+// Thymer, like `tm sync --once`
+func replayLocal(id, source string) (*replayResult, error) {
+	config := loadConfig()
+	if config.URL == "" || config.Token == "" {
+		return nil, fmt.Errorf("THYMER_URL and THYMER_TOKEN required when tm serve isn't running")
+	}
+
+	for _, name := range replaySources(source) {
+		syncer, closeFn, err := openLocalSyncer(name)
+		if err == errNotConfigured {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		rp, ok := syncer.(replayer)
+		if !ok {
+			closeFn()
+			continue
+		}
+		item, err := rp.Replay(id)
+		closeFn()
+		if errors.Is(err, errNotCached) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		item.Upsert = item.ExternalID != ""
+		if err := sendToQueue(config, item); err != nil {
+			return nil, err
+		}
+		return &replayResult{Source: name, ID: id, Title: item.Title, Verb: item.Verb}, nil
+	}
+	return nil, fmt.Errorf("%s not found in any cache", id)
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// requestIDHeader carries a push's correlation id from tm to the server (and
+// on to forward_url), so one id can be grepped across CLI output and server logs
+const requestIDHeader = "X-Request-ID"
+
+// newRequestID returns a random id for an item that arrived without one
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "time"
+
+// compactInterval is how often tm serve prunes caches when cache_retention is set
+const compactInterval = 24 * time.Hour
+
+// pruner is implemented by syncers whose cache can drop entries that no
+// future sync will ask about again (past events, long-closed issues).
+// Prune only deletes from the cache; it never queues anything.
+type pruner interface {
+	Prune(cutoff time.Time) (int, error)
+}
+
+// Compact prunes cache entries older than retention from every syncer that
+// supports it, holding each syncer's lock so it never interleaves with a run
+func (sc *Scheduler) Compact(retention time.Duration) {
+	cutoff := time.Now().Add(-retention)
+	for _, e := range sc.list() {
+		p, ok := e.syncer.(pruner)
+		if !ok {
+			continue
+		}
+
+		e.mu.Lock()
+		n, err := p.Prune(cutoff)
+		e.mu.Unlock()
+
+		if err != nil {
+			logger.Warn("cache compaction failed", "source", e.syncer.Name(), "error", err)
+			continue
+		}
+		if n > 0 {
+			logger.Info("cache compacted", "source", e.syncer.Name(), "reclaimed", n, "retention", retention)
+		}
+	}
+}
+
+// startCacheCompaction prunes once at startup and then every interval
+func (s *Server) startCacheCompaction(retention, interval time.Duration) {
+	s.scheduler.Compact(retention)
+
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		s.scheduler.Compact(retention)
+	}
+}
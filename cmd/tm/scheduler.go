@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Syncer is a source that can be polled for changes
+type Syncer interface {
+	// Name is the short source name used in routes, logs, and the audit log
+	Name() string
+	// Sync fetches changes since the last run and renders them as queue items
+	Sync(ctx context.Context) ([]QueueItem, error)
+	// ClearCache forgets sync state so the next Sync re-queues everything
+	ClearCache() error
+	// CachedCount is how many items ClearCache would forget (and re-queue)
+	CachedCount() (int, error)
+}
+
+// partialClearer is implemented by syncers that can forget part of their cache,
+// e.g. one GitHub repo, so a resync doesn't re-queue every other repo too
+type partialClearer interface {
+	ClearCacheFor(key string) (int, error)
+}
+
+// heldStore is implemented by syncers whose items may be held during quiet hours.
+// Syncers without it (calendar) are exempt and always delivered immediately.
+type heldStore interface {
+	metaStore() (*bolt.DB, string)
+}
+
+// maxBackoffFactor caps exponential backoff at this multiple of the interval
+const maxBackoffFactor = 8
+
+// maxJitter caps the random offset added to each run so sources restarted
+// together don't keep hitting their APIs in lockstep
+const maxJitter = 30 * time.Second
+
+type scheduledSyncer struct {
+	syncer       Syncer
+	interval     atomic.Int64 // time.Duration; SetInterval changes it while running
+	initialDelay time.Duration
+	timeout      time.Duration
+	mu           sync.Mutex // serializes runs (ticker vs. manual trigger)
+	failures     int
+	resyncing    bool // cache was just cleared; next successful run applies maxAge
+
+	synced  atomic.Bool // at least one run succeeded (read by /ready without waiting on mu)
+	history syncHistory // recent runs' changed/unchanged counts (for /status)
+
+	cancel  context.CancelFunc // stops the loop (nil until started)
+	wake    chan struct{}      // restarts the wait after SetInterval
+	stopped bool               // removed and closed; guarded by mu
+}
+
+// period is the current interval
+func (e *scheduledSyncer) period() time.Duration {
+	return time.Duration(e.interval.Load())
+}
+
+// Scheduler runs registered syncers on their intervals and fans changes in to onChange
+type Scheduler struct {
+	entries   []*scheduledSyncer
+	onChange  func(Syncer, []QueueItem)
+	onFailure func(Syncer, error, int) // called after each failed run with the consecutive failure count (nil = off)
+	onEvent   func(SyncEvent)          // lifecycle events for /events (nil = off)
+	maxAge    time.Duration            // resync_max_age: after ClearCache, only queue items newer than this (0 = all)
+
+	mu  sync.RWMutex    // guards entries and ctx: a config reload adds and removes syncers while running
+	ctx context.Context // set by Start; syncers added later start right away
+}
+
+// NewScheduler creates a scheduler that reports changes to onChange
+func NewScheduler(onChange func(Syncer, []QueueItem)) *Scheduler {
+	return &Scheduler{onChange: onChange}
+}
+
+// Add registers a syncer. It starts running when Start is called, or right
+// away if the scheduler is already running.
+func (sc *Scheduler) Add(syncer Syncer, interval, initialDelay, timeout time.Duration) {
+	e := &scheduledSyncer{
+		syncer:       syncer,
+		initialDelay: initialDelay,
+		timeout:      timeout,
+		wake:         make(chan struct{}, 1),
+	}
+	e.interval.Store(int64(interval))
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries = append(sc.entries, e)
+	if sc.ctx != nil {
+		sc.start(e)
+	}
+}
+
+// Remove stops the named syncer and closes it. A run in progress is cancelled
+// and waited for, so its cache is never closed mid-write. Returns false if no
+// such syncer is registered.
+func (sc *Scheduler) Remove(name string) bool {
+	sc.mu.Lock()
+	i := slices.IndexFunc(sc.entries, func(e *scheduledSyncer) bool { return e.syncer.Name() == name })
+	if i < 0 {
+		sc.mu.Unlock()
+		return false
+	}
+	e := sc.entries[i]
+	sc.entries = slices.Delete(sc.entries, i, i+1)
+	sc.mu.Unlock()
+
+	if e.cancel != nil {
+		e.cancel()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = true
+	if c, ok := e.syncer.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			logger.Warn("failed to close sync cache", "source", name, "error", err)
+		}
+	}
+	return true
+}
+
+// SetInterval changes how often the named syncer runs. The next run is an
+// interval from now. Returns false if no such syncer is registered.
+func (sc *Scheduler) SetInterval(name string, interval time.Duration) bool {
+	e := sc.entry(name)
+	if e == nil {
+		return false
+	}
+	e.interval.Store(int64(interval))
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// list returns the registered syncers, safe to range over while a reload
+// adds or removes some
+func (sc *Scheduler) list() []*scheduledSyncer {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return slices.Clone(sc.entries)
+}
+
+// Get returns the registered syncer with the given name
+func (sc *Scheduler) Get(name string) Syncer {
+	if e := sc.entry(name); e != nil {
+		return e.syncer
+	}
+	return nil
+}
+
+// Syncers returns all registered syncers in registration order
+func (sc *Scheduler) Syncers() []Syncer {
+	entries := sc.list()
+	syncers := make([]Syncer, 0, len(entries))
+	for _, e := range entries {
+		syncers = append(syncers, e.syncer)
+	}
+	return syncers
+}
+
+// Readiness reports, per registered syncer, whether it has completed a
+// successful run yet
+func (sc *Scheduler) Readiness() map[string]bool {
+	entries := sc.list()
+	ready := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		ready[e.syncer.Name()] = e.synced.Load()
+	}
+	return ready
+}
+
+func (sc *Scheduler) entry(name string) *scheduledSyncer {
+	for _, e := range sc.list() {
+		if e.syncer.Name() == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Start launches one goroutine per syncer; they stop when ctx is cancelled
+func (sc *Scheduler) Start(ctx context.Context) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.ctx = ctx
+	for _, e := range sc.entries {
+		sc.start(e)
+	}
+}
+
+// start launches e's loop under its own context, so Remove can stop it alone.
+// Call with sc.mu held.
+func (sc *Scheduler) start(e *scheduledSyncer) {
+	ctx, cancel := context.WithCancel(sc.ctx)
+	e.cancel = cancel
+	go sc.loop(ctx, e)
+}
+
+// ClearCache clears the named syncer's cache. The next run re-caches everything
+// but only queues items within maxAge, so a resync doesn't re-flood old history.
+func (sc *Scheduler) ClearCache(name string) error {
+	e := sc.entry(name)
+	if e == nil {
+		return fmt.Errorf("unknown source %q", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.syncer.ClearCache(); err != nil {
+		return err
+	}
+	e.resyncing = true
+	return nil
+}
+
+// ClearCacheFor clears the part of the named syncer's cache identified by key
+// (a repo for github) and returns how many entries were dropped
+func (sc *Scheduler) ClearCacheFor(name, key string) (int, error) {
+	e := sc.entry(name)
+	if e == nil {
+		return 0, fmt.Errorf("unknown source %q", name)
+	}
+	pc, ok := e.syncer.(partialClearer)
+	if !ok {
+		return 0, fmt.Errorf("%s can't clear part of its cache", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n, err := pc.ClearCacheFor(key)
+	if err != nil {
+		return 0, err
+	}
+	e.resyncing = true
+	return n, nil
+}
+
+// Do calls fn with the named syncer while holding its lock, so fn never
+// interleaves with a run
+func (sc *Scheduler) Do(name string, fn func(Syncer) error) error {
+	e := sc.entry(name)
+	if e == nil {
+		return fmt.Errorf("unknown source %q", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return fmt.Errorf("unknown source %q", name)
+	}
+	return fn(e.syncer)
+}
+
+// Trigger runs the named syncer now in the background. Returns false if unknown.
+func (sc *Scheduler) Trigger(name string) bool {
+	e := sc.entry(name)
+	if e == nil {
+		return false
+	}
+	go sc.run(context.Background(), e)
+	return true
+}
+
+func (sc *Scheduler) loop(ctx context.Context, e *scheduledSyncer) {
+	timer := time.NewTimer(e.initialDelay + jitter(e.period()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("sync stopped", "source", e.syncer.Name())
+			return
+		case <-e.wake:
+			timer.Stop()
+			timer.Reset(e.period() + jitter(e.period()))
+		case <-timer.C:
+			timer.Reset(sc.run(ctx, e) + jitter(e.period()))
+		}
+	}
+}
+
+// run performs one sync and returns the delay until the next one
+func (sc *Scheduler) run(ctx context.Context, e *scheduledSyncer) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return e.period()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	name := e.syncer.Name()
+	started := time.Now()
+	sc.emit(SyncEvent{Type: "start", Source: name})
+
+	items, err := e.syncer.Sync(ctx)
+	if err != nil {
+		e.failures++
+		delay := backoffDelay(e.period(), e.failures)
+		logger.Error("sync failed", "source", name, "error", err, "failures", e.failures, "retry_in", delay)
+		sc.emit(SyncEvent{Type: "error", Source: name, Duration: time.Since(started).Round(time.Millisecond).String(), Error: err.Error(), Failures: e.failures})
+		sc.emit(SyncEvent{Type: "backoff", Source: name, Failures: e.failures, RetryIn: delay.String()})
+		if sc.onFailure != nil {
+			sc.onFailure(e.syncer, err, e.failures)
+		}
+		return delay
+	}
+	e.failures = 0
+	e.synced.Store(true)
+
+	counts := runCounts{changed: len(items), unchanged: -1}
+	if uc, ok := e.syncer.(unchangedCounter); ok {
+		counts.unchanged = uc.LastUnchanged()
+	}
+	e.history.add(counts)
+
+	if e.resyncing {
+		e.resyncing = false
+		items = sc.dropOlderThanMaxAge(name, items)
+	}
+
+	if len(items) > 0 {
+		sc.onChange(e.syncer, items)
+	}
+	sc.emit(SyncEvent{Type: "finish", Source: name, Items: len(items), Duration: time.Since(started).Round(time.Millisecond).String()})
+	return e.period()
+}
+
+func (sc *Scheduler) emit(event SyncEvent) {
+	if sc.onEvent == nil {
+		return
+	}
+	event.Time = time.Now()
+	sc.onEvent(event)
+}
+
+// dropOlderThanMaxAge filters a post-resync batch down to items within maxAge.
+// Dropped items are already cached by the syncer, so they won't come back later.
+func (sc *Scheduler) dropOlderThanMaxAge(name string, items []QueueItem) []QueueItem {
+	if sc.maxAge <= 0 {
+		return items
+	}
+
+	cutoff := time.Now().Add(-sc.maxAge)
+	kept := items[:0]
+	for _, item := range items {
+		if !item.SourceTime.IsZero() && item.SourceTime.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	logger.Info("resync: skipped items older than resync_max_age", "source", name, "skipped", len(items)-len(kept), "queued", len(kept))
+	return kept
+}
+
+// jitter returns a random offset of up to a tenth of interval, capped at maxJitter
+func jitter(interval time.Duration) time.Duration {
+	limit := min(interval/10, maxJitter)
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+// backoffDelay doubles the interval per consecutive failure, capped
+func backoffDelay(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 0; i < failures && delay < interval*maxBackoffFactor; i++ {
+		delay *= 2
+	}
+	if delay > interval*maxBackoffFactor {
+		delay = interval * maxBackoffFactor
+	}
+	return delay
+}
+
+// defaultSyncConcurrency bounds parallel fetches within one sync (repos, calendars)
+const defaultSyncConcurrency = 4
+
+// fetchResult pairs a fetched value with the key it was fetched for
+type fetchResult[T any] struct {
+	key   string
+	value T
+	err   error
+}
+
+// fetchConcurrently runs fetch for each key on at most `concurrency` workers and
+// returns the results in key order. Callers apply results (e.g. bbolt upserts)
+// sequentially afterwards, so only the network I/O runs in parallel.
+func fetchConcurrently[T any](ctx context.Context, keys []string, concurrency int, fetch func(context.Context, string) (T, error)) []fetchResult[T] {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]fetchResult[T], len(keys))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			value, err := fetch(ctx, key)
+			results[i] = fetchResult[T]{key: key, value: value, err: err}
+		}(i, key)
+	}
+
+	wg.Wait()
+	return results
+}
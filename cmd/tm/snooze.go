@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// snoozeLayouts are the absolute times --snooze accepts, read in the display zone
+var snoozeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseSnooze turns --snooze into the time the item becomes deliverable: a
+// duration from now (90m, 4h, 1d) or an absolute time (2026-10-20, 2026-10-20
+// 08:30, RFC 3339). A date alone means its midnight, so the note lands at the
+// start of that day's journal.
+func parseSnooze(v string, now time.Time) (time.Time, error) {
+	if d, err := parseDuration(v); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("--snooze must be in the future, got %q", v)
+		}
+		return now.Add(d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	for _, layout := range snoozeLayouts {
+		if err == nil {
+			break
+		}
+		t, err = time.ParseInLocation(layout, v, displayLocation)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--snooze wants a duration (4h, 1d) or a date (2006-01-02 [15:04]), got %q", v)
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("--snooze must be in the future, got %q", v)
+	}
+	return t, nil
+}
+
+// due reports whether the item may be delivered at now: it isn't snoozed, or
+// its DeliverAfter has passed. An unreadable DeliverAfter never holds an item.
+func (item QueueItem) due(now time.Time) bool {
+	if item.DeliverAfter == "" {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, item.DeliverAfter)
+	return err != nil || !now.Before(t)
+}
+
+// snoozedCount is how many queued items aren't due yet. Call with s.mu held.
+func (s *Server) snoozedCount(now time.Time) int {
+	var n int
+	for _, item := range s.queue {
+		if !item.due(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// replaceSnoozed drops snoozed items for the same record as item, which is a
+// newer version of it: a rescheduled meeting mustn't also arrive at its old
+// time with stale details. Call with s.mu held.
+func (s *Server) replaceSnoozed(item QueueItem) {
+	if item.ExternalID == "" {
+		return
+	}
+	now := time.Now()
+	for id, queued := range s.queue {
+		if queued.ExternalID != item.ExternalID || queued.Source != item.Source || queued.due(now) {
+			continue
+		}
+		s.unqueue(id)
+		s.stats.forget(queued)
+		logger.Debug("replaced snoozed item", "external_id", item.ExternalID, "replaced", id, "request_id", item.RequestID)
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errNotConfigured means a source has no credentials/targets in the config
+var errNotConfigured = errors.New("not configured")
+
+// errInvalidToken means a source rejected its credentials during verification
+var errInvalidToken = errors.New("invalid token")
+
+// defaultSyncLabel is the label, tag or #hashtag that require_sync_label looks for
+const defaultSyncLabel = "thymer"
+
+// verifyTimeout bounds each source's pre-flight check in runServer
+const verifyTimeout = 10 * time.Second
+
+// verifier is implemented by syncers that can cheaply check their credentials.
+// Verify returns an error wrapping errInvalidToken when the source rejects them;
+// any other error (network, outage) leaves the source enabled.
+type verifier interface {
+	Verify(ctx context.Context) error
+}
+
+// syncSource describes how the server schedules a source
+type syncSource struct {
+	name         string
+	interval     time.Duration
+	initialDelay time.Duration
+	timeout      time.Duration
+}
+
+// intervalFor is how often the source syncs: its sync_intervals entry, else
+// the default
+func (src syncSource) intervalFor(config Config) time.Duration {
+	v, ok := config.SyncIntervals[src.name]
+	if !ok {
+		return src.interval
+	}
+	d, err := parseDuration(v)
+	if err != nil || d <= 0 {
+		logger.Warn("ignoring sync_intervals entry", "source", src.name, "value", v)
+		return src.interval
+	}
+	return d
+}
+
+// syncSources lists every source in the order the server starts them
+var syncSources = []syncSource{
+	{name: "github", interval: 1 * time.Minute, timeout: 30 * time.Second},
+	// Projects: GraphQL paging through whole boards, so poll less often than issues
+	{name: "github-projects", interval: 5 * time.Minute, timeout: 60 * time.Second},
+	// Readwise: initial sync after short delay (let server start); generous timeout for rate-limit waits
+	{name: "readwise", interval: 1 * time.Hour, initialDelay: 5 * time.Second, timeout: 10 * time.Minute},
+	{name: "calendar", interval: 5 * time.Minute, timeout: 30 * time.Second},
+	// CalDAV: discovery plus one REPORT per calendar, so allow longer than Google
+	{name: "caldav", interval: 5 * time.Minute, timeout: 60 * time.Second},
+	{name: "jira", interval: 5 * time.Minute, timeout: 60 * time.Second},
+	{name: "strava", interval: 15 * time.Minute, timeout: 60 * time.Second},
+	{name: "reddit", interval: 30 * time.Minute, timeout: 60 * time.Second},
+	// Spotify only remembers the last 50 plays, so poll often enough not to miss any
+	{name: "spotify", interval: 15 * time.Minute, timeout: 60 * time.Second},
+	// Last.fm keeps full history, so a missed run only delays scrobbles
+	{name: "lastfm", interval: 15 * time.Minute, timeout: 60 * time.Second},
+	// Weather logs once a day; hourly checks catch the first run after weatherLogHour
+	{name: "weather", interval: 1 * time.Hour, initialDelay: 10 * time.Second, timeout: 30 * time.Second},
+}
+
+func findSyncSource(name string) (syncSource, bool) {
+	for _, src := range syncSources {
+		if src.name == name {
+			return src, true
+		}
+	}
+	return syncSource{}, false
+}
+
+// tmDataDir returns ~/.config/tm, creating it if needed
+func tmDataDir() string {
+	home, _ := os.UserHomeDir()
+	dataDir := filepath.Join(home, ".config", "tm")
+	os.MkdirAll(dataDir, 0755)
+	return dataDir
+}
+
+// buildSyncer constructs the named syncer from config.
+// Returns errNotConfigured when the source isn't set up.
+func buildSyncer(name string, config Config) (Syncer, error) {
+	dataDir := tmDataDir()
+
+	switch name {
+	case "github":
+		if config.GitHubToken == "" || (len(config.GitHubRepos) == 0 && len(config.GitHubScope) == 0) {
+			return nil, errNotConfigured
+		}
+		syncer, err := NewGitHubSyncer(config.GitHubToken, config.GitHubRepos, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, scope := range config.GitHubScope {
+			switch scope {
+			case "mentioned", "subscribed", "assigned":
+				syncer.scopes = append(syncer.scopes, scope)
+			default:
+				logger.Warn("ignoring unknown github_scope", "scope", scope)
+			}
+		}
+		if len(syncer.scopes) == 0 && len(config.GitHubRepos) == 0 {
+			syncer.Close()
+			return nil, errNotConfigured
+		}
+		if config.SyncConcurrency > 0 {
+			syncer.concurrency = config.SyncConcurrency
+		}
+		syncer.reactionPriority = config.GitHubReactionPriority
+		syncer.collection = config.GitHubCollection
+		syncer.footer = config.footerTemplate()
+		syncer.htmlToMarkdown = config.HTMLToMarkdown
+		if config.GitHubInitialWindow != "" {
+			if d, err := parseDuration(config.GitHubInitialWindow); err != nil {
+				logger.Warn("ignoring github_initial_window", "error", err)
+			} else {
+				syncer.initialWindow = d
+			}
+		}
+		syncer.retention = config.cacheRetention()
+		syncer.syncLabel = config.syncLabel()
+		syncer.maxBody = config.MaxBodyChars
+		syncer.statusIcons = config.GitHubStatusIcons
+		syncer.linkPRs = config.GitHubLinkPRs
+		syncer.backfill = config.GitHubBackfill
+		if config.GitHubPRFiles {
+			syncer.prFiles = githubPRFilesMax
+			if config.GitHubPRFilesMax > 0 {
+				syncer.prFiles = config.GitHubPRFilesMax
+			}
+		}
+		return syncer, nil
+
+	case "github-projects":
+		if config.GitHubToken == "" || len(config.GitHubProjects) == 0 {
+			return nil, errNotConfigured
+		}
+		var projects []projectRef
+		for _, p := range config.GitHubProjects {
+			ref, err := parseProjectRef(p)
+			if err != nil {
+				logger.Warn("ignoring github_project", "error", err)
+				continue
+			}
+			projects = append(projects, ref)
+		}
+		if len(projects) == 0 {
+			return nil, errNotConfigured
+		}
+		return NewGitHubProjectsSyncer(config.GitHubToken, projects, dataDir)
+
+	case "readwise":
+		if config.ReadwiseToken == "" {
+			return nil, errNotConfigured
+		}
+		syncer, err := NewReadwiseSyncer(config.ReadwiseToken, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		syncer.maxHighlightsPerItem = config.ReadwiseMaxHighlights
+		syncer.collection = config.ReadwiseCollection
+		syncer.footer = config.footerTemplate()
+		syncer.htmlToMarkdown = config.HTMLToMarkdown
+		syncer.syncLabel = config.syncLabel()
+		syncer.maxBody = config.MaxBodyChars
+		switch config.ReadwiseStyle {
+		case "", "quote", "numbered":
+			syncer.highlightStyle = config.ReadwiseStyle
+		default:
+			logger.Warn("ignoring unknown readwise_highlight_style", "style", config.ReadwiseStyle)
+		}
+		switch config.ReadwiseSeparator {
+		case "", "blank", "rule":
+			syncer.highlightSeparator = config.ReadwiseSeparator
+		default:
+			logger.Warn("ignoring unknown readwise_highlight_separator", "separator", config.ReadwiseSeparator)
+		}
+		switch config.ReadwiseMode {
+		case "", "flat":
+		case "nested":
+			syncer.nested = true
+		default:
+			logger.Warn("ignoring unknown readwise_mode", "mode", config.ReadwiseMode)
+		}
+		return syncer, nil
+
+	case "calendar":
+		if len(config.GoogleCalendars) == 0 {
+			return nil, errNotConfigured
+		}
+		tokens, err := loadGoogleTokens()
+		if err != nil {
+			return nil, fmt.Errorf("not authenticated - run 'tm auth google'")
+		}
+		calTokens := &CalendarTokens{
+			AccessToken:  tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			TokenType:    tokens.TokenType,
+			Expiry:       tokens.Expiry,
+		}
+		syncer, err := NewCalendarSyncer(calTokens, config.GoogleCalendars, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		if config.CalendarMinDuration != "" {
+			if d, err := parseDuration(config.CalendarMinDuration); err != nil {
+				logger.Warn("ignoring calendar_min_duration", "error", err)
+			} else {
+				syncer.minDuration = d
+			}
+		}
+		if config.SyncConcurrency > 0 {
+			syncer.concurrency = config.SyncConcurrency
+		}
+		syncer.names = config.CalendarNames
+		syncer.collection = config.CalendarCollection
+		syncer.footer = config.footerTemplate()
+		syncer.syncLabel = config.syncLabel()
+		syncer.categories = config.CalendarColorCategories
+		syncer.lead = config.calendarDeliverBefore()
+		return syncer, nil
+
+	case "caldav":
+		if config.CalDAVURL == "" {
+			return nil, errNotConfigured
+		}
+		syncer, err := NewCalDAVSyncer(config.CalDAVURL, config.CalDAVUser, config.CalDAVPassword, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		syncer.names = config.CalendarNames
+		syncer.collection = config.CalendarCollection
+		syncer.footer = config.footerTemplate()
+		syncer.syncLabel = config.syncLabel()
+		syncer.lead = config.calendarDeliverBefore()
+		return syncer, nil
+
+	case "jira":
+		if config.JiraBaseURL == "" || config.JiraToken == "" {
+			return nil, errNotConfigured
+		}
+		jql := config.JiraJQL
+		if jql == "" {
+			jql = "assignee=currentUser() AND statusCategory!=Done"
+		}
+		return NewJiraSyncer(config.JiraBaseURL, config.JiraEmail, config.JiraToken, jql, dataDir)
+
+	case "strava":
+		if config.StravaClientID == "" {
+			return nil, errNotConfigured
+		}
+		tokens, err := loadStravaTokens()
+		if err != nil {
+			return nil, fmt.Errorf("not authenticated - run 'tm auth strava'")
+		}
+		return NewStravaSyncer(tokens, dataDir)
+
+	case "reddit":
+		if config.RedditClientID == "" {
+			return nil, errNotConfigured
+		}
+		tokens, err := loadRedditTokens()
+		if err != nil {
+			return nil, fmt.Errorf("not authenticated - run 'tm auth reddit'")
+		}
+		return NewRedditSyncer(tokens, dataDir)
+
+	case "spotify":
+		if config.SpotifyClientID == "" {
+			return nil, errNotConfigured
+		}
+		tokens, err := loadSpotifyTokens()
+		if err != nil {
+			return nil, fmt.Errorf("not authenticated - run 'tm auth spotify'")
+		}
+		syncer, err := NewSpotifySyncer(tokens, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		switch config.SpotifyMode {
+		case "":
+		case "recent", "saved", "both":
+			syncer.mode = config.SpotifyMode
+		default:
+			logger.Warn("ignoring unknown spotify_mode", "mode", config.SpotifyMode)
+		}
+		return syncer, nil
+
+	case "lastfm":
+		if config.LastfmAPIKey == "" || config.LastfmUser == "" {
+			return nil, errNotConfigured
+		}
+		return NewLastfmSyncer(config.LastfmAPIKey, config.LastfmUser, dataDir)
+
+	case "weather":
+		if config.WeatherLocation == "" {
+			return nil, errNotConfigured
+		}
+		lat, lon, place, err := parseWeatherLocation(config.WeatherLocation)
+		if err != nil {
+			return nil, err
+		}
+		return NewWeatherSyncer(lat, lon, place, dataDir)
+	}
+
+	return nil, fmt.Errorf("unknown source %q", name)
+}
+
+// footerTemplate returns the link-back footer template, or "" when footers are off
+func (c Config) footerTemplate() string {
+	if !c.Footer {
+		return ""
+	}
+	if c.FooterTemplate == "" {
+		return defaultFooterTemplate
+	}
+	return c.FooterTemplate
+}
+
+// cacheRetention returns the parsed cache_retention, or 0 (keep everything)
+// when unset or invalid; runServer reports the invalid case
+func (c Config) cacheRetention() time.Duration {
+	d, err := parseDuration(c.CacheRetention)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// calendarDeliverBefore returns the parsed calendar_deliver_before, or 0
+// (deliver on sync) when unset or invalid
+func (c Config) calendarDeliverBefore() time.Duration {
+	if c.CalendarDeliverBefore == "" {
+		return 0
+	}
+	d, err := parseDuration(c.CalendarDeliverBefore)
+	if err != nil {
+		logger.Warn("ignoring calendar_deliver_before", "error", err)
+		return 0
+	}
+	return d
+}
+
+// syncLabel returns the label items need when require_sync_label=true, or ""
+// when every item syncs
+func (c Config) syncLabel() string {
+	if !c.RequireSyncLabel {
+		return ""
+	}
+	if c.SyncLabel == "" {
+		return defaultSyncLabel
+	}
+	return c.SyncLabel
+}
+
+// describeSyncer returns extra log attributes for a source's "enabled" line
+func describeSyncer(name string, config Config) []any {
+	switch name {
+	case "github":
+		if len(config.GitHubScope) > 0 {
+			return []any{"scope", strings.Join(config.GitHubScope, ", ")}
+		}
+		return []any{"repos", strings.Join(config.GitHubRepos, ", ")}
+	case "github-projects":
+		return []any{"projects", strings.Join(config.GitHubProjects, ", ")}
+	case "calendar":
+		return []any{"calendars", strings.Join(config.GoogleCalendars, ", ")}
+	case "caldav":
+		return []any{"url", config.CalDAVURL}
+	case "jira":
+		return []any{"url", config.JiraBaseURL}
+	case "lastfm":
+		return []any{"user", config.LastfmUser}
+	case "weather":
+		return []any{"location", config.WeatherLocation}
+	}
+	return nil
+}
+
+// verifySyncer runs the syncer's pre-flight check, if it has one
+func verifySyncer(syncer Syncer) (verified bool, err error) {
+	v, ok := syncer.(verifier)
+	if !ok {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+	if err := v.Verify(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runSyncOnce syncs a single source in-process and pushes the results straight
+// to the configured Thymer URL, without needing a running `tm serve`. With
+// watch (tm sync <source> --watch), each item is printed as it's pushed.
+func runSyncOnce(name string, watch bool) {
+	config := loadConfig()
+
+	if config.URL == "" || config.Token == "" {
+		fmt.Fprintln(os.Stderr, "Error: THYMER_URL and THYMER_TOKEN required for --once and --watch")
+		os.Exit(1)
+	}
+
+	src, ok := findSyncSource(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown source %q\n", name)
+		os.Exit(1)
+	}
+
+	syncer, err := buildSyncer(name, config)
+	if err == errNotConfigured {
+		fmt.Fprintf(os.Stderr, "Error: %s sync is not configured\n", name)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if c, ok := syncer.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), src.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if watch {
+		fmt.Printf(em("→ Syncing %s...\n"), name)
+	}
+
+	items, err := syncer.Sync(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s sync failed: %v\n", name, err)
+		os.Exit(1)
+	}
+	if watch {
+		fmt.Printf("  %d changes found in %s\n", len(items), time.Since(start).Round(time.Millisecond))
+	}
+
+	var failed []QueueItem
+	for _, item := range items {
+		item.Upsert = item.ExternalID != ""
+		err := sendToQueue(config, item)
+		if err != nil {
+			failed = append(failed, item)
+		}
+		switch {
+		case watch && err != nil:
+			fmt.Printf(em("  ✗ %-10s %s: %v\n"), watchVerb(item), watchTitle(item), err)
+		case watch:
+			fmt.Printf(em("  ✓ %-10s %s\n"), watchVerb(item), watchTitle(item))
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error sending %s: %v\n", item.Title, err)
+		}
+	}
+
+	fmt.Printf(em("✓ %s: pushed %d items"), strings.Title(name), len(items)-len(failed))
+	if len(failed) > 0 {
+		fmt.Printf(" (%d failed)", len(failed))
+	}
+	if watch {
+		fmt.Printf(" in %s", time.Since(start).Round(time.Millisecond))
+	}
+	fmt.Println()
+
+	if len(failed) > 0 {
+		// Sync already cached the failed items as seen; without this the next
+		// run would skip them for good
+		if err := forgetFailed(syncer, failed); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed items won't be retried: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Cleared the %s cache so the next sync pushes the failed items again\n", name)
+		}
+		os.Exit(1)
+	}
+}
+
+// forgetFailed clears the cache behind items that couldn't be pushed, so the
+// next run queues them again. GitHub only forgets the failed items' repos;
+// other sources sync from a cursor, so their whole cache goes and the next
+// run re-sends everything. Records are upserted by external_id, but lifelog
+// entries (Last.fm, weather) that did arrive are logged again.
+func forgetFailed(syncer Syncer, failed []QueueItem) error {
+	if pc, ok := syncer.(partialClearer); ok {
+		repos := make(map[string]bool)
+		for _, item := range failed {
+			repo := frontmatterFields(item.Content)["repo"]
+			if repo == "" {
+				return syncer.ClearCache()
+			}
+			repos[repo] = true
+		}
+		for repo := range repos {
+			if _, err := pc.ClearCacheFor(repo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return syncer.ClearCache()
+}
+
+// watchVerb is the verb shown for an item in --watch output
+func watchVerb(item QueueItem) string {
+	if item.Verb != "" {
+		return item.Verb
+	}
+	return item.Action
+}
+
+// watchTitle is the title shown in --watch output; lifelog items have none,
+// so their first line stands in
+func watchTitle(item QueueItem) string {
+	if item.Title != "" {
+		return item.Title
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(item.Content), "\n")
+	if len(line) > 80 {
+		line = line[:77] + "..."
+	}
+	return line
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// fakeSyncer records which caches it was asked to clear
+type fakeSyncer struct {
+	cleared bool
+}
+
+func (f *fakeSyncer) Name() string                              { return "fake" }
+func (f *fakeSyncer) Sync(context.Context) ([]QueueItem, error) { return nil, nil }
+func (f *fakeSyncer) ClearCache() error                         { f.cleared = true; return nil }
+func (f *fakeSyncer) CachedCount() (int, error)                 { return 0, nil }
+
+type fakeRepoSyncer struct {
+	fakeSyncer
+	repos []string
+}
+
+func (f *fakeRepoSyncer) ClearCacheFor(repo string) (int, error) {
+	f.repos = append(f.repos, repo)
+	return 1, nil
+}
+
+func TestForgetFailed(t *testing.T) {
+	issue := func(repo string) QueueItem {
+		return QueueItem{Content: "---\nrepo: " + repo + "\n---\n\nbody"}
+	}
+
+	gh := &fakeRepoSyncer{}
+	if err := forgetFailed(gh, []QueueItem{issue("a/b"), issue("c/d"), issue("a/b")}); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(gh.repos)
+	if gh.cleared || !slices.Equal(gh.repos, []string{"a/b", "c/d"}) {
+		t.Errorf("cleared all=%v repos=%v, want only a/b and c/d", gh.cleared, gh.repos)
+	}
+
+	// An item without a repo can't be cleared on its own
+	gh = &fakeRepoSyncer{}
+	if err := forgetFailed(gh, []QueueItem{issue("a/b"), {Content: "no frontmatter"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !gh.cleared {
+		t.Error("cache not cleared for an item without a repo")
+	}
+
+	other := &fakeSyncer{}
+	if err := forgetFailed(other, []QueueItem{{Content: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !other.cleared {
+		t.Error("cache not cleared for a syncer without ClearCacheFor")
+	}
+}
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	spotifyBucket     = "spotify_tracks"
+	spotifyMetaBucket = "spotify_meta"
+	spotifyAPIBase    = "https://api.spotify.com/v1"
+	spotifyPageSize   = 50 // API maximum for both endpoints
+	spotifyMaxPages   = 4  // saved tracks only; recently-played is capped at 50 by Spotify
+
+	// Spotify rejects "localhost" redirect URIs, so it gets the loopback literal
+	spotifyCallbackURL = "http://127.0.0.1:" + OAuthCallbackPort + "/callback"
+)
+
+// SpotifyTokens holds OAuth tokens for the Spotify Web API
+type SpotifyTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	User         string    `json:"user,omitempty"`
+}
+
+// SpotifyTrack represents a stored Spotify track
+type SpotifyTrack struct {
+	ID         string    `json:"id"` // spotify_{trackId}
+	TrackID    string    `json:"track_id"`
+	Name       string    `json:"name"`
+	Artists    []string  `json:"artists"`
+	Album      string    `json:"album"`
+	DurationMs int       `json:"duration_ms"`
+	URL        string    `json:"url"`
+	Plays      int       `json:"plays"`
+	LastPlayed time.Time `json:"last_played,omitempty"`
+	SavedAt    time.Time `json:"saved_at,omitempty"`
+	Verb       string    `json:"-"` // transient: played, saved (not stored)
+}
+
+// Artist returns the track's artists joined for display
+func (t SpotifyTrack) Artist() string {
+	return strings.Join(t.Artists, ", ")
+}
+
+// ToMarkdown returns the track as markdown with YAML frontmatter
+func (t SpotifyTrack) ToMarkdown() string {
+	var b strings.Builder
+
+	// YAML frontmatter
+	b.WriteString("---\n")
+	b.WriteString("collection: Spotify\n")
+	b.WriteString(fmt.Sprintf("external_id: %s\n", t.ID))
+	if t.Verb != "" {
+		b.WriteString(fmt.Sprintf("verb: %s\n", t.Verb))
+	}
+	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(t.Name+" — "+t.Artist())))
+	b.WriteString(fmt.Sprintf("artist: %s\n", cleanTitle(t.Artist())))
+	b.WriteString(fmt.Sprintf("album: %s\n", cleanTitle(t.Album)))
+	if !t.LastPlayed.IsZero() {
+		b.WriteString(fmt.Sprintf("played_at: %d\n", t.LastPlayed.Unix()))
+	}
+	if t.Plays > 0 {
+		b.WriteString(fmt.Sprintf("plays: %d\n", t.Plays))
+	}
+	b.WriteString(fmt.Sprintf("url: %s\n", t.URL))
+	b.WriteString("---\n\n")
+
+	// Body
+	b.WriteString(fmt.Sprintf("- **Track:** [%s](%s)\n", t.Name, t.URL))
+	b.WriteString(fmt.Sprintf("- **Artist:** %s\n", t.Artist()))
+	b.WriteString(fmt.Sprintf("- **Album:** %s\n", t.Album))
+	b.WriteString(fmt.Sprintf("- **Length:** %s\n", formatStravaDuration(t.DurationMs/1000)))
+	if !t.LastPlayed.IsZero() {
+		b.WriteString(fmt.Sprintf("- **Played:** %s\n", t.LastPlayed.In(displayLocation).Format("2006-01-02 15:04")))
+	}
+	if !t.SavedAt.IsZero() {
+		b.WriteString(fmt.Sprintf("- **Saved:** %s\n", t.SavedAt.In(displayLocation).Format("2006-01-02 15:04")))
+	}
+
+	return filterFrontmatter(b.String())
+}
+
+// SpotifySyncer handles syncing Spotify listening history
+type SpotifySyncer struct {
+	client *http.Client
+	db     *bolt.DB
+	mode   string // recent, saved, both
+
+	unchangedCount // for /status
+}
+
+// NewSpotifySyncer creates a new syncer
+func NewSpotifySyncer(tokens *SpotifyTokens, dataDir string) (*SpotifySyncer, error) {
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "spotify.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(spotifyBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(spotifyMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+		Expiry:       tokens.Expiry,
+	}
+
+	// Spotify may rotate refresh tokens, so persist whatever the token source hands back
+	ts := newSavingTokenSource(getSpotifyOAuthConfig().TokenSource(context.Background(), token), tokens.AccessToken, func(t *oauth2.Token) error {
+		return saveSpotifyTokens(SpotifyTokens{
+			AccessToken:  t.AccessToken,
+			RefreshToken: t.RefreshToken,
+			TokenType:    t.TokenType,
+			Expiry:       t.Expiry,
+			User:         tokens.User,
+		})
+	})
+
+	client := oauth2.NewClient(context.Background(), ts)
+	client.Timeout = 30 * time.Second
+
+	return &SpotifySyncer{
+		client: client,
+		db:     db,
+		mode:   "recent",
+	}, nil
+}
+
+// Close closes the database
+func (s *SpotifySyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of cached tracks
+func (s *SpotifySyncer) CachedCount() (int, error) {
+	return countBucket(s.db, spotifyBucket)
+}
+
+// ClearCache clears all cached tracks and the play cursor
+func (s *SpotifySyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket([]byte(spotifyMetaBucket)); meta != nil {
+			if err := meta.Delete([]byte("last_played_at")); err != nil {
+				return err
+			}
+		}
+
+		b := tx.Bucket([]byte(spotifyBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SpotifySyncResult contains sync statistics
+type SpotifySyncResult struct {
+	Played    []SpotifyTrack
+	Saved     []SpotifyTrack
+	Unchanged int
+	Errors    []error
+}
+
+// SyncChanges fetches new plays and/or saves depending on the mode
+func (s *SpotifySyncer) SyncChanges(ctx context.Context) (*SpotifySyncResult, error) {
+	result := &SpotifySyncResult{
+		Played: make([]SpotifyTrack, 0),
+		Saved:  make([]SpotifyTrack, 0),
+		Errors: make([]error, 0),
+	}
+
+	if s.mode == "recent" || s.mode == "both" {
+		if err := s.syncRecentlyPlayed(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+	if s.mode == "saved" || s.mode == "both" {
+		if err := s.syncSaved(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// spotifyAPITrack is the subset of the track object we use
+type spotifyAPITrack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	DurationMs int    `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+	ExternalURLs struct {
+		Spotify string `json:"spotify"`
+	} `json:"external_urls"`
+}
+
+// syncRecentlyPlayed records plays since the stored cursor, oldest first.
+// Spotify only keeps the last 50 plays, so anything older than that between
+// syncs is gone - the 15 minute interval keeps well inside it.
+func (s *SpotifySyncer) syncRecentlyPlayed(ctx context.Context, result *SpotifySyncResult) error {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(spotifyPageSize))
+	if after := s.getLastPlayed(); !after.IsZero() {
+		params.Set("after", strconv.FormatInt(after.UnixMilli(), 10))
+	}
+
+	var resp struct {
+		Items []struct {
+			Track    spotifyAPITrack `json:"track"`
+			PlayedAt time.Time       `json:"played_at"`
+		} `json:"items"`
+	}
+	if err := s.get(ctx, "/me/player/recently-played?"+params.Encode(), &resp); err != nil {
+		return fmt.Errorf("failed to list recently played: %w", err)
+	}
+
+	var latest time.Time
+	for i := len(resp.Items) - 1; i >= 0; i-- {
+		play := resp.Items[i]
+		if play.Track.ID == "" {
+			continue // local files have no ID
+		}
+
+		track, err := s.recordPlay(convertSpotifyTrack(play.Track), play.PlayedAt)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+		track.Verb = "played"
+		result.Played = append(result.Played, track)
+
+		if play.PlayedAt.After(latest) {
+			latest = play.PlayedAt
+		}
+	}
+
+	if !latest.IsZero() {
+		if err := s.setLastPlayed(latest); err != nil {
+			logger.Warn("failed to save Spotify play cursor", "error", err)
+		}
+	}
+	return nil
+}
+
+// syncSaved pages through liked songs newest-first until a page has nothing new
+func (s *SpotifySyncer) syncSaved(ctx context.Context, result *SpotifySyncResult) error {
+	for page := 0; page < spotifyMaxPages; page++ {
+		params := url.Values{}
+		params.Set("limit", strconv.Itoa(spotifyPageSize))
+		params.Set("offset", strconv.Itoa(page*spotifyPageSize))
+
+		var resp struct {
+			Items []struct {
+				Track   spotifyAPITrack `json:"track"`
+				AddedAt time.Time       `json:"added_at"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+		if err := s.get(ctx, "/me/tracks?"+params.Encode(), &resp); err != nil {
+			return fmt.Errorf("failed to list saved tracks: %w", err)
+		}
+
+		created := 0
+		for _, saved := range resp.Items {
+			track, isNew, err := s.recordSave(convertSpotifyTrack(saved.Track), saved.AddedAt)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				continue
+			}
+			if !isNew {
+				result.Unchanged++
+				continue
+			}
+			track.Verb = "saved"
+			result.Saved = append(result.Saved, track)
+			created++
+		}
+
+		if resp.Next == "" || created == 0 {
+			return nil
+		}
+	}
+
+	logger.Warn("Spotify sync hit page limit", "pages", spotifyMaxPages, "saved", len(result.Saved))
+	return nil
+}
+
+// get performs a GET against the Web API and decodes the JSON response
+func (s *SpotifySyncer) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", spotifyAPIBase+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("spotify API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func convertSpotifyTrack(t spotifyAPITrack) SpotifyTrack {
+	artists := make([]string, 0, len(t.Artists))
+	for _, a := range t.Artists {
+		artists = append(artists, a.Name)
+	}
+
+	trackURL := t.ExternalURLs.Spotify
+	if trackURL == "" {
+		trackURL = "https://open.spotify.com/track/" + t.ID
+	}
+
+	return SpotifyTrack{
+		ID:         "spotify_" + t.ID,
+		TrackID:    t.ID,
+		Name:       t.Name,
+		Artists:    artists,
+		Album:      t.Album.Name,
+		DurationMs: t.DurationMs,
+		URL:        trackURL,
+	}
+}
+
+// recordPlay stores a play, carrying the play count and save time forward
+func (s *SpotifySyncer) recordPlay(track SpotifyTrack, playedAt time.Time) (SpotifyTrack, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(spotifyBucket))
+
+		if existing := b.Get([]byte(track.ID)); existing != nil {
+			var old SpotifyTrack
+			if err := json.Unmarshal(existing, &old); err != nil {
+				return err
+			}
+			track.Plays = old.Plays
+			track.SavedAt = old.SavedAt
+		}
+		track.Plays++
+		track.LastPlayed = playedAt
+
+		data, err := json.Marshal(track)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(track.ID), data)
+	})
+
+	return track, err
+}
+
+// recordSave stores a liked song, returning false if it was already known as saved
+func (s *SpotifySyncer) recordSave(track SpotifyTrack, addedAt time.Time) (SpotifyTrack, bool, error) {
+	var isNew bool
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(spotifyBucket))
+
+		if existing := b.Get([]byte(track.ID)); existing != nil {
+			var old SpotifyTrack
+			if err := json.Unmarshal(existing, &old); err != nil {
+				return err
+			}
+			if !old.SavedAt.IsZero() {
+				return nil
+			}
+			track.Plays = old.Plays
+			track.LastPlayed = old.LastPlayed
+		}
+		track.SavedAt = addedAt
+		isNew = true
+
+		data, err := json.Marshal(track)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(track.ID), data)
+	})
+
+	return track, isNew, err
+}
+
+func (s *SpotifySyncer) getLastPlayed() time.Time {
+	var t time.Time
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(spotifyMetaBucket))
+		if v := b.Get([]byte("last_played_at")); v != nil {
+			t, _ = time.Parse(time.RFC3339Nano, string(v))
+		}
+		return nil
+	})
+	return t
+}
+
+func (s *SpotifySyncer) setLastPlayed(t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(spotifyMetaBucket))
+		return b.Put([]byte("last_played_at"), []byte(t.Format(time.RFC3339Nano)))
+	})
+}
+
+// Name implements Syncer
+func (s *SpotifySyncer) Name() string {
+	return "spotify"
+}
+
+// metaStore implements heldStore
+func (s *SpotifySyncer) metaStore() (*bolt.DB, string) {
+	return s.db, spotifyMetaBucket
+}
+
+// Sync implements Syncer: fetches new plays/saves and renders them as queue items
+func (s *SpotifySyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Spotify sync complete", "played", len(result.Played), "saved", len(result.Saved), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	s.unchanged = result.Unchanged
+
+	changes := append(result.Played, result.Saved...)
+	items := make([]QueueItem, 0, len(changes))
+	for _, track := range changes {
+		items = append(items, track.queueItem())
+	}
+	return items, nil
+}
+
+func (t SpotifyTrack) queueItem() QueueItem {
+	// Plays are stamped with when they happened so the journal entry lands at the right time
+	createdAt := time.Now()
+	sourceTime := t.SavedAt
+	if t.Verb == "played" {
+		createdAt = t.LastPlayed
+		sourceTime = t.LastPlayed
+	}
+
+	return QueueItem{
+		ID:         fmt.Sprintf("spotify-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      t.Name,
+		Content:    t.ToMarkdown(),
+		CreatedAt:  createdAt.Format(time.RFC3339),
+		Source:     "spotify",
+		ExternalID: t.ID,
+		SourceTime: sourceTime,
+		Verb:       t.Verb,
+	}
+}
+
+// Replay implements replayer: a liked track as saved, otherwise its last play
+func (s *SpotifySyncer) Replay(id string) (QueueItem, error) {
+	track, err := loadCached[SpotifyTrack](s.db, spotifyBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	track.Verb = "played"
+	if !track.SavedAt.IsZero() {
+		track.Verb = "saved"
+	}
+	return track.queueItem(), nil
+}
+
+// ReplayIDs implements replayer
+func (s *SpotifySyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, spotifyBucket)
+}
+
+// getSpotifyOAuthConfig returns the OAuth2 config for the Spotify Web API
+func getSpotifyOAuthConfig() *oauth2.Config {
+	cfg := loadConfig()
+
+	return &oauth2.Config{
+		ClientID:     cfg.SpotifyClientID,
+		ClientSecret: cfg.SpotifyClientSecret,
+		Scopes:       []string{"user-read-recently-played", "user-library-read"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://accounts.spotify.com/authorize",
+			TokenURL:  "https://accounts.spotify.com/api/token",
+			AuthStyle: oauth2.AuthStyleInHeader,
+		},
+		RedirectURL: spotifyCallbackURL,
+	}
+}
+
+// runSpotifyAuth runs the OAuth browser flow for Spotify
+func runSpotifyAuth() {
+	fmt.Println(em("🔐 Spotify Authentication"))
+	fmt.Println()
+
+	config := getSpotifyOAuthConfig()
+
+	// Check if client ID is configured
+	if config.ClientID == "" || config.ClientSecret == "" {
+		fmt.Println(em("⚠️  Spotify OAuth not configured!"))
+		fmt.Println()
+		fmt.Println("To set up Spotify sync:")
+		fmt.Println()
+		fmt.Println("1. Go to https://developer.spotify.com/dashboard and create an app (Web API)")
+		fmt.Printf("2. Add the redirect URI: %s\n", spotifyCallbackURL)
+		fmt.Println("3. Add your client ID and secret to ~/.config/tm/config:")
+		fmt.Println()
+		fmt.Println("   spotify_client_id=YOUR_CLIENT_ID")
+		fmt.Println("   spotify_client_secret=YOUR_CLIENT_SECRET")
+		fmt.Println()
+		fmt.Println("4. Run 'tm auth spotify' again")
+		os.Exit(1)
+	}
+
+	// Generate state for CSRF protection
+	state, err := generateState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating state: %v\n", err)
+		os.Exit(1)
+	}
+
+	authURL := config.AuthCodeURL(state, oauth2.SetAuthURLParam("show_dialog", "true"))
+
+	fmt.Println("Opening browser for Spotify sign-in...")
+	code, err := waitForOAuthCode(authURL, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exchange code for tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exchanging code: %v\n", err)
+		os.Exit(1)
+	}
+
+	user, err := getSpotifyUser(ctx, config, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting Spotify profile: %v\n", err)
+		os.Exit(1)
+	}
+
+	tokens := SpotifyTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		User:         user,
+	}
+
+	if err := saveSpotifyTokens(tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf(em("✅ Authenticated as %s\n"), user)
+	fmt.Println(em("✅ Token saved to ~/.config/tm/spotify.json"))
+	fmt.Println()
+	fmt.Println("Restart 'tm serve' to start syncing your listening")
+}
+
+// getSpotifyUser returns the display name of the signed-in user
+func getSpotifyUser(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", spotifyAPIBase+"/me", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := config.Client(ctx, token).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("spotify API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var me struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return "", err
+	}
+	if me.DisplayName != "" {
+		return me.DisplayName, nil
+	}
+	return me.ID, nil
+}
+
+func loadSpotifyTokens() (*SpotifyTokens, error) {
+	home, _ := os.UserHomeDir()
+	tokenPath := filepath.Join(home, ".config", "tm", "spotify.json")
+
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens SpotifyTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+func saveSpotifyTokens(tokens SpotifyTokens) error {
+	home, _ := os.UserHomeDir()
+	configDir := filepath.Join(home, ".config", "tm")
+	os.MkdirAll(configDir, 0700)
+
+	tokenPath := filepath.Join(configDir, "spotify.json")
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(tokenPath, data, 0600)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// syncStatsRuns is how many recent successful runs /status looks at per source
+const syncStatsRuns = 10
+
+// quietRatio is the unchanged share (over a full syncStatsRuns window) above
+// which /status suggests polling less often
+const quietRatio = 0.98
+
+// unchangedCounter is implemented by syncers that know how many items their
+// last Sync looked at but didn't queue because nothing had changed
+type unchangedCounter interface {
+	LastUnchanged() int
+}
+
+// unchangedCount is embedded by syncers to implement unchangedCounter; Sync
+// sets unchanged, and the scheduler reads it right after, under the run lock
+type unchangedCount struct {
+	unchanged int
+}
+
+// LastUnchanged implements unchangedCounter
+func (c *unchangedCount) LastUnchanged() int {
+	return c.unchanged
+}
+
+// runCounts is one successful run: items queued and items seen unchanged
+// (-1 when the syncer doesn't count them)
+type runCounts struct {
+	changed   int
+	unchanged int
+}
+
+// syncHistory keeps the last syncStatsRuns runs. It has its own lock so
+// /status never waits for a sync in progress.
+type syncHistory struct {
+	mu   sync.Mutex
+	runs []runCounts
+}
+
+func (h *syncHistory) add(r runCounts) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs = append(h.runs, r)
+	if len(h.runs) > syncStatsRuns {
+		h.runs = h.runs[len(h.runs)-syncStatsRuns:]
+	}
+}
+
+func (h *syncHistory) snapshot() []runCounts {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]runCounts(nil), h.runs...)
+}
+
+// SourceStatus is one source's line in GET /status
+type SourceStatus struct {
+	Source         string   `json:"source"`
+	Interval       string   `json:"interval"`
+	Runs           int      `json:"runs"`                      // successful runs counted (up to syncStatsRuns)
+	Changed        int      `json:"changed"`                   // items queued by those runs
+	Unchanged      int      `json:"unchanged,omitempty"`       // items they saw but skipped as unchanged
+	UnchangedRatio *float64 `json:"unchanged_ratio,omitempty"` // unchanged / (changed + unchanged); nil when not counted
+	Hint           string   `json:"hint,omitempty"`
+
+	Backfill *backfillProgress `json:"backfill,omitempty"` // first full import still in progress
+}
+
+// Status summarizes each syncer's recent runs, suggesting a longer interval
+// for sources that almost never change
+func (sc *Scheduler) Status() []SourceStatus {
+	entries := sc.list()
+	statuses := make([]SourceStatus, 0, len(entries))
+	for _, e := range entries {
+		st := SourceStatus{Source: e.syncer.Name(), Interval: e.period().String()}
+		counted := true
+		for _, r := range e.history.snapshot() {
+			st.Runs++
+			st.Changed += r.changed
+			if r.unchanged < 0 {
+				counted = false
+				continue
+			}
+			st.Unchanged += r.unchanged
+		}
+		if counted && st.Changed+st.Unchanged > 0 {
+			ratio := math.Round(float64(st.Unchanged)/float64(st.Changed+st.Unchanged)*1000) / 1000
+			st.UnchangedRatio = &ratio
+			if st.Runs == syncStatsRuns && ratio >= quietRatio {
+				st.Hint = fmt.Sprintf("%.1f%% unchanged over the last %d syncs; syncing every %s would likely be enough (sync_intervals=%s:%s)",
+					ratio*100, st.Runs, 2*e.period(), st.Source, 2*e.period())
+			}
+		}
+		if b, ok := e.syncer.(backfiller); ok {
+			st.Backfill = b.Backfill()
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// handleStatus reports per-source sync statistics (GET /status)
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "GET only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	pending, snoozed, inFlight := len(s.queue), s.snoozedCount(now), len(s.inflight)
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"time":      now,
+		"pending":   pending,
+		"snoozed":   snoozed,  // of pending: not due yet (--snooze)
+		"in_flight": inFlight, // sent to ?ack=true consumers, not acked yet
+		"sources":   s.scheduler.Status(),
+	})
+}
+
+// statusReport is the GET /status body, as read by tm status
+type statusReport struct {
+	Time     time.Time      `json:"time"`
+	Pending  int            `json:"pending"`
+	Snoozed  int            `json:"snoozed"`
+	InFlight int            `json:"in_flight"`
+	Sources  []SourceStatus `json:"sources"`
+}
+
+// runStatus implements `tm status [--json]`: each running sync's recent
+// runs, and how far any backfill has got
+func runStatus(args []string) {
+	asJSON := len(args) > 0 && args[0] == "--json"
+
+	config := loadConfig()
+	url := config.URL
+	if url == "" {
+		url = LocalServerURL
+	}
+	token := config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/status?token=%s", url, token))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (is 'tm serve' running?)\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", apiErrorMessage(body))
+		os.Exit(1)
+	}
+	if asJSON {
+		os.Stdout.Write(body)
+		return
+	}
+
+	var report statusReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-16s %9s %5s %8s %10s\n", "SOURCE", "INTERVAL", "RUNS", "CHANGED", "UNCHANGED")
+	for _, st := range report.Sources {
+		unchanged := "-"
+		if st.UnchangedRatio != nil {
+			unchanged = fmt.Sprint(st.Unchanged)
+		}
+		fmt.Printf("%-16s %9s %5d %8d %10s\n", st.Source, st.Interval, st.Runs, st.Changed, unchanged)
+	}
+
+	fmt.Printf("\n%d waiting in the queue", report.Pending)
+	if report.Snoozed > 0 {
+		fmt.Printf(" (%d snoozed)", report.Snoozed)
+	}
+	if report.InFlight > 0 {
+		fmt.Printf(", %d sent and waiting for an ack", report.InFlight)
+	}
+	fmt.Println()
+	for _, st := range report.Sources {
+		if b := st.Backfill; b != nil {
+			total := "?"
+			if b.Total > 0 {
+				total = fmt.Sprint(b.Total)
+			}
+			fmt.Printf("%s backfill: %d/%s %s\n", st.Source, b.Done, total, b.Unit)
+		}
+	}
+	for _, st := range report.Sources {
+		if st.Hint != "" {
+			fmt.Printf("%s: %s\n", st.Source, st.Hint)
+		}
+	}
+}
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	stravaBucket     = "strava_activities"
+	stravaMetaBucket = "strava_meta"
+	stravaAPIBase    = "https://www.strava.com/api/v3"
+	stravaPageSize   = 100
+	stravaMaxPages   = 20
+
+	// stravaInitialWindow is how far back the first sync (or a resync) looks
+	stravaInitialWindow = 30 * 24 * time.Hour
+	// stravaOverlap re-fetches recent activities so late uploads and renames are picked up
+	stravaOverlap = 24 * time.Hour
+)
+
+// StravaTokens holds OAuth tokens for the Strava API
+type StravaTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	Athlete      string    `json:"athlete,omitempty"`
+}
+
+// StravaActivity represents a stored Strava activity
+type StravaActivity struct {
+	ID            string    `json:"id"` // strava_{activityId}
+	ActivityID    int64     `json:"activity_id"`
+	Name          string    `json:"name"`
+	SportType     string    `json:"sport_type"` // Run, Ride, Swim, Hike, ...
+	Distance      float64   `json:"distance"`   // meters
+	MovingTime    int       `json:"moving_time"`
+	ElapsedTime   int       `json:"elapsed_time"`
+	ElevationGain float64   `json:"elevation_gain"` // meters
+	StartDate     time.Time `json:"start_date"`
+	StartLatLng   []float64 `json:"start_latlng,omitempty"`
+	Description   string    `json:"description"`
+	URL           string    `json:"url"`
+	Verb          string    `json:"-"` // transient: completed, updated (not stored)
+}
+
+// ToMarkdown returns the activity as markdown with YAML frontmatter
+func (a StravaActivity) ToMarkdown() string {
+	var b strings.Builder
+
+	// YAML frontmatter
+	b.WriteString("---\n")
+	b.WriteString("collection: Strava\n")
+	b.WriteString(fmt.Sprintf("external_id: %s\n", a.ID))
+	if a.Verb != "" {
+		b.WriteString(fmt.Sprintf("verb: %s\n", a.Verb))
+	}
+	b.WriteString(fmt.Sprintf("title: %s\n", cleanTitle(a.Name)))
+	b.WriteString(fmt.Sprintf("type: %s\n", a.SportType))
+	b.WriteString(fmt.Sprintf("start: %d\n", a.StartDate.Unix()))
+	b.WriteString(fmt.Sprintf("distance_km: %.2f\n", a.Distance/1000))
+	b.WriteString(fmt.Sprintf("moving_time: %s\n", formatStravaDuration(a.MovingTime)))
+	if pace := a.Pace(); pace != "" {
+		b.WriteString(fmt.Sprintf("pace: %s\n", pace))
+	}
+	if a.ElevationGain > 0 {
+		b.WriteString(fmt.Sprintf("elevation_gain_m: %.0f\n", a.ElevationGain))
+	}
+	b.WriteString(fmt.Sprintf("url: %s\n", a.URL))
+	b.WriteString("---\n\n")
+
+	// Body
+	b.WriteString(fmt.Sprintf("- **Distance:** %.2f km\n", a.Distance/1000))
+	b.WriteString(fmt.Sprintf("- **Duration:** %s", formatStravaDuration(a.MovingTime)))
+	if a.ElapsedTime > a.MovingTime {
+		b.WriteString(fmt.Sprintf(" (%s elapsed)", formatStravaDuration(a.ElapsedTime)))
+	}
+	b.WriteString("\n")
+	if pace := a.Pace(); pace != "" {
+		b.WriteString(fmt.Sprintf("- **Pace:** %s\n", pace))
+	}
+	if a.ElevationGain > 0 {
+		b.WriteString(fmt.Sprintf("- **Elevation:** %.0f m\n", a.ElevationGain))
+	}
+	b.WriteString(fmt.Sprintf("- **Map:** [View on Strava](%s)", a.URL))
+	if len(a.StartLatLng) == 2 {
+		b.WriteString(fmt.Sprintf(" · [Start](https://www.openstreetmap.org/?mlat=%f&mlon=%f#map=14/%f/%f)",
+			a.StartLatLng[0], a.StartLatLng[1], a.StartLatLng[0], a.StartLatLng[1]))
+	}
+	b.WriteString("\n")
+
+	if a.Description != "" {
+		b.WriteString("\n")
+		b.WriteString(a.Description)
+	}
+
+	return filterFrontmatter(b.String())
+}
+
+// Pace returns min/km for foot sports and km/h for everything else
+func (a StravaActivity) Pace() string {
+	if a.Distance <= 0 || a.MovingTime <= 0 {
+		return ""
+	}
+	switch a.SportType {
+	case "Run", "TrailRun", "VirtualRun", "Walk", "Hike":
+		secsPerKm := float64(a.MovingTime) / (a.Distance / 1000)
+		return fmt.Sprintf("%d:%02d /km", int(secsPerKm)/60, int(secsPerKm)%60)
+	}
+	return fmt.Sprintf("%.1f km/h", (a.Distance/1000)/(float64(a.MovingTime)/3600))
+}
+
+// formatStravaDuration renders seconds as 1h02m or 42m10s
+func formatStravaDuration(secs int) string {
+	d := time.Duration(secs) * time.Second
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), secs%60)
+}
+
+// StravaSyncer handles syncing Strava activities
+type StravaSyncer struct {
+	client *http.Client
+	db     *bolt.DB
+
+	unchangedCount // for /status
+}
+
+// NewStravaSyncer creates a new syncer
+func NewStravaSyncer(tokens *StravaTokens, dataDir string) (*StravaSyncer, error) {
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "strava.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(stravaBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(stravaMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+		Expiry:       tokens.Expiry,
+	}
+
+	// Strava rotates refresh tokens, so persist whatever the token source hands back
+	ts := newSavingTokenSource(getStravaOAuthConfig().TokenSource(context.Background(), token), tokens.AccessToken, func(t *oauth2.Token) error {
+		return saveStravaTokens(StravaTokens{
+			AccessToken:  t.AccessToken,
+			RefreshToken: t.RefreshToken,
+			TokenType:    t.TokenType,
+			Expiry:       t.Expiry,
+			Athlete:      tokens.Athlete,
+		})
+	})
+
+	client := oauth2.NewClient(context.Background(), ts)
+	client.Timeout = 30 * time.Second
+
+	return &StravaSyncer{
+		client: client,
+		db:     db,
+	}, nil
+}
+
+// Close closes the database
+func (s *StravaSyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of cached activities
+func (s *StravaSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, stravaBucket)
+}
+
+// ClearCache clears all cached activities and the sync watermark
+func (s *StravaSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket([]byte(stravaMetaBucket)); meta != nil {
+			if err := meta.Delete([]byte("last_sync")); err != nil {
+				return err
+			}
+		}
+
+		b := tx.Bucket([]byte(stravaBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// StravaSyncResult contains sync statistics
+type StravaSyncResult struct {
+	Created   []StravaActivity
+	Updated   []StravaActivity
+	Unchanged int
+	Errors    []error
+}
+
+// SyncChanges fetches activities since the last sync and returns changes
+func (s *StravaSyncer) SyncChanges(ctx context.Context) (*StravaSyncResult, error) {
+	result := &StravaSyncResult{
+		Created: make([]StravaActivity, 0),
+		Updated: make([]StravaActivity, 0),
+		Errors:  make([]error, 0),
+	}
+
+	syncStart := time.Now()
+	after := syncStart.Add(-stravaInitialWindow)
+	if last := s.getLastSync(); !last.IsZero() {
+		after = last.Add(-stravaOverlap)
+	}
+
+	activities, err := s.fetchActivities(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, activity := range activities {
+		upsertResult, err := s.upsert(activity)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+
+		activity.Verb = upsertResult.Verb
+		switch upsertResult.Action {
+		case "created":
+			result.Created = append(result.Created, activity)
+		case "updated":
+			result.Updated = append(result.Updated, activity)
+		case "unchanged":
+			result.Unchanged++
+		}
+	}
+
+	if err := s.setLastSync(syncStart); err != nil {
+		logger.Warn("failed to save Strava sync time", "error", err)
+	}
+
+	return result, nil
+}
+
+// stravaAPIActivity is the subset of /athlete/activities we use
+type stravaAPIActivity struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	SportType          string    `json:"sport_type"`
+	Type               string    `json:"type"`
+	Distance           float64   `json:"distance"`
+	MovingTime         int       `json:"moving_time"`
+	ElapsedTime        int       `json:"elapsed_time"`
+	TotalElevationGain float64   `json:"total_elevation_gain"`
+	StartDate          time.Time `json:"start_date"`
+	StartLatLng        []float64 `json:"start_latlng"`
+	Description        string    `json:"description"`
+}
+
+// fetchActivities pages through the athlete's activities started after `after`
+func (s *StravaSyncer) fetchActivities(ctx context.Context, after time.Time) ([]StravaActivity, error) {
+	var activities []StravaActivity
+
+	for page := 1; page <= stravaMaxPages; page++ {
+		params := url.Values{}
+		params.Set("after", fmt.Sprintf("%d", after.Unix()))
+		params.Set("per_page", fmt.Sprintf("%d", stravaPageSize))
+		params.Set("page", fmt.Sprintf("%d", page))
+
+		req, err := http.NewRequestWithContext(ctx, "GET", stravaAPIBase+"/athlete/activities?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list activities: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("strava API returned %d: %s", resp.StatusCode, string(body))
+		}
+
+		var apiActivities []stravaAPIActivity
+		err = json.NewDecoder(resp.Body).Decode(&apiActivities)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range apiActivities {
+			activities = append(activities, convertStravaActivity(a))
+		}
+
+		if len(apiActivities) < stravaPageSize {
+			return activities, nil
+		}
+	}
+
+	logger.Warn("Strava sync hit page limit", "pages", stravaMaxPages, "activities", len(activities))
+	return activities, nil
+}
+
+func convertStravaActivity(a stravaAPIActivity) StravaActivity {
+	sportType := a.SportType
+	if sportType == "" {
+		sportType = a.Type
+	}
+
+	return StravaActivity{
+		ID:            fmt.Sprintf("strava_%d", a.ID),
+		ActivityID:    a.ID,
+		Name:          a.Name,
+		SportType:     sportType,
+		Distance:      a.Distance,
+		MovingTime:    a.MovingTime,
+		ElapsedTime:   a.ElapsedTime,
+		ElevationGain: a.TotalElevationGain,
+		StartDate:     a.StartDate,
+		StartLatLng:   a.StartLatLng,
+		Description:   a.Description,
+		URL:           fmt.Sprintf("https://www.strava.com/activities/%d", a.ID),
+	}
+}
+
+// StravaUpsertResult contains the result of an upsert operation
+type StravaUpsertResult struct {
+	Action string // created, updated, unchanged
+	Verb   string // completed, updated
+}
+
+func (s *StravaSyncer) upsert(activity StravaActivity) (*StravaUpsertResult, error) {
+	result := &StravaUpsertResult{}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(stravaBucket))
+
+		existing := b.Get([]byte(activity.ID))
+		if existing == nil {
+			data, err := json.Marshal(activity)
+			if err != nil {
+				return err
+			}
+			result.Action = "created"
+			result.Verb = "completed"
+			return b.Put([]byte(activity.ID), data)
+		}
+
+		// Check if changed
+		var old StravaActivity
+		if err := json.Unmarshal(existing, &old); err != nil {
+			return err
+		}
+
+		if needsStravaUpdate(old, activity) {
+			data, err := json.Marshal(activity)
+			if err != nil {
+				return err
+			}
+			result.Action = "updated"
+			result.Verb = "updated"
+			return b.Put([]byte(activity.ID), data)
+		}
+
+		result.Action = "unchanged"
+		return nil
+	})
+
+	return result, err
+}
+
+func needsStravaUpdate(old, new StravaActivity) bool {
+	if old.Name != new.Name {
+		return true
+	}
+	if old.SportType != new.SportType {
+		return true
+	}
+	if old.Distance != new.Distance {
+		return true
+	}
+	if old.MovingTime != new.MovingTime {
+		return true
+	}
+	if old.Description != new.Description {
+		return true
+	}
+	return false
+}
+
+func (s *StravaSyncer) getLastSync() time.Time {
+	var t time.Time
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(stravaMetaBucket))
+		if v := b.Get([]byte("last_sync")); v != nil {
+			t, _ = time.Parse(time.RFC3339, string(v))
+		}
+		return nil
+	})
+	return t
+}
+
+func (s *StravaSyncer) setLastSync(t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(stravaMetaBucket))
+		return b.Put([]byte("last_sync"), []byte(t.Format(time.RFC3339)))
+	})
+}
+
+// Name implements Syncer
+func (s *StravaSyncer) Name() string {
+	return "strava"
+}
+
+// metaStore implements heldStore
+func (s *StravaSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, stravaMetaBucket
+}
+
+// Sync implements Syncer: fetches new activities and renders them as queue items
+func (s *StravaSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	result, err := s.SyncChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Strava sync complete", "created", len(result.Created), "updated", len(result.Updated), "unchanged", result.Unchanged, "errors", len(result.Errors))
+	s.unchanged = result.Unchanged
+
+	changes := append(result.Created, result.Updated...)
+	items := make([]QueueItem, 0, len(changes))
+	for _, activity := range changes {
+		items = append(items, activity.queueItem())
+	}
+	return items, nil
+}
+
+func (a StravaActivity) queueItem() QueueItem {
+	return QueueItem{
+		ID:         fmt.Sprintf("strava-%d", time.Now().UnixNano()),
+		Action:     "append",
+		Title:      a.Name,
+		Content:    a.ToMarkdown(),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Source:     "strava",
+		ExternalID: a.ID,
+		SourceTime: a.StartDate,
+		Verb:       a.Verb,
+	}
+}
+
+// Replay implements replayer: the cached activity as completed
+func (s *StravaSyncer) Replay(id string) (QueueItem, error) {
+	activity, err := loadCached[StravaActivity](s.db, stravaBucket, id)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	activity.Verb = "completed"
+	return activity.queueItem(), nil
+}
+
+// ReplayIDs implements replayer
+func (s *StravaSyncer) ReplayIDs() ([]string, error) {
+	return cachedIDs(s.db, stravaBucket)
+}
+
+// getStravaOAuthConfig returns the OAuth2 config for the Strava API
+func getStravaOAuthConfig() *oauth2.Config {
+	cfg := loadConfig()
+
+	return &oauth2.Config{
+		ClientID:     cfg.StravaClientID,
+		ClientSecret: cfg.StravaClientSecret,
+		Scopes:       []string{"activity:read_all"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://www.strava.com/oauth/authorize",
+			TokenURL:  "https://www.strava.com/oauth/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		RedirectURL: OAuthCallbackURL,
+	}
+}
+
+// runStravaAuth runs the OAuth browser flow for Strava
+func runStravaAuth() {
+	fmt.Println(em("🔐 Strava Authentication"))
+	fmt.Println()
+
+	config := getStravaOAuthConfig()
+
+	// Check if client ID is configured
+	if config.ClientID == "" || config.ClientSecret == "" {
+		fmt.Println(em("⚠️  Strava OAuth not configured!"))
+		fmt.Println()
+		fmt.Println("To set up Strava sync:")
+		fmt.Println()
+		fmt.Println("1. Go to https://www.strava.com/settings/api and create an application")
+		fmt.Println("2. Set the Authorization Callback Domain to: localhost")
+		fmt.Println("3. Add your client ID and secret to ~/.config/tm/config:")
+		fmt.Println()
+		fmt.Println("   strava_client_id=12345")
+		fmt.Println("   strava_client_secret=YOUR_CLIENT_SECRET")
+		fmt.Println()
+		fmt.Println("4. Run 'tm auth strava' again")
+		os.Exit(1)
+	}
+
+	// Generate state for CSRF protection
+	state, err := generateState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating state: %v\n", err)
+		os.Exit(1)
+	}
+
+	authURL := config.AuthCodeURL(state, oauth2.SetAuthURLParam("approval_prompt", "force"))
+
+	fmt.Println("Opening browser for Strava sign-in...")
+	code, err := waitForOAuthCode(authURL, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exchange code for tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exchanging code: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The token response carries the athlete profile
+	var athlete string
+	if a, ok := token.Extra("athlete").(map[string]interface{}); ok {
+		athlete = strings.TrimSpace(fmt.Sprintf("%v %v", a["firstname"], a["lastname"]))
+	}
+
+	tokens := StravaTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		Athlete:      athlete,
+	}
+
+	if err := saveStravaTokens(tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf(em("✅ Authenticated as %s\n"), athlete)
+	fmt.Println(em("✅ Token saved to ~/.config/tm/strava.json"))
+	fmt.Println()
+	fmt.Println("Restart 'tm serve' to start syncing activities")
+}
+
+func loadStravaTokens() (*StravaTokens, error) {
+	home, _ := os.UserHomeDir()
+	tokenPath := filepath.Join(home, ".config", "tm", "strava.json")
+
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens StravaTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+func saveStravaTokens(tokens StravaTokens) error {
+	home, _ := os.UserHomeDir()
+	configDir := filepath.Join(home, ".config", "tm")
+	os.MkdirAll(configDir, 0700)
+
+	tokenPath := filepath.Join(configDir, "strava.json")
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(tokenPath, data, 0600)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// streamChunkSize is the target size of each --stream-large part. Parts end on
+// a line boundary unless a single line is longer than this.
+const streamChunkSize = 256 << 10
+
+// streamStdin sends r as sequential parts while it is still being read, so huge
+// pipes never sit in memory whole and delivery starts before EOF. The parts
+// share an external_id: with a collection, parts after the first append to the
+// record part 1 created; on the journal they continue verbatim (raw).
+func streamStdin(config Config, req QueueItem, r io.Reader) (parts, total int, err error) {
+	req.ExternalID = fmt.Sprintf("stdin_%d", time.Now().UnixNano())
+	req.Upsert = req.Collection != ""
+
+	reader := bufio.NewReaderSize(r, 64<<10)
+	buf := make([]byte, 0, streamChunkSize)
+
+	send := func() error {
+		parts++
+		part := req
+		part.Part = parts
+		part.Content = string(buf)
+		part.CreatedAt = time.Now().Format(time.RFC3339)
+		if parts > 1 && part.Collection == "" {
+			part.Raw = true
+		}
+		if err := sendToQueue(config, part); err != nil {
+			return fmt.Errorf("part %d: %w", parts, err)
+		}
+		total += len(buf)
+		buf = buf[:0]
+		return nil
+	}
+
+	for {
+		line, readErr := reader.ReadSlice('\n')
+		buf = append(buf, line...)
+
+		if len(buf) >= streamChunkSize {
+			if err := send(); err != nil {
+				return parts, total, err
+			}
+		}
+
+		if errors.Is(readErr, bufio.ErrBufferFull) {
+			continue
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return parts, total, fmt.Errorf("reading stdin: %w", readErr)
+		}
+	}
+
+	if len(buf) > 0 {
+		if err := send(); err != nil {
+			return parts, total, err
+		}
+	}
+	return parts, total, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"time"
+	_ "time/tzdata" // timezone= works on hosts without a zoneinfo database (slim containers)
+)
+
+// displayLocation is the zone for every time shown to the user: plan-my-day
+// times and "today", Spotify play times, quiet hours, and `tm log`.
+// timezone= overrides the system zone for servers that don't run where you live.
+var displayLocation = time.Local
+
+// setDisplayLocation applies timezone= from the config
+func setDisplayLocation(config Config) {
+	if config.Timezone == "" {
+		return
+	}
+	loc, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		logger.Warn("ignoring timezone", "timezone", config.Timezone, "error", err)
+		return
+	}
+	displayLocation = loc
+}
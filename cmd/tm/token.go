@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// serverTokens returns the tokens tm serve accepts: token (or the local dev
+// default) and, while a rotation is in progress, token_next
+func serverTokens(config Config) (token, next string) {
+	token = config.Token
+	if token == "" {
+		token = "local-dev-token"
+	}
+	return token, config.TokenNext
+}
+
+// setTokens replaces the accepted tokens (startup and POST /token/reload)
+func (s *Server) setTokens(token, next string) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	s.token = token
+	s.tokenNext = next
+}
+
+// handleTokenReload re-reads token and token_next from the config, so a
+// rotation takes effect without restarting tm serve (POST /token/reload)
+func (s *Server) handleTokenReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "POST only")
+		return
+	}
+
+	if !s.checkAuth(r) {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	token, next := serverTokens(loadConfig())
+	s.setTokens(token, next)
+	logger.Info("tokens reloaded", "token_next", next != "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "token_next": next != ""})
+}
+
+// runToken implements `tm token rotate [new-token]`. The first run adds
+// token_next, which the server accepts alongside token while clients move
+// over; the second promotes it to token and drops the old one.
+func runToken(args []string) {
+	if len(args) == 0 || args[0] != "rotate" || len(args) > 2 {
+		fmt.Println("Usage: tm token rotate [new-token]")
+		return
+	}
+
+	if os.Getenv("THYMER_TOKEN") != "" {
+		fmt.Fprintln(os.Stderr, "Error: THYMER_TOKEN is set, and it overrides token= in the config; rotate it where it's set")
+		os.Exit(1)
+	}
+	current, ok, err := readConfigValue("token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, fromFile, _ := readConfigValue("token_file"); fromFile && !ok {
+		fmt.Fprintln(os.Stderr, "Error: the token comes from token_file; rotate it in that file")
+		os.Exit(1)
+	}
+	if strings.Contains(current, ",") {
+		fmt.Fprintln(os.Stderr, "Error: tm token rotate doesn't support one token per target; edit token= by hand")
+		os.Exit(1)
+	}
+
+	next, rotating, err := readConfigValue("token_next")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !rotating {
+		next = ""
+		if len(args) == 2 {
+			next = args[1]
+		}
+		if next == "" {
+			if next, err = generateState(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating token: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := writeConfigValue("token_next", next, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		reloadServerTokens()
+		fmt.Printf(em("✓ Added token_next=%s\n"), next)
+		fmt.Println()
+		fmt.Println("The server now accepts both tokens. Give the new one to every client")
+		fmt.Println("(plugin settings, shortcuts, other machines), then run 'tm token rotate'")
+		fmt.Println("again to make it the only token.")
+		return
+	}
+
+	if len(args) == 2 {
+		fmt.Fprintln(os.Stderr, "Error: a rotation is already in progress; run 'tm token rotate' without a token to promote token_next")
+		os.Exit(1)
+	}
+	if err := writeConfigValue("token", next, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeConfigValue("token_next", "", true); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	reloadServerTokens()
+	fmt.Println(em("✓ Promoted token_next to token; the old token no longer works"))
+}
+
+// reloadServerTokens asks a running server to pick up the new tokens. Without
+// one there's nothing to do: tm serve reads them when it starts.
+func reloadServerTokens() {
+	if !serverRunning() {
+		return
+	}
+
+	// The server serverRunning found, whatever url= points the other commands at
+	token, _ := serverTokens(loadConfig())
+	req, err := http.NewRequest("POST", LocalServerURL+"/token/reload", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, em("⚠️  Couldn't reach tm serve (%v); restart it to apply the change\n"), err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, em("⚠️  tm serve didn't reload (%s); restart it to apply the change\n"), apiErrorMessage(body))
+		return
+	}
+	fmt.Println(em("✓ tm serve reloaded its tokens"))
+}
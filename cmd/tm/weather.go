@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	weatherBucket     = "weather_days"
+	weatherMetaBucket = "weather_meta"
+
+	// openMeteoURL is Open-Meteo's forecast API: free, no key needed
+	openMeteoURL = "https://api.open-meteo.com/v1/forecast"
+
+	// weatherLogHour is the earliest local hour the day's entry is logged, so it
+	// describes the day rather than the conditions at midnight
+	weatherLogHour = 8
+)
+
+// WeatherEntry is one logged day, keyed by its date in weatherBucket
+type WeatherEntry struct {
+	Date        string    `json:"date"` // 2006-01-02 in the display zone
+	Temperature float64   `json:"temperature"`
+	Unit        string    `json:"unit"` // °C
+	Code        int       `json:"code"` // WMO weather interpretation code
+	LoggedAt    time.Time `json:"logged_at"`
+}
+
+// Lifelog renders the entry as a one-line lifelog, e.g. "☀️ 18°C, clear in Lisbon"
+func (e WeatherEntry) Lifelog(place string) string {
+	icon, description := weatherCondition(e.Code)
+	line := fmt.Sprintf("%s %d%s, %s", icon, int(math.Round(e.Temperature)), e.Unit, description)
+	if place != "" {
+		line += " in " + place
+	}
+	return line
+}
+
+// weatherCondition maps a WMO weather code to an icon and a short description
+func weatherCondition(code int) (string, string) {
+	switch {
+	case code == 0:
+		return "☀️", "clear"
+	case code == 1:
+		return "🌤️", "mainly clear"
+	case code == 2:
+		return "⛅", "partly cloudy"
+	case code == 3:
+		return "☁️", "overcast"
+	case code == 45 || code == 48:
+		return "🌫️", "fog"
+	case code >= 51 && code <= 57:
+		return "🌦️", "drizzle"
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return "🌧️", "rain"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "🌨️", "snow"
+	case code >= 95:
+		return "⛈️", "thunderstorm"
+	}
+	return "🌡️", "unknown conditions"
+}
+
+// parseWeatherLocation parses weather_location=lat,lon[,place]
+func parseWeatherLocation(s string) (lat, lon float64, place string, err error) {
+	parts := strings.SplitN(s, ",", 3)
+	if len(parts) < 2 {
+		return 0, 0, "", fmt.Errorf("weather_location %q: want lat,lon[,place]", s)
+	}
+	if lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || lat < -90 || lat > 90 {
+		return 0, 0, "", fmt.Errorf("weather_location %q: invalid latitude", s)
+	}
+	if lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || lon < -180 || lon > 180 {
+		return 0, 0, "", fmt.Errorf("weather_location %q: invalid longitude", s)
+	}
+	if len(parts) == 3 {
+		place = strings.TrimSpace(parts[2])
+	}
+	return lat, lon, place, nil
+}
+
+// WeatherSyncer logs the current conditions at one location once a day
+type WeatherSyncer struct {
+	client *http.Client
+	db     *bolt.DB
+	lat    float64
+	lon    float64
+	place  string // shown as "in <place>" ("" = omitted)
+}
+
+// NewWeatherSyncer creates a new syncer
+func NewWeatherSyncer(lat, lon float64, place, dataDir string) (*WeatherSyncer, error) {
+	// Open bbolt database
+	dbPath := filepath.Join(dataDir, "weather.db")
+	db, err := openBolt(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Create buckets
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(weatherBucket)); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(weatherMetaBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &WeatherSyncer{
+		client: &http.Client{Timeout: 30 * time.Second},
+		db:     db,
+		lat:    lat,
+		lon:    lon,
+		place:  place,
+	}, nil
+}
+
+// Close closes the database
+func (s *WeatherSyncer) Close() error {
+	return s.db.Close()
+}
+
+// CachedCount implements Syncer: the number of logged days
+func (s *WeatherSyncer) CachedCount() (int, error) {
+	return countBucket(s.db, weatherBucket)
+}
+
+// ClearCache forgets every logged day, so today is logged again on the next sync
+func (s *WeatherSyncer) ClearCache() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(weatherBucket))
+		if b == nil {
+			return nil
+		}
+
+		var keysToDelete [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keysToDelete = append(keysToDelete, k)
+			return nil
+		})
+
+		for _, k := range keysToDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Prune implements pruner: drops days logged before cutoff
+func (s *WeatherSyncer) Prune(cutoff time.Time) (int, error) {
+	return pruneBucket(s.db, weatherBucket, func(v []byte) bool {
+		var entry WeatherEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return false
+		}
+		return entry.LoggedAt.Before(cutoff)
+	})
+}
+
+// logged reports whether day already has an entry
+func (s *WeatherSyncer) logged(day string) bool {
+	var found bool
+	s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket([]byte(weatherBucket)).Get([]byte(day)) != nil
+		return nil
+	})
+	return found
+}
+
+func (s *WeatherSyncer) store(entry WeatherEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(weatherBucket)).Put([]byte(entry.Date), data)
+	})
+}
+
+// openMeteoResponse is the subset of the forecast response we use
+type openMeteoResponse struct {
+	Current struct {
+		Temperature float64 `json:"temperature_2m"`
+		WeatherCode int     `json:"weather_code"`
+	} `json:"current"`
+	CurrentUnits struct {
+		Temperature string `json:"temperature_2m"`
+	} `json:"current_units"`
+}
+
+// fetchCurrent asks Open-Meteo for the current temperature and weather code
+func (s *WeatherSyncer) fetchCurrent(ctx context.Context) (*openMeteoResponse, error) {
+	params := url.Values{}
+	params.Set("latitude", strconv.FormatFloat(s.lat, 'f', -1, 64))
+	params.Set("longitude", strconv.FormatFloat(s.lon, 'f', -1, 64))
+	params.Set("current", "temperature_2m,weather_code")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", openMeteoURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch weather: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("open-meteo API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var apiResp openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// Name implements Syncer
+func (s *WeatherSyncer) Name() string {
+	return "weather"
+}
+
+// metaStore implements heldStore
+func (s *WeatherSyncer) metaStore() (*bolt.DB, string) {
+	return s.db, weatherMetaBucket
+}
+
+// Sync implements Syncer: once per day, after weatherLogHour, queues a lifelog
+// entry with the current conditions. Other runs are no-ops.
+func (s *WeatherSyncer) Sync(ctx context.Context) ([]QueueItem, error) {
+	now := time.Now().In(displayLocation)
+	day := now.Format("2006-01-02")
+	if now.Hour() < weatherLogHour || s.logged(day) {
+		return nil, nil
+	}
+
+	current, err := s.fetchCurrent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := WeatherEntry{
+		Date:        day,
+		Temperature: current.Current.Temperature,
+		Unit:        current.CurrentUnits.Temperature,
+		Code:        current.Current.WeatherCode,
+		LoggedAt:    now,
+	}
+	if err := s.store(entry); err != nil {
+		return nil, err
+	}
+
+	content := entry.Lifelog(s.place)
+	logger.Debug("Weather logged", "day", day, "entry", content)
+
+	return []QueueItem{{
+		ID:         fmt.Sprintf("weather-%d", time.Now().UnixNano()),
+		Action:     "lifelog",
+		Content:    content,
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Source:     "weather",
+		ExternalID: "weather_" + day,
+		SourceTime: now,
+		Verb:       "logged",
+	}}, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v66/github"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// readwiseAuthURL answers 204 for a valid token; Readwise exposes no account details
+const readwiseAuthURL = "https://readwise.io/api/v2/auth/"
+
+// whoamiResult is one provider's line in `tm whoami`
+type whoamiResult struct {
+	provider string
+	identity string // account, or "" if unknown
+	err      error  // nil = credentials work
+	missing  string // set when not configured: how to configure it
+}
+
+// runWhoami prints the account behind each provider and whether its credentials work
+func runWhoami() {
+	config := loadConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	results := []whoamiResult{
+		whoamiGoogle(ctx),
+		whoamiGitHub(ctx, config.GitHubToken),
+		whoamiReadwise(ctx, config.ReadwiseToken),
+	}
+
+	for _, r := range results {
+		switch {
+		case r.missing != "":
+			fmt.Printf("  %-9s not configured (%s)\n", r.provider, r.missing)
+		case r.err != nil:
+			fmt.Printf(em("✗ %-9s %s: %v\n"), r.provider, orUnknown(r.identity), r.err)
+		default:
+			fmt.Printf(em("✓ %-9s %s\n"), r.provider, orUnknown(r.identity))
+		}
+	}
+}
+
+func orUnknown(identity string) string {
+	if identity == "" {
+		return "(account unknown)"
+	}
+	return identity
+}
+
+// whoamiGoogle reads the email saved in google.json, then confirms the token
+// still works by looking up the primary calendar (whose ID is the account email)
+func whoamiGoogle(ctx context.Context) whoamiResult {
+	r := whoamiResult{provider: "Google"}
+	tokens, err := loadGoogleTokens()
+	if err != nil {
+		r.missing = "run 'tm auth google'"
+		return r
+	}
+	r.identity = tokens.Email
+
+	token := &oauth2.Token{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+		Expiry:       tokens.Expiry,
+	}
+	srv, err := calendar.NewService(ctx, option.WithTokenSource(getGoogleOAuthConfig().TokenSource(ctx, token)))
+	if err != nil {
+		r.err = err
+		return r
+	}
+	cal, err := srv.CalendarList.Get("primary").Context(ctx).Do()
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if r.identity == "" {
+		r.identity = cal.Id
+	}
+	return r
+}
+
+// whoamiGitHub resolves the token's login with Users.Get
+func whoamiGitHub(ctx context.Context, token string) whoamiResult {
+	r := whoamiResult{provider: "GitHub"}
+	if token == "" {
+		r.missing = "set github_token"
+		return r
+	}
+
+	user, _, err := github.NewClient(nil).WithAuthToken(token).Users.Get(ctx, "")
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.identity = user.GetLogin()
+	if name := user.GetName(); name != "" {
+		r.identity += " (" + name + ")"
+	}
+	return r
+}
+
+// whoamiReadwise checks the token; the account itself isn't available from the API
+func whoamiReadwise(ctx context.Context, token string) whoamiResult {
+	r := whoamiResult{provider: "Readwise"}
+	if token == "" {
+		r.missing = "set readwise_token"
+		return r
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", readwiseAuthURL, nil)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	req.Header.Set("Authorization", "Token "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		r.err = fmt.Errorf("readwise API returned %d", resp.StatusCode)
+		return r
+	}
+	r.identity = "token valid (Readwise doesn't expose the account)"
+	return r
+}
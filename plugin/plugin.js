@@ -118,9 +118,10 @@ class Plugin extends AppPlugin {
     }
 
     startStream() {
-        // Build URL with token as query param (EventSource can't set headers)
-        const streamUrl = `${this.queueUrl}/stream` +
-            (this.queueToken ? `?token=${this.queueToken}` : '');
+        // Build URL with token as query param (EventSource can't set headers).
+        // ack=true: the server keeps each item until we POST /ack, so a reload mid-write doesn't lose it
+        const streamUrl = `${this.queueUrl}/stream?ack=true` +
+            (this.queueToken ? `&token=${this.queueToken}` : '');
 
         this.eventSource = new EventSource(streamUrl);
 
@@ -131,9 +132,15 @@ class Plugin extends AppPlugin {
         this.eventSource.onmessage = (event) => {
             try {
                 const data = JSON.parse(event.data);
-                if (data.content || data.markdown) {
-                    this.handleQueueItem(data);
+                if (!data.content && !data.markdown) {
+                    this.ackItem(data.id);
+                    return;
                 }
+                // Not acked on failure: the server sends it again when the lease runs out
+                this.handleQueueItem(data).then(
+                    () => this.ackItem(data.id),
+                    (e) => console.error(`[tm] failed to handle request_id=${data.requestId}:`, e),
+                );
             } catch (e) {
                 console.error('Failed to parse SSE message:', e);
             }
@@ -183,6 +190,24 @@ class Plugin extends AppPlugin {
         }
     }
 
+    // ackItem confirms an item from the ack=true stream was written
+    async ackItem(id) {
+        if (!id) return;
+        try {
+            const response = await fetch(`${this.queueUrl}/ack?id=${encodeURIComponent(id)}`, {
+                method: 'POST',
+                headers: {
+                    'Authorization': `Bearer ${this.queueToken}`
+                }
+            });
+            if (!response.ok) {
+                console.warn(`[tm] ack ${id} failed: ${response.status}`);
+            }
+        } catch (e) {
+            console.warn(`[tm] ack ${id} failed:`, e);
+        }
+    }
+
     async triggerReadwiseSync() {
         try {
             const response = await fetch(`${this.queueUrl}/readwise-sync`, {
@@ -200,9 +225,16 @@ class Plugin extends AppPlugin {
                 });
             } else {
                 const text = await response.text();
+                // API errors are {"error":{"code":"...","message":"..."}}
+                let message = text;
+                try {
+                    message = JSON.parse(text).error?.message || text;
+                } catch (e) {
+                    // not JSON (e.g. a proxy error page): show it as is
+                }
                 this.ui.addToaster({
                     title: '📚 Readwise',
-                    message: `Sync failed: ${text}`,
+                    message: `Sync failed: ${message}`,
                     dismissible: true,
                     autoDestroyTime: 3000,
                 });
@@ -274,23 +306,65 @@ class Plugin extends AppPlugin {
         const rawContent = data.content || data.markdown || '';
         const action = data.action || 'append';
         const cliTimestamp = data.createdAt ? new Date(data.createdAt) : new Date();
+        if (data.requestId) {
+            console.log(`[tm] received request_id=${data.requestId} action=${action}`);
+        }
         const timeStr = cliTimestamp.toLocaleTimeString('en-US', { hour: '2-digit', minute: '2-digit', hour12: false });
 
         // Check for frontmatter - universal interface for all integrations
         const { meta, body } = this.parseFrontmatter(rawContent);
         const hasFrontmatter = Object.keys(meta).length > 0;
         const content = hasFrontmatter ? body : rawContent;
+        // contentType text: insert every line as typed, no markdown parsing (frontmatter content stays markdown)
+        const literal = data.contentType === 'text' && !hasFrontmatter;
+
+        // upsert: find-or-create by the top-level external_id, so re-syncs update instead of duplicating
+        if (data.upsert && data.external_id) {
+            meta.external_id = data.external_id;
+        }
+        // --stream-large: parts after the first append to the record part 1 created
+        if (data.part) {
+            meta.part = data.part;
+        }
+
+        // append-to-record: the sender named the exact record, so it wins over any routing
+        if (action === 'append-to-record' && data.recordId) {
+            const record = await this.findRecordByGuid(data.recordId);
+            if (!record) {
+                this.ui.addToaster({
+                    title: '🪄 Error',
+                    message: `Record ${data.recordId} not found`,
+                    dismissible: true,
+                    autoDestroyTime: 3000,
+                });
+                return;
+            }
+            await this.insertMarkdown(data.raw ? content : `**${timeStr}** ${content.trim()}`, record, null, literal);
+            this.ui.addToaster({
+                title: '🪄 Appended',
+                message: `${content.length} bytes to "${record.getName() || data.recordId}"`,
+                dismissible: true,
+                autoDestroyTime: 2000,
+            });
+            return;
+        }
 
         // If frontmatter specifies a collection, route there
         if (hasFrontmatter && meta.collection) {
-            await this.handleFrontmatterItem(data.title || meta.title, meta, body);
+            await this.handleFrontmatterItem(data.title || meta.title, meta, body, { createCollection: data.createCollection, onMissingCollection: data.on_missing_collection });
             return;
         }
 
         // If CLI passed --collection flag, route there (non-frontmatter content)
         if (data.collection) {
             const syntheticMeta = { collection: data.collection };
-            await this.handleFrontmatterItem(data.title, syntheticMeta, content);
+            if (data.upsert && data.external_id) {
+                syntheticMeta.external_id = data.external_id;
+            }
+            if (data.part) {
+                syntheticMeta.part = data.part;
+            }
+            await this.handleFrontmatterItem(data.title, syntheticMeta, content, { createCollection: data.createCollection, onMissingCollection: data.on_missing_collection, literal });
             return;
         }
 
@@ -307,9 +381,37 @@ class Plugin extends AppPlugin {
             return;
         }
 
+        // --raw: the sender formatted this exactly - insert it verbatim, no timestamp or layout heuristics
+        if (data.raw) {
+            const parent = data.section ? await this.findOrCreateSection(journalRecord, data.section) : null;
+            await this.insertMarkdown(content, journalRecord, parent, literal);
+            this.ui.addToaster({
+                title: '🪄 Raw',
+                message: `${content.length} bytes to Journal`,
+                dismissible: true,
+                autoDestroyTime: 2000,
+            });
+            return;
+        }
+
+        // --section: append under a named heading of today's page, creating it if needed
+        if (data.section && action === 'append') {
+            const section = await this.findOrCreateSection(journalRecord, data.section);
+            if (section) {
+                await this.insertMarkdown(`**${timeStr}** ${content.trim()}`, journalRecord, section, literal);
+                this.ui.addToaster({
+                    title: `🪄 ${data.section}`,
+                    message: content.slice(0, 50),
+                    dismissible: true,
+                    autoDestroyTime: 2000,
+                });
+                return;
+            }
+        }
+
         // Handle lifelog action specially
         if (action === 'lifelog') {
-            await this.insertMarkdown(`**${timeStr}** ${content}`, journalRecord);
+            await this.insertMarkdown(`**${timeStr}** ${content}`, journalRecord, null, literal);
             this.ui.addToaster({
                 title: '🪄 Lifelog',
                 message: `${timeStr} ${content.slice(0, 40)}${content.length > 40 ? '...' : ''}`,
@@ -322,8 +424,8 @@ class Plugin extends AppPlugin {
         // Detect content type
         const lines = content.split('\n').filter(l => l.trim() !== '');
         const isOneLiner = lines.length === 1;
-        const isShort = lines.length >= 2 && lines.length <= 5 && !content.trim().startsWith('# ');
-        const isMarkdownDoc = content.trim().startsWith('# ');
+        const isShort = lines.length >= 2 && lines.length <= 5 && (literal || !content.trim().startsWith('# '));
+        const isMarkdownDoc = !literal && content.trim().startsWith('# ');
 
         if (isOneLiner) {
             // One-liner: simple append with timestamp
@@ -336,7 +438,7 @@ class Plugin extends AppPlugin {
             });
         } else if (isShort) {
             // Short content (2-5 lines): first line as parent, rest as children
-            await this.appendShortNote(journalRecord, timeStr, lines);
+            await this.appendShortNote(journalRecord, timeStr, lines, literal);
             this.ui.addToaster({
                 title: '🪄 Note added',
                 message: `${lines.length} lines to Journal`,
@@ -369,7 +471,7 @@ class Plugin extends AppPlugin {
             const firstLine = lines[0];
             const restLines = content.split('\n').slice(1).join('\n');
             const timestampedContent = `**${timeStr}** ${firstLine}\n${restLines}`;
-            await this.insertMarkdown(timestampedContent, journalRecord);
+            await this.insertMarkdown(timestampedContent, journalRecord, null, literal);
             this.ui.addToaster({
                 title: '🪄 Content added',
                 message: `${lines.length} lines to Journal`,
@@ -379,6 +481,50 @@ class Plugin extends AppPlugin {
         }
     }
 
+    async createCollection(name) {
+        if (typeof this.data.createCollection !== 'function') {
+            console.warn('This Thymer version cannot create collections from plugins');
+            return null;
+        }
+        try {
+            const collection = await this.data.createCollection();
+            if (!collection) return null;
+            const conf = collection.getConfiguration();
+            conf.name = name;
+            await collection.saveConfiguration(conf);
+            this.ui.addToaster({
+                title: '🪄 Collection created',
+                message: name,
+                dismissible: true,
+                autoDestroyTime: 2000,
+            });
+            return collection;
+        } catch (e) {
+            console.error('Error creating collection:', name, e);
+            return null;
+        }
+    }
+
+    async findOrCreateSection(record, name) {
+        // Top-level heading whose text matches name (case-insensitive)
+        const existingItems = await record.getLineItems();
+        const topLevelItems = existingItems.filter(item => item.parent_guid === record.guid);
+        const wanted = name.trim().toLowerCase();
+        const found = topLevelItems.find(item => {
+            if (item.type !== 'heading') return false;
+            const text = (item.segments || []).map(s => typeof s.text === 'string' ? s.text : '').join('');
+            return text.trim().toLowerCase() === wanted;
+        });
+        if (found) return found;
+
+        const lastItem = topLevelItems.length > 0 ? topLevelItems[topLevelItems.length - 1] : null;
+        const heading = await record.createLineItem(null, lastItem, 'heading');
+        if (heading) {
+            heading.setSegments([{ type: 'text', text: name.trim() }]);
+        }
+        return heading;
+    }
+
     async appendOneLiner(record, timeStr, text) {
         // Simple one-liner: "15:21 Quick thought"
         const existingItems = await record.getLineItems();
@@ -394,7 +540,7 @@ class Plugin extends AppPlugin {
         }
     }
 
-    async appendShortNote(record, timeStr, lines) {
+    async appendShortNote(record, timeStr, lines, literal = false) {
         // Short note (2-5 lines): first line as parent with timestamp, rest as children
         const existingItems = await record.getLineItems();
         const topLevelItems = existingItems.filter(item => item.parent_guid === record.guid);
@@ -409,10 +555,10 @@ class Plugin extends AppPlugin {
             { type: 'text', text: ' ' + lines[0] }
         ]);
 
-        // Parse remaining content with full markdown support, insert under parent
+        // Parse remaining content with full markdown support (or literally), insert under parent
         const restContent = lines.slice(1).join('\n');
         if (restContent.trim()) {
-            await this.insertMarkdown(restContent, record, parentItem);
+            await this.insertMarkdown(restContent, record, parentItem, literal);
         }
     }
 
@@ -479,7 +625,7 @@ class Plugin extends AppPlugin {
         }
     }
 
-    async clearAndReplaceContent(record, newContent) {
+    async clearAndReplaceContent(record, newContent, literal = false) {
         // Clear existing line items and replace with new content
         try {
             const existingItems = await record.getLineItems();
@@ -497,11 +643,11 @@ class Plugin extends AppPlugin {
             await new Promise(resolve => setTimeout(resolve, 100));
 
             // Insert new content
-            await this.insertMarkdown(newContent, record);
+            await this.insertMarkdown(newContent, record, null, literal);
         } catch (e) {
             console.error('Error replacing content:', e);
             // Fallback: just append
-            await this.insertMarkdown(newContent, record);
+            await this.insertMarkdown(newContent, record, null, literal);
         }
     }
 
@@ -536,19 +682,43 @@ class Plugin extends AppPlugin {
         return { meta, body };
     }
 
-    async handleFrontmatterItem(title, meta, body) {
+    async handleFrontmatterItem(title, meta, body, options = {}) {
         // Universal handler for frontmatter-based content
         // Routes to collection, finds existing by external_id, adds journal entries
         const collectionName = meta.collection;
         const externalId = meta.external_id;
         const verb = meta.verb; // e.g., opened, closed, merged, updated
+        // Split documents arrive as "part: 2/3"; later parts append to part 1
+        const partIndex = meta.part ? parseInt(String(meta.part).split('/')[0], 10) : 1;
+        delete meta.part;
 
         // Find target collection
         const collections = await this.data.getAllCollections();
-        const targetCollection = collections.find(c =>
+        let targetCollection = collections.find(c =>
             c.getName().toLowerCase() === collectionName.toLowerCase()
         );
 
+        // --create-collection / on_missing_collection=create: the sender allows us to create a missing collection
+        if (!targetCollection && (options.createCollection || options.onMissingCollection === 'create')) {
+            targetCollection = await this.createCollection(collectionName);
+        }
+
+        // on_missing_collection=default: keep the item by writing it to today's Journal instead
+        if (!targetCollection && options.onMissingCollection === 'default') {
+            const journalRecord = await this.getTodayJournalRecord();
+            if (journalRecord) {
+                const heading = title ? `**${title}**\n\n` : '';
+                await this.insertMarkdown(heading + body, journalRecord, null, options.literal);
+                this.ui.addToaster({
+                    title: '🪄 Journal',
+                    message: `Collection "${collectionName}" not found - added to today's Journal`,
+                    dismissible: true,
+                    autoDestroyTime: 3000,
+                });
+                return;
+            }
+        }
+
         if (!targetCollection) {
             console.error('Collection not found:', collectionName);
             this.ui.addToaster({
@@ -589,9 +759,13 @@ class Plugin extends AppPlugin {
             // Update existing - set properties
             await this.setPropertiesFromMeta(existingRecord, meta);
 
-            // Update body content - clear existing and re-insert
+            // Update body content - clear existing and re-insert (continuation parts append)
             if (body.trim()) {
-                await this.clearAndReplaceContent(existingRecord, body);
+                if (partIndex > 1) {
+                    await this.insertMarkdown(body, existingRecord, null, options.literal);
+                } else {
+                    await this.clearAndReplaceContent(existingRecord, body, options.literal);
+                }
             }
 
             // Only add to journal if verb is specified (silent update otherwise)
@@ -629,7 +803,7 @@ class Plugin extends AppPlugin {
             if (newRecord) {
                 await this.setPropertiesFromMeta(newRecord, meta);
                 if (body.trim()) {
-                    await this.insertMarkdown(body, newRecord);
+                    await this.insertMarkdown(body, newRecord, null, options.literal);
                 }
             }
 
@@ -740,6 +914,20 @@ class Plugin extends AppPlugin {
         return /^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}/.test(str);
     }
 
+    async findRecordByGuid(guid) {
+        try {
+            const collections = await this.data.getAllCollections();
+            for (const collection of collections) {
+                const records = await collection.getAllRecords();
+                const record = records.find(r => r.guid === guid);
+                if (record) return record;
+            }
+        } catch (e) {
+            console.error('Error finding record:', guid, e);
+        }
+        return null;
+    }
+
     async getTodayJournalRecord() {
         try {
             const collections = await this.data.getAllCollections();
@@ -779,7 +967,7 @@ class Plugin extends AppPlugin {
         }
     }
 
-    async insertMarkdown(markdown, targetRecord = null, parentItem = null) {
+    async insertMarkdown(markdown, targetRecord = null, parentItem = null, literal = false) {
         const record = targetRecord || this.ui.getActivePanel()?.getActiveRecord();
 
         if (!record) {
@@ -792,8 +980,8 @@ class Plugin extends AppPlugin {
             return;
         }
 
-        // Parse markdown into blocks (handles multi-line code blocks)
-        const blocks = this.parseMarkdown(markdown);
+        // Parse markdown into blocks (handles multi-line code blocks); plain text is one block per line
+        const blocks = literal ? this.parsePlainText(markdown) : this.parseMarkdown(markdown);
 
         // Find the last item to append after
         // If parentItem provided, we're nesting under it; otherwise at record top level
@@ -926,6 +1114,23 @@ class Plugin extends AppPlugin {
         }
     }
 
+    parsePlainText(text) {
+        // contentType text: every non-empty line is a text item exactly as written.
+        // The only markup is the "**15:21** " timestamp this plugin puts on the first line.
+        const blocks = [];
+        for (const line of text.split('\n')) {
+            if (!line.trim()) continue;
+            const stamp = blocks.length === 0 && line.match(/^\*\*(\d{1,2}:\d{2})\*\* ([\s\S]*)$/);
+            blocks.push({
+                type: 'text',
+                segments: stamp
+                    ? [{ type: 'bold', text: stamp[1] }, { type: 'text', text: ' ' + stamp[2] }]
+                    : [{ type: 'text', text: line }]
+            });
+        }
+        return blocks;
+    }
+
     parseMarkdown(markdown) {
         const lines = markdown.split('\n');
         const blocks = [];